@@ -0,0 +1,67 @@
+package routing
+
+import (
+	"errors"
+	"io"
+	"mime"
+	"strconv"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+	"golang.org/x/text/encoding/ianaindex"
+)
+
+// decodeCharset converts the request body to UTF-8 according to the charset parameter of the "Content-Type" header,
+// so that data readers only deal with UTF-8 text. The header is updated to declare the new charset.
+// Form data is left as it is, as its charset applies to the percent-encoded values rather than to the body.
+func decodeCharset(ctx *fasthttp.RequestCtx) error {
+	t, params, err := mime.ParseMediaType(string(ctx.Request.Header.ContentType()))
+	if err != nil || t == MIME_FORM || t == MIME_MULTIPART_FORM {
+		return nil
+	}
+	charset := params["charset"]
+	if charset == "" || isUTF8(charset) {
+		return nil
+	}
+	enc, err := ianaindex.IANA.Encoding(charset)
+	if err != nil || enc == nil {
+		return NewHTTPError(fasthttp.StatusUnsupportedMediaType, "unsupported charset \""+charset+"\"")
+	}
+	body, err := enc.NewDecoder().Bytes(ctx.PostBody())
+	if err != nil {
+		return NewHTTPError(fasthttp.StatusBadRequest, "invalid "+charset+" text in request body")
+	}
+	ctx.Request.SetBody(body)
+	params["charset"] = "utf-8"
+	ctx.Request.Header.SetContentType(mime.FormatMediaType(t, params))
+	return nil
+}
+
+// isUTF8 checks if text in the given charset can be read as UTF-8. US-ASCII is a subset of UTF-8.
+func isUTF8(charset string) bool {
+	switch strings.ToLower(charset) {
+	case "utf-8", "utf8", "us-ascii", "ascii":
+		return true
+	}
+	return false
+}
+
+// xmlCharsetReader returns the CharsetReader of the XML decoders reading the request body, which is called
+// for the charsets other than UTF-8 declared in the XML declaration of the body.
+// If the "Content-Type" header declares UTF-8, as it does once decodeCharset() has converted the body, the input
+// is returned unchanged, as the charset of the header takes precedence over the declaration (RFC 7303, Section 3.2).
+// Otherwise the input is converted from the declared charset.
+func xmlCharsetReader(ctx *fasthttp.RequestCtx) func(string, io.Reader) (io.Reader, error) {
+	_, params, _ := mime.ParseMediaType(string(ctx.Request.Header.ContentType()))
+	transcoded := isUTF8(params["charset"])
+	return func(charset string, input io.Reader) (io.Reader, error) {
+		if transcoded {
+			return input, nil
+		}
+		enc, err := ianaindex.IANA.Encoding(charset)
+		if err != nil || enc == nil {
+			return nil, errors.New("unsupported charset " + strconv.Quote(charset))
+		}
+		return enc.NewDecoder().Reader(input), nil
+	}
+}
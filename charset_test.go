@@ -0,0 +1,57 @@
+package routing
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func TestReadCharset(t *testing.T) {
+	type user struct {
+		Name string `json:"name" xml:"name"`
+	}
+
+	tests := []struct {
+		tag         string
+		contentType string
+		body        string
+		name        string
+	}{
+		{"t1", "application/json; charset=ISO-8859-1", "{\"name\":\"Jos\xe9\"}", "José"},
+		{"t2", "application/xml; charset=\"Shift_JIS\"", "<user><name>\x93\x8c\x8b\x9e</name></user>", "東京"},
+		{"t3", "application/json; charset=utf-8", `{"name":"José"}`, "José"},
+		{"t4", "application/json; charset=us-ascii", `{"name":"Jose"}`, "Jose"},
+		{"t5", "application/json", `{"name":"José"}`, "José"},
+		{"t6", "application/xml; charset=ISO-8859-1", "<?xml version=\"1.0\" encoding=\"ISO-8859-1\"?><user><name>Jos\xe9</name></user>", "José"},
+		{"t7", "application/xml", "<?xml version=\"1.0\" encoding=\"ISO-8859-1\"?><user><name>Jos\xe9</name></user>", "José"},
+		{"t8", "text/xml; charset=utf-8", "<?xml version=\"1.0\" encoding=\"windows-1252\"?><user><name>José</name></user>", "José"},
+	}
+	for _, test := range tests {
+		c := newReadContext(test.contentType, test.body)
+		var u user
+		if assert.Nil(t, c.Read(&u), test.tag) {
+			assert.Equal(t, test.name, u.Name, test.tag)
+		}
+		// reading again does not decode the body twice
+		u = user{}
+		assert.Nil(t, c.Read(&u), test.tag)
+		assert.Equal(t, test.name, u.Name, test.tag)
+	}
+
+	c := newReadContext("application/json; charset=ISO-8859-1", "{\"name\":\"\xe9\"}")
+	assert.Nil(t, c.Read(&user{}))
+	assert.Equal(t, "application/json; charset=utf-8", string(c.Request.Header.ContentType()))
+
+	c = newReadContext("application/json; charset=x-unknown", `{}`)
+	assertHTTPError(t, c.Read(&user{}), fasthttp.StatusUnsupportedMediaType, `unsupported charset "x-unknown"`)
+
+	c = newReadContext("application/xml", `<?xml version="1.0" encoding="x-unknown"?><user/>`)
+	assertHTTPError(t, c.Read(&user{}), fasthttp.StatusBadRequest, "")
+
+	// form data is not decoded
+	c = newReadContext("application/x-www-form-urlencoded; charset=ISO-8859-1", "Name=Jos%E9")
+	var form struct{ Name string }
+	assert.Nil(t, c.Read(&form))
+	assert.Equal(t, "Jos\xe9", form.Name)
+}
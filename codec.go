@@ -0,0 +1,46 @@
+package routing
+
+// Codecs is a registry of the data readers and writers available for each content type.
+//
+// By default, Context.Read() chooses a reader from DataReaders, and content negotiation handlers choose
+// a writer from their own package-level registry (such as content.DataWriters). A Codecs registry
+// can be attached to a Router or a RouteGroup via SetCodecs() to replace these defaults for the routes it serves,
+// so that different parts of an application, or different routers in the same process, can use different codecs
+// without modifying the package-level variables.
+//
+// When a registry is in effect, it is the only place where readers and writers are looked up.
+// Use NewCodecs() to start from a copy of the defaults.
+type Codecs struct {
+	// Readers maps request content types to the data readers used by Context.Read().
+	Readers map[string]DataReader
+	// Writers maps response content types to the data writers that can be chosen by content negotiation.
+	Writers map[string]DataWriter
+	// DefaultWriter is the data writer used by Context.Write() when no data writer has been set
+	// via Context.SetDataWriter(). If nil, DefaultDataWriter is used.
+	DefaultWriter DataWriter
+}
+
+// NewCodecs creates a new Codecs registry whose readers are initialized with a copy of DataReaders.
+// The registry initially has no writers.
+func NewCodecs() *Codecs {
+	readers := make(map[string]DataReader, len(DataReaders))
+	for t, r := range DataReaders {
+		readers[t] = r
+	}
+	return &Codecs{
+		Readers: readers,
+		Writers: make(map[string]DataWriter),
+	}
+}
+
+// Reader returns the data reader registered for the given content type.
+func (c *Codecs) Reader(contentType string) (DataReader, bool) {
+	r, ok := c.Readers[contentType]
+	return r, ok
+}
+
+// Writer returns the data writer registered for the given content type.
+func (c *Codecs) Writer(contentType string) (DataWriter, bool) {
+	w, ok := c.Writers[contentType]
+	return w, ok
+}
@@ -0,0 +1,134 @@
+package routing
+
+import (
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+type upperDataReader struct{}
+
+func (r *upperDataReader) Read(ctx *fasthttp.RequestCtx, data interface{}) error {
+	data.(*FA).A1 = "upper:" + string(ctx.PostBody())
+	return nil
+}
+
+type tagDataWriter struct{}
+
+func (w *tagDataWriter) SetHeader(h *fasthttp.ResponseHeader) {
+	h.SetContentType("text/x-tag")
+}
+
+func (w *tagDataWriter) Write(res io.Writer, data interface{}) error {
+	return DefaultDataWriter.Write(res, fmt.Sprintf("<%v>", data))
+}
+
+func TestCodecs(t *testing.T) {
+	codecs := NewCodecs()
+	assert.Equal(t, len(DataReaders), len(codecs.Readers))
+	assert.Equal(t, 0, len(codecs.Writers))
+	codecs.Readers["text/plain"] = &upperDataReader{}
+	codecs.DefaultWriter = &tagDataWriter{}
+	_, ok := DataReaders["text/plain"]
+	assert.False(t, ok)
+
+	router := New()
+	api := router.Group("/api")
+	api.SetCodecs(codecs)
+	assert.Equal(t, codecs, api.Codecs())
+	v1 := api.Group("/v1")
+	assert.Equal(t, codecs, v1.Codecs())
+	assert.Nil(t, router.Codecs())
+
+	handler := func(c *Context) error {
+		var data FA
+		if err := c.Read(&data); err != nil {
+			return err
+		}
+		return c.Write(data.A1)
+	}
+	v1.Post("/users", handler)
+	router.Post("/users", handler)
+
+	var ctx fasthttp.RequestCtx
+	ctx.Request.Header.SetMethod("POST")
+	ctx.Request.SetRequestURI("/api/v1/users")
+	ctx.Request.Header.SetContentType("text/plain")
+	ctx.Request.SetBodyString("abc")
+	router.HandleRequest(&ctx)
+	assert.Equal(t, "text/x-tag", string(ctx.Response.Header.ContentType()))
+	assert.Equal(t, "<upper:abc>", string(ctx.Response.Body()))
+
+	ctx.Response.Reset()
+	ctx.Request.SetRequestURI("/users")
+	router.HandleRequest(&ctx)
+	assert.Equal(t, "", string(ctx.Response.Body()))
+
+	ctx.Response.Reset()
+	ctx.Request.SetRequestURI("/api/v1/users")
+	ctx.Request.Header.SetContentType(MIME_JSON)
+	ctx.Request.SetBodyString(`{"A1":"json"}`)
+	router.HandleRequest(&ctx)
+	assert.Equal(t, "<json>", string(ctx.Response.Body()))
+}
+
+func TestCodecsInheritance(t *testing.T) {
+	router := New()
+	api := router.Group("/api")
+	v1 := api.Group("/v1")
+	handler := func(c *Context) error {
+		return c.Write("data")
+	}
+	router.Get("/users", handler)
+	v1.Get("/users", handler)
+	assert.Nil(t, v1.Codecs())
+
+	// registries attached after the groups are created still apply to them
+	codecs := NewCodecs()
+	codecs.DefaultWriter = &tagDataWriter{}
+	router.SetCodecs(codecs)
+	assert.Equal(t, codecs, v1.Codecs())
+
+	var ctx fasthttp.RequestCtx
+	ctx.Request.Header.SetMethod("GET")
+	for _, uri := range []string{"/users", "/api/v1/users"} {
+		ctx.Response.Reset()
+		ctx.Request.SetRequestURI(uri)
+		router.HandleRequest(&ctx)
+		assert.Equal(t, "<data>", string(ctx.Response.Body()), uri)
+	}
+
+	other := NewCodecs()
+	api.SetCodecs(other)
+	assert.Equal(t, other, v1.Codecs())
+	assert.Equal(t, codecs, router.Codecs())
+	ctx.Response.Reset()
+	router.HandleRequest(&ctx)
+	assert.Equal(t, "data", string(ctx.Response.Body()))
+}
+
+func TestContextRoute(t *testing.T) {
+	router := New()
+	var route *Route
+	route = router.Get("/users/<id>", func(c *Context) error {
+		assert.Equal(t, route, c.Route())
+		return nil
+	})
+
+	var ctx fasthttp.RequestCtx
+	ctx.Request.Header.SetMethod("GET")
+	ctx.Request.SetRequestURI("/users/1")
+	router.HandleRequest(&ctx)
+	assert.Equal(t, fasthttp.StatusOK, ctx.Response.StatusCode())
+
+	router.NotFound(func(c *Context) error {
+		assert.Nil(t, c.Route())
+		return NewHTTPError(fasthttp.StatusNotFound)
+	})
+	ctx.Request.SetRequestURI("/posts")
+	router.HandleRequest(&ctx)
+	assert.Equal(t, fasthttp.StatusNotFound, ctx.Response.StatusCode())
+}
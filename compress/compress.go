@@ -0,0 +1,292 @@
+// Package compress provides a response compression handler for the ozzo routing package.
+package compress
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/jackwhelpton/fasthttp-routing/v2"
+	"github.com/valyala/fasthttp"
+)
+
+// DefaultMinSize is the default size in bytes below which response bodies are not compressed.
+const DefaultMinSize = 1024
+
+// DefaultContentTypes lists the media types compressed by default. Patterns are matched using path.Match(),
+// so that "text/*" matches any text type and "application/*+json" matches any JSON-based type.
+var DefaultContentTypes = []string{
+	"text/*",
+	"application/json",
+	"application/*+json",
+	"application/xml",
+	"application/*+xml",
+	"application/javascript",
+	"application/x-javascript",
+	"application/x-ndjson",
+	"image/svg+xml",
+}
+
+// DefaultEncodings lists the content codings in the default order of preference.
+// Codings that have no encoder, such as "br" and "zstd" unless registered via RegisterEncoder(), are ignored.
+var DefaultEncodings = []string{"br", "zstd", "gzip", "deflate"}
+
+// Encoder compresses the data written to it into an underlying writer.
+// *gzip.Writer and *zlib.Writer, as well as the writers of most third-party compression packages, implement Encoder.
+type Encoder interface {
+	io.WriteCloser
+	// Reset discards the state of the encoder so that it writes to w, as if it had just been created.
+	Reset(w io.Writer)
+}
+
+// EncoderFunc creates an Encoder writing to w.
+type EncoderFunc func(w io.Writer) Encoder
+
+var (
+	encoders   = map[string]EncoderFunc{}
+	encodersMu sync.RWMutex
+)
+
+// RegisterEncoder registers the encoder used for the given content coding, such as "br" or "zstd".
+// Registering an encoder for "gzip" or "deflate" replaces the built-in one. Encoders must be registered
+// before the handlers using them are created.
+//
+//     compress.RegisterEncoder("br", func(w io.Writer) compress.Encoder {
+//         return brotli.NewWriterLevel(w, brotli.DefaultCompression)
+//     })
+func RegisterEncoder(coding string, f EncoderFunc) {
+	encodersMu.Lock()
+	encoders[strings.ToLower(coding)] = f
+	encodersMu.Unlock()
+}
+
+// Options specifies how responses are compressed.
+type Options struct {
+	// Level is the compression level used by gzip and deflate, from gzip.BestSpeed to gzip.BestCompression.
+	// Zero and invalid levels select gzip.DefaultCompression.
+	Level int
+	// MinSize is the size in bytes below which response bodies are not compressed. Defaults to DefaultMinSize.
+	// A negative value compresses bodies of any size.
+	MinSize int
+	// ContentTypes lists the media types that are compressed. Defaults to DefaultContentTypes.
+	ContentTypes []string
+	// Encodings lists the content codings that may be used, in order of preference. The preference decides
+	// between codings accepted with the same quality by the client. Defaults to DefaultEncodings.
+	Encodings []string
+}
+
+// Handler returns a handler that compresses response bodies with the best content coding accepted by the client,
+// as indicated by the "Accept-Encoding" request header.
+//
+// The handler calls the following handlers first, then compresses the response they produced if its content type
+// is listed in Options.ContentTypes and its body is at least Options.MinSize bytes long. Responses to HEAD requests,
+// responses without body (such as 1xx, 204 and 304 responses), partial content, hijacked connections, responses
+// that already have a "Content-Encoding" header or a "Cache-Control: no-transform" directive, and bodies streamed
+// without a known length (such as Server-Sent Events and NDJSON streams) are never compressed.
+// Bodies streamed with a known length, such as the files served by file.Server, are compressed.
+//
+// "Vary: Accept-Encoding" is added to every response whose content type may be compressed, and the strong ETag of
+// a compressed response is turned into a weak ETag, as the compressed representation is not byte-for-byte identical.
+// When a handler returns an error, the response is left untouched and the error is returned.
+//
+//     r := routing.New()
+//     r.Use(compress.Handler(compress.Options{MinSize: 512}))
+func Handler(opts ...Options) routing.Handler {
+	var options Options
+	if len(opts) > 0 {
+		options = opts[0]
+	}
+	if options.MinSize == 0 {
+		options.MinSize = DefaultMinSize
+	}
+	if options.ContentTypes == nil {
+		options.ContentTypes = DefaultContentTypes
+	}
+	if options.Encodings == nil {
+		options.Encodings = DefaultEncodings
+	}
+	level := options.Level
+	if level == 0 || level < gzip.BestSpeed || level > gzip.BestCompression {
+		level = gzip.DefaultCompression
+	}
+
+	builtin := map[string]EncoderFunc{
+		"gzip": func(w io.Writer) Encoder {
+			e, _ := gzip.NewWriterLevel(w, level)
+			return e
+		},
+		"deflate": func(w io.Writer) Encoder {
+			e, _ := zlib.NewWriterLevel(w, level)
+			return e
+		},
+	}
+	var codings []coding
+	encodersMu.RLock()
+	for _, name := range options.Encodings {
+		name = strings.ToLower(name)
+		f, ok := encoders[name]
+		if !ok {
+			f, ok = builtin[name]
+		}
+		if ok {
+			codings = append(codings, coding{name, &sync.Pool{New: func() interface{} { return f(nil) }}})
+		}
+	}
+	encodersMu.RUnlock()
+
+	return func(c *routing.Context) error {
+		if err := c.Next(); err != nil {
+			return err
+		}
+		compressResponse(c, &options, codings)
+		return nil
+	}
+}
+
+// coding is a content coding together with a pool of its encoders.
+type coding struct {
+	name     string
+	encoders *sync.Pool
+}
+
+// compressResponse compresses the response body if the response and the request allow it.
+func compressResponse(c *routing.Context, options *Options, codings []coding) {
+	res := &c.Response
+	status := res.StatusCode()
+	if c.Hijacked() || status < fasthttp.StatusOK || status == fasthttp.StatusNoContent ||
+		status == fasthttp.StatusNotModified || status == fasthttp.StatusPartialContent ||
+		len(res.Header.Peek("Content-Encoding")) > 0 || !matchContentType(string(res.Header.ContentType()), options.ContentTypes) {
+		return
+	}
+	addVary(&res.Header, "Accept-Encoding")
+
+	if c.IsHead() || hasToken(res.Header.Peek("Cache-Control"), "no-transform") {
+		return
+	}
+	size, streamed := res.Header.ContentLength(), res.IsBodyStream()
+	if !streamed {
+		size = len(res.Body())
+	} else if size < 0 {
+		// the body is streamed without a known length, and each part must reach the client as soon as it is written
+		return
+	}
+	if size == 0 || size < options.MinSize {
+		return
+	}
+	cd, ok := negotiateEncoding(string(c.Request.Header.Peek("Accept-Encoding")), codings)
+	if !ok {
+		return
+	}
+
+	var buf bytes.Buffer
+	e := cd.encoders.Get().(Encoder)
+	e.Reset(&buf)
+	err := res.BodyWriteTo(e)
+	if cerr := e.Close(); err == nil {
+		err = cerr
+	}
+	cd.encoders.Put(e)
+	if err != nil {
+		res.ResetBody()
+		res.SetStatusCode(fasthttp.StatusInternalServerError)
+		return
+	}
+	if buf.Len() >= size && !streamed {
+		// the original body is kept as compression does not make it smaller
+		return
+	}
+
+	res.SetBody(buf.Bytes())
+	res.Header.SetContentLength(buf.Len())
+	res.Header.Set("Content-Encoding", cd.name)
+	if etag := res.Header.Peek("ETag"); len(etag) > 0 && !bytes.HasPrefix(etag, []byte("W/")) {
+		res.Header.Set("ETag", "W/"+string(etag))
+	}
+}
+
+// negotiateEncoding returns the coding with the highest quality in the given "Accept-Encoding" header value.
+// Codings with the same quality are chosen in the order in which they are listed.
+func negotiateEncoding(accept string, codings []coding) (coding, bool) {
+	qualities := map[string]float64{}
+	for _, part := range strings.Split(accept, ",") {
+		params := strings.Split(part, ";")
+		name := strings.ToLower(strings.TrimSpace(params[0]))
+		if name == "" {
+			continue
+		}
+		if name == "x-gzip" {
+			name = "gzip"
+		}
+		q := 1.0
+		for _, p := range params[1:] {
+			kv := strings.SplitN(strings.TrimSpace(p), "=", 2)
+			if len(kv) == 2 && strings.EqualFold(kv[0], "q") {
+				if v, err := strconv.ParseFloat(kv[1], 64); err == nil {
+					q = v
+				}
+			}
+		}
+		qualities[name] = q
+	}
+
+	var (
+		best  coding
+		bestQ float64
+	)
+	for _, cd := range codings {
+		q, ok := qualities[cd.name]
+		if !ok {
+			q = qualities["*"]
+		}
+		if q > bestQ {
+			best, bestQ = cd, q
+		}
+	}
+	return best, bestQ > 0
+}
+
+// matchContentType checks if the given content type matches one of the given media type patterns.
+func matchContentType(contentType string, patterns []string) bool {
+	if i := strings.IndexByte(contentType, ';'); i >= 0 {
+		contentType = contentType[:i]
+	}
+	contentType = strings.ToLower(strings.TrimSpace(contentType))
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(strings.ToLower(pattern), contentType); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// addVary adds the given header name to the "Vary" response header unless it is already listed.
+func addVary(h *fasthttp.ResponseHeader, name string) {
+	vary := h.Peek("Vary")
+	if hasToken(vary, name) || hasToken(vary, "*") {
+		return
+	}
+	if len(vary) == 0 {
+		h.Set("Vary", name)
+	} else {
+		h.Set("Vary", string(vary)+", "+name)
+	}
+}
+
+// hasToken checks if the given comma-separated header value contains the given token, ignoring case.
+func hasToken(value []byte, token string) bool {
+	for _, t := range bytes.Split(value, []byte{','}) {
+		t = bytes.TrimSpace(t)
+		if i := bytes.IndexByte(t, '='); i >= 0 {
+			t = t[:i]
+		}
+		if strings.EqualFold(string(t), token) {
+			return true
+		}
+	}
+	return false
+}
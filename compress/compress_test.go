@@ -0,0 +1,258 @@
+package compress
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jackwhelpton/fasthttp-routing/v2"
+	"github.com/jackwhelpton/fasthttp-routing/v2/content"
+	"github.com/jackwhelpton/fasthttp-routing/v2/file"
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+var text = strings.Repeat("All work and no play makes Jack a dull boy. ", 100)
+
+func decode(t *testing.T, coding string, body []byte) string {
+	var (
+		r   io.Reader
+		err error
+	)
+	switch coding {
+	case "gzip":
+		r, err = gzip.NewReader(bytes.NewReader(body))
+	case "deflate":
+		r, err = zlib.NewReader(bytes.NewReader(body))
+	case "x-flate":
+		r = flate.NewReader(bytes.NewReader(body))
+	default:
+		return string(body)
+	}
+	if !assert.Nil(t, err) {
+		return ""
+	}
+	data, err := io.ReadAll(r)
+	assert.Nil(t, err)
+	return string(data)
+}
+
+func TestHandler(t *testing.T) {
+	tests := []struct {
+		tag            string
+		method         string
+		accept         string
+		contentType    string
+		body           string
+		header, value  string
+		encoding, vary string
+	}{
+		{"t1", "GET", "gzip, deflate", "text/html", text, "", "", "gzip", "Accept-Encoding"},
+		{"t2", "GET", "gzip;q=0.5, deflate", "application/json", text, "", "", "deflate", "Accept-Encoding"},
+		{"t3", "GET", "*", "application/vnd.api+json; charset=utf-8", text, "", "", "gzip", "Accept-Encoding"},
+		{"t4", "GET", "br", "text/html", text, "", "", "", "Accept-Encoding"},
+		{"t5", "GET", "gzip;q=0, *", "text/html", text, "", "", "deflate", "Accept-Encoding"},
+		{"t6", "GET", "", "text/html", text, "", "", "", "Accept-Encoding"},
+		{"t7", "GET", "gzip", "image/png", text, "", "", "", ""},
+		{"t8", "GET", "gzip", "text/html", "short", "", "", "", "Accept-Encoding"},
+		{"t9", "GET", "gzip", "text/html", text, "Content-Encoding", "br", "br", ""},
+		{"t10", "GET", "gzip", "text/html", text, "Cache-Control", "public, no-transform", "", "Accept-Encoding"},
+		{"t11", "GET", "gzip", "text/html", text, "Vary", "Origin", "gzip", "Origin, Accept-Encoding"},
+		{"t12", "GET", "gzip", "text/html", text, "Vary", "accept-encoding", "gzip", "accept-encoding"},
+		{"t13", "HEAD", "gzip", "text/html", text, "", "", "", "Accept-Encoding"},
+		{"t14", "GET", "x-gzip", "text/html", text, "", "", "gzip", "Accept-Encoding"},
+	}
+	for _, test := range tests {
+		router := routing.New()
+		router.To("GET,HEAD", "/", Handler(), func(c *routing.Context) error {
+			c.Response.Header.SetContentType(test.contentType)
+			if test.header != "" {
+				c.Response.Header.Set(test.header, test.value)
+			}
+			return c.Write(test.body)
+		})
+
+		var ctx fasthttp.RequestCtx
+		ctx.Request.Header.SetMethod(test.method)
+		ctx.Request.SetRequestURI("/")
+		ctx.Request.Header.Set("Accept-Encoding", test.accept)
+		router.HandleRequest(&ctx)
+		assert.Equal(t, test.encoding, string(ctx.Response.Header.Peek("Content-Encoding")), test.tag)
+		assert.Equal(t, test.vary, string(ctx.Response.Header.Peek("Vary")), test.tag)
+		if test.method == "GET" {
+			assert.Equal(t, test.body, decode(t, test.encoding, ctx.Response.Body()), test.tag)
+		}
+	}
+}
+
+func TestHandlerSkip(t *testing.T) {
+	// errors are returned untouched
+	router := routing.New()
+	router.Get("/error", Handler(), func(c *routing.Context) error {
+		return routing.NewHTTPError(fasthttp.StatusBadRequest, text)
+	})
+	var ctx fasthttp.RequestCtx
+	ctx.Request.SetRequestURI("/error")
+	ctx.Request.Header.Set("Accept-Encoding", "gzip")
+	router.HandleRequest(&ctx)
+	assert.Equal(t, fasthttp.StatusBadRequest, ctx.Response.StatusCode())
+	assert.Equal(t, "", string(ctx.Response.Header.Peek("Content-Encoding")))
+
+	// no content and not modified responses
+	for _, status := range []int{fasthttp.StatusNoContent, fasthttp.StatusNotModified, fasthttp.StatusPartialContent} {
+		var ctx fasthttp.RequestCtx
+		ctx.Request.Header.Set("Accept-Encoding", "gzip")
+		c := routing.NewContext(&ctx, Handler(), func(c *routing.Context) error {
+			c.SetStatusCode(status)
+			return c.Write(text)
+		})
+		assert.Nil(t, c.Next())
+		assert.Equal(t, "", string(ctx.Response.Header.Peek("Content-Encoding")), status)
+	}
+
+	// streams without a known length are sent as they are produced
+	ctx = fasthttp.RequestCtx{}
+	ctx.Request.Header.Set("Accept-Encoding", "gzip")
+	c := routing.NewContext(&ctx, Handler(), func(c *routing.Context) error {
+		c.SetContentType(content.NDJSON)
+		c.SetBodyStreamWriter(func(w *bufio.Writer) {
+			w.WriteString(text)
+		})
+		return nil
+	})
+	assert.Nil(t, c.Next())
+	assert.True(t, ctx.Response.IsBodyStream())
+	assert.Equal(t, "", string(ctx.Response.Header.Peek("Content-Encoding")))
+	assert.Equal(t, "Accept-Encoding", string(ctx.Response.Header.Peek("Vary")))
+	assert.Equal(t, text, string(ctx.Response.Body()))
+
+	// hijacked connections
+	ctx = fasthttp.RequestCtx{}
+	ctx.Request.Header.Set("Accept-Encoding", "gzip")
+	c = routing.NewContext(&ctx, Handler(), func(c *routing.Context) error {
+		c.SetStatusCode(fasthttp.StatusSwitchingProtocols)
+		c.Hijack(func(net.Conn) {})
+		return nil
+	})
+	assert.Nil(t, c.Next())
+	assert.Equal(t, "", string(ctx.Response.Header.Peek("Vary")))
+}
+
+func TestHandlerETag(t *testing.T) {
+	for _, etag := range []string{`"abc"`, `W/"abc"`} {
+		var ctx fasthttp.RequestCtx
+		ctx.Request.Header.Set("Accept-Encoding", "gzip")
+		c := routing.NewContext(&ctx, Handler(), func(c *routing.Context) error {
+			c.Response.Header.Set("ETag", etag)
+			return c.Write(text)
+		})
+		assert.Nil(t, c.Next())
+		assert.Equal(t, `W/"abc"`, string(ctx.Response.Header.Peek("ETag")), etag)
+	}
+}
+
+func TestHandlerOptions(t *testing.T) {
+	var ctx fasthttp.RequestCtx
+	ctx.Request.Header.Set("Accept-Encoding", "gzip, deflate")
+	c := routing.NewContext(&ctx, Handler(Options{
+		Level:        gzip.BestCompression,
+		MinSize:      -1,
+		ContentTypes: []string{"image/*"},
+		Encodings:    []string{"deflate", "gzip"},
+	}), func(c *routing.Context) error {
+		c.SetContentType("image/bmp")
+		return c.Write(strings.Repeat("a", 100))
+	})
+	assert.Nil(t, c.Next())
+	assert.Equal(t, "deflate", string(ctx.Response.Header.Peek("Content-Encoding")))
+	assert.Equal(t, strings.Repeat("a", 100), decode(t, "deflate", ctx.Response.Body()))
+
+	// bodies that compression does not make smaller are kept as they are
+	ctx = fasthttp.RequestCtx{}
+	ctx.Request.Header.Set("Accept-Encoding", "gzip")
+	c = routing.NewContext(&ctx, Handler(Options{MinSize: -1}), func(c *routing.Context) error {
+		return c.Write("a")
+	})
+	assert.Nil(t, c.Next())
+	assert.Equal(t, "", string(ctx.Response.Header.Peek("Content-Encoding")))
+	assert.Equal(t, "a", string(ctx.Response.Body()))
+}
+
+func TestRegisterEncoder(t *testing.T) {
+	RegisterEncoder("X-Flate", func(w io.Writer) Encoder {
+		e, _ := flate.NewWriter(w, flate.BestSpeed)
+		return e
+	})
+	defer func() {
+		encodersMu.Lock()
+		delete(encoders, "x-flate")
+		encodersMu.Unlock()
+	}()
+
+	h := Handler(Options{Encodings: []string{"x-flate", "gzip"}})
+	for i := 0; i < 3; i++ {
+		var ctx fasthttp.RequestCtx
+		ctx.Request.Header.Set("Accept-Encoding", "gzip, x-flate")
+		c := routing.NewContext(&ctx, h, func(c *routing.Context) error {
+			return c.Write(text)
+		})
+		assert.Nil(t, c.Next())
+		assert.Equal(t, "x-flate", string(ctx.Response.Header.Peek("Content-Encoding")))
+		assert.Equal(t, text, decode(t, "x-flate", ctx.Response.Body()))
+	}
+}
+
+func TestHandlerFileServer(t *testing.T) {
+	dir := t.TempDir()
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "main.css"), []byte(text), 0644))
+
+	router := routing.New()
+	router.Get("/*", Handler(), file.Server(file.PathMap{"/": "/"}, file.ServerOptions{RootPath: dir}))
+
+	var ctx fasthttp.RequestCtx
+	ctx.Request.SetRequestURI("/main.css")
+	ctx.Request.Header.Set("Accept-Encoding", "gzip")
+	router.HandleRequest(&ctx)
+	assert.Equal(t, fasthttp.StatusOK, ctx.Response.StatusCode())
+	assert.Equal(t, "gzip", string(ctx.Response.Header.Peek("Content-Encoding")))
+	body := ctx.Response.Body()
+	assert.Equal(t, len(body), ctx.Response.Header.ContentLength())
+	assert.Equal(t, text, decode(t, "gzip", body))
+}
+
+func Test_negotiateEncoding(t *testing.T) {
+	codings := []coding{{name: "br"}, {name: "gzip"}, {name: "deflate"}}
+	tests := []struct {
+		accept, expected string
+	}{
+		{"", ""},
+		{"identity", ""},
+		{"gzip, deflate, br", "br"},
+		{"deflate, gzip;q=1.0, br;q=0.9", "gzip"},
+		{"GZIP;q=0.3, *;q=0.2", "gzip"},
+		{"*;q=0.5, br;q=0", "gzip"},
+		{"compress", ""},
+		{"br;q=0", ""},
+		{"deflate;q=invalid", "deflate"},
+	}
+	for _, test := range tests {
+		cd, ok := negotiateEncoding(test.accept, codings)
+		assert.Equal(t, test.expected != "", ok, test.accept)
+		assert.Equal(t, test.expected, cd.name, test.accept)
+	}
+}
+
+func Test_matchContentType(t *testing.T) {
+	assert.True(t, matchContentType("text/html; charset=utf-8", DefaultContentTypes))
+	assert.True(t, matchContentType("Application/Problem+JSON", DefaultContentTypes))
+	assert.False(t, matchContentType("application/octet-stream", DefaultContentTypes))
+	assert.False(t, matchContentType("", DefaultContentTypes))
+}
@@ -0,0 +1,58 @@
+package content
+
+import (
+	"io"
+
+	"github.com/jackwhelpton/fasthttp-routing/v2"
+	"github.com/jackwhelpton/fasthttp-routing/v2/internal/codec"
+	"github.com/valyala/fasthttp"
+)
+
+// MIME types of binary data
+const (
+	MsgPack  = routing.MIME_MSGPACK
+	MsgPack2 = routing.MIME_MSGPACK2
+	CBOR     = routing.MIME_CBOR
+)
+
+// MsgPackDataWriter sets the "Content-Type" response header as "application/msgpack" and writes the given data
+// in MessagePack format to the response.
+//
+// Data is mapped to MessagePack as JSONDataWriter maps it to JSON: struct fields are written as map entries named
+// after their `json` struct tags, values implementing encoding.TextMarshaler (such as time.Time) are written as strings,
+// and []byte values are written as binary data.
+type MsgPackDataWriter struct{}
+
+// SetHeader sets the "Content-Type" response header as "application/msgpack".
+func (w *MsgPackDataWriter) SetHeader(h *fasthttp.ResponseHeader) {
+	h.SetContentType(MsgPack)
+}
+
+// Write writes the given data in MessagePack format to the response.
+func (w *MsgPackDataWriter) Write(res io.Writer, data interface{}) error {
+	bytes, err := codec.MarshalMsgPack(data)
+	if err != nil {
+		return err
+	}
+	_, err = res.Write(bytes)
+	return err
+}
+
+// CBORDataWriter sets the "Content-Type" response header as "application/cbor" and writes the given data
+// in CBOR format (RFC 8949) to the response. Data is mapped to CBOR as MsgPackDataWriter maps it to MessagePack.
+type CBORDataWriter struct{}
+
+// SetHeader sets the "Content-Type" response header as "application/cbor".
+func (w *CBORDataWriter) SetHeader(h *fasthttp.ResponseHeader) {
+	h.SetContentType(CBOR)
+}
+
+// Write writes the given data in CBOR format to the response.
+func (w *CBORDataWriter) Write(res io.Writer, data interface{}) error {
+	bytes, err := codec.MarshalCBOR(data)
+	if err != nil {
+		return err
+	}
+	_, err = res.Write(bytes)
+	return err
+}
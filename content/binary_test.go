@@ -0,0 +1,76 @@
+package content
+
+import (
+	"testing"
+
+	"github.com/jackwhelpton/fasthttp-routing/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func TestMsgPackDataWriter(t *testing.T) {
+	var ctx fasthttp.RequestCtx
+	w := &MsgPackDataWriter{}
+	w.SetHeader(&ctx.Response.Header)
+	err := w.Write(&ctx, struct {
+		ID   int    `json:"id"`
+		Name string `json:"name,omitempty"`
+	}{ID: 1})
+	assert.Nil(t, err)
+	assert.Equal(t, "application/msgpack", string(ctx.Response.Header.ContentType()))
+	assert.Equal(t, "\x81\xa2id\x01", string(ctx.Response.Body()))
+
+	assert.NotNil(t, w.Write(&ctx, make(chan int)))
+}
+
+func TestCBORDataWriter(t *testing.T) {
+	var ctx fasthttp.RequestCtx
+	w := &CBORDataWriter{}
+	w.SetHeader(&ctx.Response.Header)
+	err := w.Write(&ctx, []interface{}{"a", []byte{1}, nil})
+	assert.Nil(t, err)
+	assert.Equal(t, "application/cbor", string(ctx.Response.Header.ContentType()))
+	assert.Equal(t, "\x83\x61a\x41\x01\xf6", string(ctx.Response.Body()))
+}
+
+func TestBinaryTypeNegotiation(t *testing.T) {
+	type user struct {
+		Name string `json:"name"`
+	}
+	router := routing.New()
+	router.Use(TypeNegotiator(JSON, MsgPack, CBOR))
+	router.Post("/users", func(c *routing.Context) error {
+		var u user
+		if err := c.Read(&u); err != nil {
+			return err
+		}
+		return c.Write(u)
+	})
+
+	tests := []struct {
+		tag         string
+		contentType string
+		body        string
+		accept      string
+		status      int
+		response    string
+	}{
+		{"t1", MsgPack, "\x81\xa4name\xa3abc", "application/msgpack", fasthttp.StatusOK, "\x81\xa4name\xa3abc"},
+		{"t2", CBOR, "\xa1\x64name\x63abc", "application/cbor", fasthttp.StatusOK, "\xa1\x64name\x63abc"},
+		{"t3", CBOR, "\xa1\x64name\x63abc", "application/json", fasthttp.StatusOK, `{"name":"abc"}` + "\n"},
+		{"t4", JSON, `{"name":"abc"}`, "application/cbor;q=0.5, application/msgpack", fasthttp.StatusOK, "\x81\xa4name\xa3abc"},
+		{"t5", MsgPack, "\x81\xa4name\x01", "application/msgpack", fasthttp.StatusBadRequest,
+			"\x82\xa6status\xcd\x01\x90\xa7message\xd9\x21msgpack: invalid value (offset 7)"},
+	}
+	for _, test := range tests {
+		var ctx fasthttp.RequestCtx
+		ctx.Request.Header.SetMethod("POST")
+		ctx.Request.SetRequestURI("/users")
+		ctx.Request.Header.SetContentType(test.contentType)
+		ctx.Request.Header.Set("Accept", test.accept)
+		ctx.Request.SetBodyString(test.body)
+		router.HandleRequest(&ctx)
+		assert.Equal(t, test.status, ctx.Response.StatusCode(), test.tag)
+		assert.Equal(t, test.response, string(ctx.Response.Body()), test.tag)
+	}
+}
@@ -0,0 +1,159 @@
+package content
+
+import (
+	"mime"
+	"strings"
+
+	"github.com/jackwhelpton/fasthttp-routing/v2"
+	"github.com/valyala/fasthttp"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/ianaindex"
+)
+
+// Charset is the key used to store and retrieve the chosen charset in routing.Context
+const Charset = "Charset"
+
+// charset is a charset offered by CharsetNegotiator.
+type charset struct {
+	// name is the preferred MIME name of the charset, such as "ISO-8859-1".
+	name string
+	// encoding is nil for UTF-8, in which responses are written.
+	encoding encoding.Encoding
+}
+
+// CharsetNegotiator returns a charset negotiation handler.
+//
+// The method takes a list of charsets that are supported by the application, such as "UTF-8", "ISO-8859-1" or "Shift_JIS".
+// The negotiator will determine the best charset to use by checking the Accept-Charset request header.
+// If no match is found, the first charset will be used.
+//
+// In a handler, you can access the chosen charset, by its preferred MIME name, through routing.Context like the following:
+//
+//     func(c *routing.Context) error {
+//         charset := c.Get(content.Charset).(string)
+//     }
+//
+// Once the following handlers have been called, a response declared as "charset=UTF-8", as done by the XML, HTML,
+// CSV and TSV data writers, is transcoded to the chosen charset and its "Content-Type" header is updated accordingly.
+// Characters that the charset cannot represent are written as character references in HTML and XML responses,
+// and as the substitute character of the charset in other responses. Streamed responses, and errors returned
+// by the following handlers, are sent in UTF-8.
+//
+// If you do not specify charsets, the negotiator will set the charset to be "UTF-8".
+// The method panics if a charset is unknown.
+func CharsetNegotiator(charsets ...string) routing.Handler {
+	if len(charsets) == 0 {
+		charsets = []string{"UTF-8"}
+	}
+	offers := make([]charset, len(charsets))
+	for i, name := range charsets {
+		cs, ok := lookupCharset(name)
+		if !ok {
+			panic(name + " is not supported")
+		}
+		offers[i] = cs
+	}
+
+	return func(c *routing.Context) error {
+		cs := negotiateCharset(string(c.Request.Header.Peek("Accept-Charset")), offers)
+		c.Set(Charset, cs.name)
+		if len(offers) > 1 {
+			addVary(&c.Response.Header, "Accept-Charset")
+		}
+		if err := c.Next(); err != nil {
+			return err
+		}
+		return transcodeResponse(c, cs)
+	}
+}
+
+// lookupCharset returns the charset of the given name, which may be any name or alias registered by IANA.
+func lookupCharset(name string) (charset, bool) {
+	if strings.EqualFold(name, "UTF-8") || strings.EqualFold(name, "UTF8") {
+		return charset{name: "UTF-8"}, true
+	}
+	e, err := ianaindex.IANA.Encoding(name)
+	if err != nil || e == nil {
+		return charset{}, false
+	}
+	if name, err = ianaindex.MIME.Name(e); err != nil {
+		return charset{}, false
+	}
+	if name == "UTF-8" {
+		e = nil
+	}
+	return charset{name, e}, true
+}
+
+// negotiateCharset negotiates the acceptable charset according to the Accept-Charset HTTP header.
+func negotiateCharset(accept string, offers []charset) charset {
+	if accept == "" {
+		return offers[0]
+	}
+	weights := map[string]float64{}
+	for _, r := range ParseAcceptRanges(accept) {
+		// a charset has no subtype, so its name is parsed as the type of a media range
+		name := r.Type
+		if name != "*" {
+			cs, ok := lookupCharset(name)
+			if !ok {
+				continue
+			}
+			name = cs.name
+		}
+		weights[name] = r.Weight
+	}
+
+	best, bestWeight := offers[0], 0.0
+	for _, offer := range offers {
+		w, ok := weights[offer.name]
+		if !ok {
+			w = weights["*"]
+		}
+		if w > bestWeight {
+			best, bestWeight = offer, w
+		}
+	}
+	return best
+}
+
+// transcodeResponse converts a response body written in UTF-8 to the given charset.
+func transcodeResponse(c *routing.Context, cs charset) error {
+	res := &c.Response
+	if cs.encoding == nil || c.Hijacked() || res.IsBodyStream() {
+		return nil
+	}
+	t, params, err := mime.ParseMediaType(string(res.Header.ContentType()))
+	if err != nil || !strings.EqualFold(params["charset"], "UTF-8") {
+		return nil
+	}
+	e := cs.encoding.NewEncoder()
+	if t == HTML || t == XML || t == XML2 || strings.HasSuffix(t, "+xml") {
+		e = encoding.HTMLEscapeUnsupported(e)
+	} else {
+		e = encoding.ReplaceUnsupported(e)
+	}
+	body, err := e.Bytes(res.Body())
+	if err != nil {
+		return err
+	}
+	res.SetBody(body)
+	params["charset"] = cs.name
+	res.Header.SetContentType(mime.FormatMediaType(t, params))
+	return nil
+}
+
+// addVary adds the given header name to the "Vary" response header unless it is already listed.
+func addVary(h *fasthttp.ResponseHeader, name string) {
+	vary := string(h.Peek("Vary"))
+	for _, v := range strings.Split(vary, ",") {
+		if v = strings.TrimSpace(v); v == "*" || strings.EqualFold(v, name) {
+			return
+		}
+	}
+	if vary == "" {
+		h.Set("Vary", name)
+	} else {
+		h.Set("Vary", vary+", "+name)
+	}
+}
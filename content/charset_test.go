@@ -0,0 +1,82 @@
+package content
+
+import (
+	"testing"
+
+	"github.com/jackwhelpton/fasthttp-routing/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func TestNegotiateCharset(t *testing.T) {
+	offers := make([]charset, 3)
+	for i, name := range []string{"utf-8", "latin1", "Shift_JIS"} {
+		offers[i], _ = lookupCharset(name)
+	}
+	tests := []struct {
+		tag    string
+		accept string
+		result string
+	}{
+		{"t1", "", "UTF-8"},
+		{"t2", "iso-8859-1", "ISO-8859-1"},
+		{"t3", "ISO_8859-1:1987;q=0.5, shift_jis;q=0.8", "Shift_JIS"},
+		{"t4", "x-unknown, *;q=0.1", "UTF-8"},
+		{"t5", "*, utf-8;q=0", "ISO-8859-1"},
+		{"t6", "EUC-JP", "UTF-8"},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.result, negotiateCharset(test.accept, offers).name, test.tag)
+	}
+}
+
+func TestCharsetNegotiator(t *testing.T) {
+	router := routing.New()
+	router.Use(CharsetNegotiator("UTF-8", "ISO-8859-1", "Shift_JIS"), TypeNegotiator(HTML, XML, JSON, CSV))
+	router.Get("/cities", func(c *routing.Context) error {
+		if c.QueryArgs().Has("stream") {
+			cities := make(chan []string, 1)
+			cities <- []string{"Zürich"}
+			close(cities)
+			return c.Write(cities)
+		}
+		return c.Write([]string{"Zürich", "東京"})
+	})
+	router.Get("/charset", func(c *routing.Context) error {
+		return c.Write(c.Get(Charset))
+	})
+
+	tests := []struct {
+		tag         string
+		uri         string
+		accept      string
+		charset     string
+		contentType string
+		body        string
+	}{
+		{"t1", "/cities", "application/xml", "", "application/xml; charset=UTF-8",
+			"<string>Zürich</string><string>東京</string>"},
+		{"t2", "/cities", "application/xml", "ISO-8859-1", "application/xml; charset=ISO-8859-1",
+			"<string>Z\xfcrich</string><string>&#26481;&#20140;</string>"},
+		{"t3", "/cities", "application/xml", "Shift_JIS;q=0.9, ISO-8859-1;q=0.5", "application/xml; charset=Shift_JIS",
+			"<string>Z&#252;rich</string><string>\x93\x8c\x8b\x9e</string>"},
+		{"t4", "/cities", "text/html", "iso-8859-1", "text/html; charset=ISO-8859-1", "[Z\xfcrich &#26481;&#20140;]"},
+		{"t5", "/cities", "text/csv", "iso-8859-1", "text/csv; charset=ISO-8859-1", "Z\xfcrich,\x1a\x1a\n"},
+		{"t6", "/cities", "application/json", "iso-8859-1", "application/json", `["Zürich","東京"]` + "\n"},
+		{"t7", "/cities?stream", "text/csv", "iso-8859-1", "text/csv; charset=UTF-8", "Zürich\n"},
+		{"t8", "/charset", "text/html", "latin1", "text/html; charset=ISO-8859-1", "ISO-8859-1"},
+	}
+	for _, test := range tests {
+		var ctx fasthttp.RequestCtx
+		ctx.Request.SetRequestURI(test.uri)
+		ctx.Request.Header.Set("Accept", test.accept)
+		ctx.Request.Header.Set("Accept-Charset", test.charset)
+		router.HandleRequest(&ctx)
+		assert.Equal(t, fasthttp.StatusOK, ctx.Response.StatusCode(), test.tag)
+		assert.Equal(t, test.contentType, string(ctx.Response.Header.ContentType()), test.tag)
+		assert.Equal(t, test.body, string(ctx.Response.Body()), test.tag)
+		assert.Equal(t, "Accept-Charset, Accept", string(ctx.Response.Header.Peek("Vary")), test.tag)
+	}
+
+	assert.Panics(t, func() { CharsetNegotiator("x-unknown") })
+}
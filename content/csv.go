@@ -0,0 +1,341 @@
+package content
+
+import (
+	"bufio"
+	"encoding"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// MIME types of tabular data
+const (
+	CSV = "text/csv"
+	TSV = "text/tab-separated-values"
+)
+
+// CSVDataWriter sets the "Content-Type" response header as "text/csv; charset=UTF-8" and writes the given data
+// as comma-separated values.
+//
+// The data may be a slice, an array, a channel or a StreamFunc whose items are rows, or a single row.
+// A row is either a struct (or a pointer to a struct), a map with string keys, or a []string:
+//
+//   - struct rows are preceded by a header row listing their exported fields. The header of a field can be
+//     customized with a struct tag named `csv`, and fields tagged with `csv:"-"` are omitted. The fields of
+//     embedded structs are listed as if they belonged to the outer struct.
+//   - map rows are preceded by a header row listing the keys of the first map in alphabetical order.
+//     The values of the following maps are written in the same order, and keys missing from them are left empty.
+//   - []string rows are written as they are, so the first row of a [][]string serves as its header.
+//
+// Values implementing encoding.TextMarshaler or fmt.Stringer are written using these interfaces,
+// and nil pointers are written as empty fields.
+//
+// Channels and StreamFunc values are streamed to the client (see StreamOptions), and so are slices and arrays
+// having more than StreamThreshold rows.
+type CSVDataWriter struct {
+	StreamOptions
+	// Comma is the field delimiter. Defaults to ','.
+	Comma rune
+	// UseCRLF ends the rows with "\r\n" instead of "\n".
+	UseCRLF bool
+	// NoHeader omits the header row of struct and map rows.
+	NoHeader bool
+	// StreamThreshold is the number of rows above which slices and arrays are streamed instead of being
+	// written into the response body before it is sent. Zero means slices and arrays are never streamed.
+	StreamThreshold int
+}
+
+// SetHeader sets the "Content-Type" response header as "text/csv; charset=UTF-8".
+func (w *CSVDataWriter) SetHeader(h *fasthttp.ResponseHeader) {
+	h.SetContentType(CSV + "; charset=UTF-8")
+}
+
+// Write writes the given data as comma-separated values.
+func (w *CSVDataWriter) Write(res io.Writer, data interface{}) error {
+	if stream, ok := streamOf(data); ok {
+		var rowType reflect.Type
+		if t := reflect.TypeOf(data); t.Kind() == reflect.Chan {
+			rowType = t.Elem()
+		}
+		return w.run(res, func(bw *bufio.Writer) error {
+			return w.writeRows(bw, stream, rowType)
+		})
+	}
+
+	rv := reflect.ValueOf(data)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array || isRow(rv.Type()) {
+		return w.writeRows(bufio.NewWriter(res), func(yield func(interface{}) bool) error {
+			yield(data)
+			return nil
+		}, nil)
+	}
+	rows := func(yield func(interface{}) bool) error {
+		for i := 0; i < rv.Len(); i++ {
+			if !yield(rv.Index(i).Interface()) {
+				break
+			}
+		}
+		return nil
+	}
+	if w.StreamThreshold > 0 && rv.Len() > w.StreamThreshold {
+		return w.run(res, func(bw *bufio.Writer) error {
+			return w.writeRows(bw, rows, rv.Type().Elem())
+		})
+	}
+	return w.writeRows(bufio.NewWriter(res), rows, rv.Type().Elem())
+}
+
+// writeRows writes the rows produced by the given stream. If known, rowType is the type of the rows,
+// which is used to write the header of struct rows when the stream produces no row.
+func (w *CSVDataWriter) writeRows(bw *bufio.Writer, rows StreamFunc, rowType reflect.Type) (err error) {
+	cw := csv.NewWriter(bw)
+	if w.Comma != 0 {
+		cw.Comma = w.Comma
+	}
+	cw.UseCRLF = w.UseCRLF
+	enc := &csvEncoder{w: cw, header: !w.NoHeader}
+	flush := func() error {
+		cw.Flush()
+		if err := cw.Error(); err != nil {
+			return err
+		}
+		return bw.Flush()
+	}
+
+	interval := w.flushInterval()
+	lastFlush := time.Now()
+	streamErr := rows(func(row interface{}) bool {
+		if row != flushMarker {
+			if err = enc.encode(row); err != nil {
+				return false
+			}
+			if time.Since(lastFlush) < interval {
+				return true
+			}
+		}
+		err = flush()
+		lastFlush = time.Now()
+		return err == nil
+	})
+	if err != nil {
+		return err
+	}
+	if streamErr != nil {
+		return streamErr
+	}
+	if !enc.started() && rowType != nil {
+		if t := indirectType(rowType); t.Kind() == reflect.Struct && enc.header {
+			if err := cw.Write(csvHeader(csvFields(t))); err != nil {
+				return err
+			}
+		}
+	}
+	return flush()
+}
+
+// TSVDataWriter sets the "Content-Type" response header as "text/tab-separated-values; charset=UTF-8" and writes
+// the given data as tab-separated values. It supports the same data and options as CSVDataWriter,
+// except that Comma defaults to '\t'.
+type TSVDataWriter struct {
+	CSVDataWriter
+}
+
+// SetHeader sets the "Content-Type" response header as "text/tab-separated-values; charset=UTF-8".
+func (w *TSVDataWriter) SetHeader(h *fasthttp.ResponseHeader) {
+	h.SetContentType(TSV + "; charset=UTF-8")
+}
+
+// Write writes the given data as tab-separated values.
+func (w *TSVDataWriter) Write(res io.Writer, data interface{}) error {
+	cw := w.CSVDataWriter
+	if cw.Comma == 0 {
+		cw.Comma = '\t'
+	}
+	return cw.Write(res, data)
+}
+
+// csvEncoder writes rows, preceded by a header row determined by the first row.
+type csvEncoder struct {
+	w      *csv.Writer
+	header bool
+	// kind is the kind of the rows (reflect.Struct, reflect.Map or reflect.Slice), set by the first row
+	kind reflect.Kind
+	// rowType is the type of struct rows
+	rowType reflect.Type
+	fields  []csvField
+	// keys are the keys of map rows
+	keys []string
+}
+
+func (e *csvEncoder) started() bool {
+	return e.kind != reflect.Invalid
+}
+
+func (e *csvEncoder) encode(row interface{}) error {
+	v := reflect.ValueOf(row)
+	for v.Kind() == reflect.Ptr && !v.IsNil() {
+		v = v.Elem()
+	}
+	kind := v.Kind()
+	if kind == reflect.Array {
+		kind = reflect.Slice
+	}
+	switch {
+	case e.started() && (kind != e.kind || kind == reflect.Struct && v.Type() != e.rowType):
+	case kind == reflect.Slice && v.Type().Elem().Kind() == reflect.String:
+		e.kind = kind
+		record := make([]string, v.Len())
+		for i := range record {
+			record[i] = v.Index(i).String()
+		}
+		return e.w.Write(record)
+
+	case kind == reflect.Struct:
+		if !e.started() {
+			e.kind, e.rowType, e.fields = kind, v.Type(), csvFields(v.Type())
+			if e.header {
+				if err := e.w.Write(csvHeader(e.fields)); err != nil {
+					return err
+				}
+			}
+		}
+		record := make([]string, len(e.fields))
+		for i, f := range e.fields {
+			if fv, err := v.FieldByIndexErr(f.index); err == nil {
+				record[i] = formatCSVValue(fv)
+			}
+		}
+		return e.w.Write(record)
+
+	case kind == reflect.Map && v.Type().Key().Kind() == reflect.String:
+		if !e.started() {
+			e.kind = kind
+			for _, k := range v.MapKeys() {
+				e.keys = append(e.keys, k.String())
+			}
+			sort.Strings(e.keys)
+			if e.header {
+				if err := e.w.Write(e.keys); err != nil {
+					return err
+				}
+			}
+		}
+		record := make([]string, len(e.keys))
+		for i, k := range e.keys {
+			record[i] = formatCSVValue(v.MapIndex(reflect.ValueOf(k).Convert(v.Type().Key())))
+		}
+		return e.w.Write(record)
+	}
+	return fmt.Errorf("content: cannot write %T as a CSV row", row)
+}
+
+// isRow checks if the given slice or array type is a single row rather than a list of rows.
+func isRow(t reflect.Type) bool {
+	return t.Elem().Kind() == reflect.String
+}
+
+// indirectType returns the type pointed to by t if t is a pointer type, or t otherwise.
+func indirectType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
+
+// csvField is a struct field written as a CSV column.
+type csvField struct {
+	name  string
+	index []int
+}
+
+var csvFieldCache sync.Map // map[reflect.Type][]csvField
+
+// csvFields returns the fields of the given struct type that are written as CSV columns.
+func csvFields(t reflect.Type) []csvField {
+	if fields, ok := csvFieldCache.Load(t); ok {
+		return fields.([]csvField)
+	}
+	fields := appendCSVFields(nil, t, nil)
+	csvFieldCache.Store(t, fields)
+	return fields
+}
+
+func appendCSVFields(fields []csvField, t reflect.Type, index []int) []csvField {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("csv")
+		if tag == "-" {
+			continue
+		}
+		name := strings.Split(tag, ",")[0]
+		fi := append(append([]int{}, index...), i)
+		if f.Anonymous && name == "" && indirectType(f.Type).Kind() == reflect.Struct {
+			fields = appendCSVFields(fields, indirectType(f.Type), fi)
+			continue
+		}
+		if f.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields = append(fields, csvField{name, fi})
+	}
+	return fields
+}
+
+func csvHeader(fields []csvField) []string {
+	header := make([]string, len(fields))
+	for i, f := range fields {
+		header[i] = f.name
+	}
+	return header
+}
+
+// formatCSVValue returns the text written for the given value in a CSV field.
+func formatCSVValue(v reflect.Value) string {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		return ""
+	}
+	if v.CanInterface() {
+		switch x := v.Interface().(type) {
+		case encoding.TextMarshaler:
+			if text, err := x.MarshalText(); err == nil {
+				return string(text)
+			}
+		case fmt.Stringer:
+			return x.String()
+		}
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return v.String()
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(v.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, v.Type().Bits())
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return string(v.Bytes())
+		}
+	}
+	return fmt.Sprint(v.Interface())
+}
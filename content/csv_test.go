@@ -0,0 +1,131 @@
+package content
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jackwhelpton/fasthttp-routing/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+type csvBase struct {
+	ID int `csv:"id"`
+}
+
+type csvUser struct {
+	csvBase
+	Name     string    `csv:"name"`
+	Email    string    `csv:"-"`
+	Joined   time.Time `csv:"joined"`
+	Score    *float64  `csv:"score"`
+	Admin    bool
+	internal string
+}
+
+func TestCSVDataWriter(t *testing.T) {
+	score := 9.5
+	joined := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	users := []csvUser{
+		{csvBase{1}, "John, Jr.", "john@example.com", joined, &score, true, "x"},
+		{csvBase{2}, `Jane "JJ"`, "jane@example.com", joined, nil, false, "y"},
+	}
+
+	tests := []struct {
+		tag    string
+		writer *CSVDataWriter
+		data   interface{}
+		body   string
+	}{
+		{"t1", &CSVDataWriter{}, users, "id,name,joined,score,Admin\n1,\"John, Jr.\",2020-01-02T03:04:05Z,9.5,true\n2,\"Jane \"\"JJ\"\"\",2020-01-02T03:04:05Z,,false\n"},
+		{"t2", &CSVDataWriter{}, &users[0], "id,name,joined,score,Admin\n1,\"John, Jr.\",2020-01-02T03:04:05Z,9.5,true\n"},
+		{"t3", &CSVDataWriter{}, []csvUser{}, "id,name,joined,score,Admin\n"},
+		{"t4", &CSVDataWriter{NoHeader: true, Comma: ';', UseCRLF: true}, []*csvUser{&users[1]}, "2;\"Jane \"\"JJ\"\"\";2020-01-02T03:04:05Z;;false\r\n"},
+		{"t5", &CSVDataWriter{}, [][]string{{"a", "b"}, {"1", "2"}}, "a,b\n1,2\n"},
+		{"t6", &CSVDataWriter{}, []string{"a", "b c"}, "a,b c\n"},
+		{"t7", &CSVDataWriter{}, []map[string]interface{}{{"b": 1, "a": "x"}, {"a": "y", "c": 3}}, "a,b\nx,1\ny,\n"},
+		{"t8", &CSVDataWriter{}, map[string]int{"x": 1}, "x\n1\n"},
+		{"t9", &CSVDataWriter{}, []string{}, "\n"},
+	}
+	for _, test := range tests {
+		var buf bytes.Buffer
+		assert.Nil(t, test.writer.Write(&buf, test.data), test.tag)
+		assert.Equal(t, test.body, buf.String(), test.tag)
+	}
+
+	var buf bytes.Buffer
+	w := &CSVDataWriter{}
+	assert.NotNil(t, w.Write(&buf, []int{1, 2}))
+	assert.NotNil(t, w.Write(&buf, []interface{}{users[0], map[string]int{"a": 1}}))
+	assert.NotNil(t, w.Write(&buf, []interface{}{users[0], csvBase{1}}))
+
+	var ctx fasthttp.RequestCtx
+	w.SetHeader(&ctx.Response.Header)
+	assert.Equal(t, "text/csv; charset=UTF-8", string(ctx.Response.Header.ContentType()))
+}
+
+func TestTSVDataWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := &TSVDataWriter{}
+	assert.Nil(t, w.Write(&buf, []csvBase{{1}, {2}}))
+	assert.Equal(t, "id\n1\n2\n", buf.String())
+
+	buf.Reset()
+	assert.Nil(t, w.Write(&buf, [][]string{{"a b", "c"}}))
+	assert.Equal(t, "a b\tc\n", buf.String())
+
+	var ctx fasthttp.RequestCtx
+	w.SetHeader(&ctx.Response.Header)
+	assert.Equal(t, "text/tab-separated-values; charset=UTF-8", string(ctx.Response.Header.ContentType()))
+}
+
+func TestCSVDataWriterStream(t *testing.T) {
+	var streamErr error
+	codecs := NewCodecs()
+	codecs.Writers[CSV] = &CSVDataWriter{
+		StreamThreshold: 2,
+		StreamOptions:   StreamOptions{OnError: func(err error) { streamErr = err }},
+	}
+	router := routing.New()
+	router.SetCodecs(codecs)
+	router.Use(TypeNegotiator(JSON, CSV))
+	router.Get("/small", func(c *routing.Context) error {
+		return c.Write([]csvBase{{1}, {2}})
+	})
+	router.Get("/large", func(c *routing.Context) error {
+		return c.Write([]csvBase{{1}, {2}, {3}})
+	})
+	router.Get("/channel", func(c *routing.Context) error {
+		ch := make(chan csvBase)
+		close(ch)
+		return c.Write(ch)
+	})
+	router.Get("/error", func(c *routing.Context) error {
+		return c.Write(StreamFunc(func(yield func(interface{}) bool) error {
+			yield([]string{"a"})
+			return errors.New("db failure")
+		}))
+	})
+
+	tests := []struct {
+		tag, uri, accept, body string
+		streamed               bool
+	}{
+		{"t1", "/small", "text/csv", "id\n1\n2\n", false},
+		{"t2", "/large", "text/csv", "id\n1\n2\n3\n", true},
+		{"t3", "/channel", "text/csv", "id\n", true},
+		{"t4", "/small", "application/json", "[{\"ID\":1},{\"ID\":2}]\n", false},
+		{"t5", "/error", "text/csv", "a\n", true},
+	}
+	for _, test := range tests {
+		var ctx fasthttp.RequestCtx
+		ctx.Request.SetRequestURI(test.uri)
+		ctx.Request.Header.Set("Accept", test.accept)
+		router.HandleRequest(&ctx)
+		assert.Equal(t, test.streamed, ctx.Response.IsBodyStream(), test.tag)
+		assert.Equal(t, test.body, string(ctx.Response.Body()), test.tag)
+	}
+	assert.EqualError(t, streamErr, "db failure")
+}
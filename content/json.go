@@ -0,0 +1,239 @@
+package content
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/jackwhelpton/fasthttp-routing/v2"
+	"github.com/valyala/fasthttp"
+)
+
+// JavaScript is the MIME type of JSONP responses.
+const JavaScript = "application/javascript"
+
+// jsonpPrefix precedes the callback name in JSONP responses. The empty comment prevents the response
+// from being interpreted as another content type, such as a Flash file, when the callback name is controlled by an attacker.
+const jsonpPrefix = "/**/"
+
+// maxCallbackLength is the maximum length of a JSONP callback name.
+const maxCallbackLength = 128
+
+var callbackPattern = regexp.MustCompile(`^[A-Za-z_$][0-9A-Za-z_$]*(\.[A-Za-z_$][0-9A-Za-z_$]*)*$`)
+
+// jsonShape describes how the client requested the JSON output to be shaped.
+type jsonShape struct {
+	pretty   bool
+	indent   string
+	fields   fieldSet
+	callback string
+}
+
+// shapeOf returns the shape requested for the given data by the request being written to, if any.
+func (w *JSONDataWriter) shapeOf(res io.Writer, data interface{}) (shape jsonShape, err error) {
+	ctx, ok := res.(*fasthttp.RequestCtx)
+	if !ok {
+		return
+	}
+	args := ctx.QueryArgs()
+	if w.Pretty {
+		shape.pretty = args.Has("pretty") && isTrue(string(args.Peek("pretty")))
+		for _, r := range AcceptMediaTypes(ctx) {
+			if p, ok := r.Parameters["pretty"]; ok && r.Type+"/"+r.Subtype == JSON && isTrue(p) {
+				shape.pretty = true
+			}
+		}
+		if shape.indent = w.Indent; shape.indent == "" {
+			shape.indent = "  "
+		}
+	}
+	if _, isError := data.(routing.HTTPError); w.SparseFields && !isError {
+		shape.fields = parseFields(string(args.Peek("fields")))
+	}
+	if w.JSONP {
+		param := w.CallbackParam
+		if param == "" {
+			param = "callback"
+		}
+		if shape.callback = string(args.Peek(param)); shape.callback != "" {
+			if len(shape.callback) > maxCallbackLength || !callbackPattern.MatchString(shape.callback) {
+				return shape, routing.NewHTTPError(fasthttp.StatusBadRequest, "invalid JSONP callback name")
+			}
+			ctx.SetContentType(JavaScript + "; charset=UTF-8")
+			ctx.Response.Header.Set("X-Content-Type-Options", "nosniff")
+		}
+	}
+	return shape, nil
+}
+
+// isTrue checks if a parameter value enables an option. An empty value, as in "?pretty", enables it.
+func isTrue(value string) bool {
+	b, err := strconv.ParseBool(value)
+	return value == "" || err == nil && b
+}
+
+// empty checks if the output is written as it is.
+func (s *jsonShape) empty() bool {
+	return !s.pretty && s.fields == nil && s.callback == ""
+}
+
+// write writes the given data in JSON format with the requested shape.
+func (s *jsonShape) write(res io.Writer, data interface{}) error {
+	var buf bytes.Buffer
+	if err := encodeJSON(&buf, data); err != nil {
+		return err
+	}
+	out, err := filterJSON(bytes.TrimSuffix(buf.Bytes(), []byte{'\n'}), s.fields)
+	if err != nil {
+		return err
+	}
+	if s.pretty {
+		var indented bytes.Buffer
+		if err := json.Indent(&indented, out, "", s.indent); err != nil {
+			return err
+		}
+		out = indented.Bytes()
+	}
+	if s.callback != "" {
+		_, err = io.WriteString(res, jsonpPrefix+s.callback+"(")
+		if err == nil {
+			_, err = res.Write(out)
+		}
+		if err == nil {
+			_, err = io.WriteString(res, ");\n")
+		}
+		return err
+	}
+	if _, err = res.Write(out); err == nil {
+		_, err = res.Write([]byte{'\n'})
+	}
+	return err
+}
+
+// filterStream returns a stream producing the items of the given stream with their fields filtered.
+func (s *jsonShape) filterStream(stream StreamFunc) StreamFunc {
+	if s.fields == nil {
+		return stream
+	}
+	return func(yield func(interface{}) bool) error {
+		var err error
+		streamErr := stream(func(item interface{}) bool {
+			if item == flushMarker {
+				return yield(item)
+			}
+			var buf bytes.Buffer
+			if err = encodeJSON(&buf, item); err != nil {
+				return false
+			}
+			var out []byte
+			if out, err = filterJSON(bytes.TrimSuffix(buf.Bytes(), []byte{'\n'}), s.fields); err != nil {
+				return false
+			}
+			return yield(json.RawMessage(out))
+		})
+		if err != nil {
+			return err
+		}
+		return streamErr
+	}
+}
+
+// fieldSet is a set of selected object fields. A nil value selects the whole field,
+// while a non-nil value selects the fields of the nested object.
+type fieldSet map[string]fieldSet
+
+// parseFields parses a comma-separated list of dot-separated field paths.
+// Nil is returned if the list is empty, meaning that the fields are not filtered.
+func parseFields(list string) fieldSet {
+	var fields fieldSet
+	for _, path := range strings.Split(list, ",") {
+		if path = strings.TrimSpace(path); path == "" {
+			continue
+		}
+		if fields == nil {
+			fields = fieldSet{}
+		}
+		set := fields
+		names := strings.Split(path, ".")
+		for i, name := range names {
+			sub, ok := set[name]
+			if ok && sub == nil {
+				// the whole field is already selected
+				break
+			}
+			if i == len(names)-1 {
+				set[name] = nil
+				break
+			}
+			if sub == nil {
+				sub = fieldSet{}
+				set[name] = sub
+			}
+			set = sub
+		}
+	}
+	return fields
+}
+
+// filterJSON returns the JSON value keeping only the selected fields of its objects.
+// The fields of the objects in arrays are filtered the same way. Other values are returned as they are.
+func filterJSON(data []byte, fields fieldSet) ([]byte, error) {
+	if fields == nil || len(data) == 0 || data[0] != '{' && data[0] != '[' {
+		return data, nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	buf.WriteByte(data[0])
+	first := true
+	for dec.More() {
+		selected := fields
+		if data[0] == '{' {
+			token, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			key, _ := token.(string)
+			var ok bool
+			if selected, ok = fields[key]; !ok {
+				var skipped json.RawMessage
+				if err := dec.Decode(&skipped); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			if !first {
+				buf.WriteByte(',')
+			}
+			if err := encodeJSON(&buf, key); err != nil {
+				return nil, err
+			}
+			buf.Truncate(buf.Len() - 1)
+			buf.WriteByte(':')
+		} else if !first {
+			buf.WriteByte(',')
+		}
+		first = false
+
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return nil, err
+		}
+		filtered, err := filterJSON(value, selected)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(filtered)
+	}
+	if data[0] == '{' {
+		buf.WriteByte('}')
+	} else {
+		buf.WriteByte(']')
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,112 @@
+package content
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/jackwhelpton/fasthttp-routing/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+type jsonOwner struct {
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+type jsonProject struct {
+	ID    int        `json:"id"`
+	Name  string     `json:"name"`
+	Owner *jsonOwner `json:"owner"`
+	Tags  []string   `json:"tags"`
+}
+
+var jsonProjects = []jsonProject{
+	{1, "a", &jsonOwner{"x", "x@example.com"}, []string{"t"}},
+	{2, "b", nil, nil},
+}
+
+func TestJSONDataWriterShaping(t *testing.T) {
+	w := &JSONDataWriter{Pretty: true, SparseFields: true, JSONP: true}
+	tests := []struct {
+		tag         string
+		uri         string
+		accept      string
+		data        interface{}
+		contentType string
+		body        string
+	}{
+		{"t1", "/", "", jsonProjects[1], "application/json", `{"id":2,"name":"b","owner":null,"tags":null}` + "\n"},
+		{"t2", "/?pretty=1", "", jsonOwner{"x", "y"}, "application/json", "{\n  \"name\": \"x\",\n  \"email\": \"y\"\n}\n"},
+		{"t3", "/?pretty", "", []int{1}, "application/json", "[\n  1\n]\n"},
+		{"t4", "/?pretty=false", "", []int{1}, "application/json", "[1]\n"},
+		{"t5", "/", "application/json; pretty=true", []int{1}, "application/json", "[\n  1\n]\n"},
+		{"t6", "/", "text/html; pretty=true, application/json", []int{1}, "application/json", "[1]\n"},
+		{"t7", "/?fields=id,owner.email", "", jsonProjects, "application/json",
+			`[{"id":1,"owner":{"email":"x@example.com"}},{"id":2,"owner":null}]` + "\n"},
+		{"t8", "/?fields=owner.email,owner,tags", "", jsonProjects[0], "application/json",
+			`{"owner":{"name":"x","email":"x@example.com"},"tags":["t"]}` + "\n"},
+		{"t9", "/?fields=id,x.y&pretty=1", "", map[string]interface{}{"id": 1, "x": map[string]int{"y": 1, "z": 2}, "<z>": 3},
+			"application/json", "{\n  \"id\": 1,\n  \"x\": {\n    \"y\": 1\n  }\n}\n"},
+		{"t10", "/?fields=", "", jsonOwner{"x", "y"}, "application/json", `{"name":"x","email":"y"}` + "\n"},
+		{"t11", "/?fields=message", "", routing.NewHTTPError(404), "application/json", `{"status":404,"message":"Not Found"}` + "\n"},
+		{"t12", "/?callback=app.handle_1&fields=name", "", jsonOwner{"x", "y"}, "application/javascript; charset=UTF-8",
+			`/**/app.handle_1({"name":"x"});` + "\n"},
+		{"t13", "/?callback=$cb&pretty=1", "", []int{1}, "application/javascript; charset=UTF-8", "/**/$cb([\n  1\n]);\n"},
+	}
+	for _, test := range tests {
+		var ctx fasthttp.RequestCtx
+		ctx.Request.SetRequestURI(test.uri)
+		ctx.Request.Header.Set("Accept", test.accept)
+		w.SetHeader(&ctx.Response.Header)
+		data := test.data
+		if he, ok := data.(routing.HTTPError); ok {
+			c := routing.NewContext(&ctx)
+			c.SetDataWriter(w)
+			assert.Nil(t, c.WriteError(he), test.tag)
+		} else {
+			assert.Nil(t, w.Write(&ctx, data), test.tag)
+		}
+		assert.Equal(t, test.contentType, string(ctx.Response.Header.ContentType()), test.tag)
+		assert.Equal(t, test.body, string(ctx.Response.Body()), test.tag)
+	}
+
+	var ctx fasthttp.RequestCtx
+	ctx.Request.SetRequestURI("/?callback=alert(1)//")
+	err := w.Write(&ctx, []int{1})
+	if assert.NotNil(t, err) {
+		assert.Equal(t, fasthttp.StatusBadRequest, err.(routing.HTTPError).StatusCode())
+	}
+	assert.Empty(t, ctx.Response.Body())
+	assert.Empty(t, ctx.Response.Header.Peek("X-Content-Type-Options"))
+
+	// options are ignored unless enabled, or when not writing to a request context
+	ctx.Request.SetRequestURI("/?callback=cb&pretty=1&fields=id")
+	assert.Nil(t, (&JSONDataWriter{}).Write(&ctx, jsonProjects[1]))
+	assert.Equal(t, `{"id":2,"name":"b","owner":null,"tags":null}`+"\n", string(ctx.Response.Body()))
+	var buf bytes.Buffer
+	assert.Nil(t, w.Write(&buf, jsonProjects[1]))
+	assert.Equal(t, `{"id":2,"name":"b","owner":null,"tags":null}`+"\n", buf.String())
+}
+
+func TestJSONDataWriterShapingStream(t *testing.T) {
+	w := &JSONDataWriter{Pretty: true, SparseFields: true, JSONP: true, CallbackParam: "jsonp"}
+	var ctx fasthttp.RequestCtx
+	ctx.Request.SetRequestURI("/?jsonp=cb&fields=name&pretty=1")
+	w.SetHeader(&ctx.Response.Header)
+	projects := make(chan jsonProject, 2)
+	projects <- jsonProjects[0]
+	projects <- jsonProjects[1]
+	close(projects)
+	assert.Nil(t, w.Write(&ctx, projects))
+	assert.Equal(t, "application/javascript; charset=UTF-8", string(ctx.Response.Header.ContentType()))
+	assert.Equal(t, "nosniff", string(ctx.Response.Header.Peek("X-Content-Type-Options")))
+	assert.Equal(t, `/**/cb([{"name":"a"},{"name":"b"}]);`+"\n", string(ctx.Response.Body()))
+}
+
+func TestParseFields(t *testing.T) {
+	assert.Nil(t, parseFields(""))
+	assert.Nil(t, parseFields(" , "))
+	assert.Equal(t, fieldSet{"a": nil, "b": fieldSet{"c": nil, "d": fieldSet{"e": nil}}}, parseFields("a, b.c,b.d.e,a.x"))
+	assert.Equal(t, fieldSet{"a": nil}, parseFields("a.b,a"))
+}
@@ -0,0 +1,152 @@
+package content
+
+import (
+	"io"
+	"reflect"
+	"strconv"
+
+	"github.com/jackwhelpton/fasthttp-routing/v2"
+	"github.com/jackwhelpton/fasthttp-routing/v2/internal/jsonapi"
+	"github.com/valyala/fasthttp"
+)
+
+// JSONAPI is the MIME type of JSON:API documents.
+const JSONAPI = routing.MIME_JSONAPI
+
+// JSONAPIPage is a page of resources written by JSONAPIDataWriter together with pagination links.
+type JSONAPIPage struct {
+	// Data is the slice of resources in the page.
+	Data interface{}
+	// Number is the page number, starting from 1.
+	Number int
+	// Size is the maximum number of resources in a page.
+	Size int
+	// Total is the total number of resources, or a negative number if it is unknown.
+	Total int
+}
+
+// JSONAPIDataWriter sets the "Content-Type" response header as "application/vnd.api+json" and writes the given data
+// as a JSON:API document (https://jsonapi.org) to the response.
+//
+// The data is a resource struct, a slice of them, a JSONAPIPage, or nil. A struct is mapped to a resource object
+// with `jsonapi` struct tags: "primary,<type>" marks the field holding the resource ID, which is a string or an integer,
+// while "attr,<name>" and "relation,<name>" map the attributes and the relationships, and may be followed by ",omitempty".
+// Attributes are encoded in JSON format, and relationships are pointers to resources, resources, or slices of them.
+//
+//     type Article struct {
+//         ID       int        `jsonapi:"primary,articles"`
+//         Title    string     `jsonapi:"attr,title"`
+//         Author   *Person    `jsonapi:"relation,author"`
+//         Comments []*Comment `jsonapi:"relation,comments,omitempty"`
+//     }
+//
+// The related resources named by the "include" query parameter, such as "?include=author,comments.author",
+// are added to the document as included resources. An unknown relationship results in a 400 HTTP error.
+//
+// For a JSONAPIPage, the "self", "first", "prev", "next" and "last" links are built with the URL of the current route,
+// in which the "page[number]" and "page[size]" query parameters are set, and the total is written as meta information.
+// An HTTPError is written as a JSON:API error object.
+type JSONAPIDataWriter struct{}
+
+// SetHeader sets the "Content-Type" response header as "application/vnd.api+json".
+func (w *JSONAPIDataWriter) SetHeader(h *fasthttp.ResponseHeader) {
+	h.SetContentType(JSONAPI)
+}
+
+// Write writes the given data as a JSON:API document to the response.
+// Pagination links are built with the request path, as no route is known when the data is written without a routing.Context.
+func (w *JSONAPIDataWriter) Write(res io.Writer, data interface{}) error {
+	ctx, _ := res.(*fasthttp.RequestCtx)
+	return w.write(res, ctx, nil, data)
+}
+
+// WriteView writes the given data as a JSON:API document to the response. The name is ignored.
+func (w *JSONAPIDataWriter) WriteView(c *routing.Context, name string, data interface{}) error {
+	return w.write(c.RequestCtx, c.RequestCtx, c, data)
+}
+
+func (w *JSONAPIDataWriter) write(res io.Writer, ctx *fasthttp.RequestCtx, c *routing.Context, data interface{}) error {
+	if he, ok := data.(routing.HTTPError); ok {
+		status := he.StatusCode()
+		doc := &jsonapi.Document{Errors: []*jsonapi.ErrorObject{{
+			Status: strconv.Itoa(status),
+			Title:  fasthttp.StatusMessage(status),
+		}}}
+		if msg := he.Error(); msg != doc.Errors[0].Title {
+			doc.Errors[0].Detail = msg
+		}
+		return encodeJSON(res, doc)
+	}
+
+	page, isPage := data.(JSONAPIPage)
+	if p, ok := data.(*JSONAPIPage); ok && p != nil {
+		page, isPage = *p, true
+	}
+	if isPage {
+		data = page.Data
+	}
+	var include [][]string
+	if ctx != nil {
+		include = jsonapi.ParseInclude(string(ctx.QueryArgs().Peek("include")))
+	}
+	doc, err := jsonapi.Marshal(data, include)
+	if _, ok := err.(*jsonapi.IncludeError); ok {
+		return routing.NewHTTPError(fasthttp.StatusBadRequest, err.Error())
+	} else if err != nil {
+		return err
+	}
+	if isPage && ctx != nil {
+		doc.Links = pageLinks(ctx, c, page)
+		if page.Total >= 0 {
+			doc.Meta = map[string]interface{}{"total": page.Total}
+		}
+	}
+	return encodeJSON(res, doc)
+}
+
+// pageLinks returns the pagination links of the given page. The query parameters of the request are kept in the links.
+func pageLinks(ctx *fasthttp.RequestCtx, c *routing.Context, page JSONAPIPage) map[string]string {
+	path := string(ctx.Path())
+	if c != nil && c.Route() != nil {
+		var pairs []interface{}
+		for name, value := range c.Params() {
+			pairs = append(pairs, name, value)
+		}
+		path = c.Route().URL(pairs...)
+	}
+	link := func(number int) string {
+		var args fasthttp.Args
+		ctx.QueryArgs().CopyTo(&args)
+		args.SetUint("page[number]", number)
+		if page.Size > 0 {
+			args.SetUint("page[size]", page.Size)
+		}
+		return path + "?" + args.String()
+	}
+
+	number := page.Number
+	if number < 1 {
+		number = 1
+	}
+	links := map[string]string{"self": link(number), "first": link(1)}
+	if number > 1 {
+		links["prev"] = link(number - 1)
+	}
+	if page.Size <= 0 {
+		return links
+	}
+	if page.Total >= 0 {
+		last := (page.Total + page.Size - 1) / page.Size
+		if last < 1 {
+			last = 1
+		}
+		links["last"] = link(last)
+		if number < last {
+			links["next"] = link(number + 1)
+		}
+	} else if v := reflect.Indirect(reflect.ValueOf(page.Data)); (v.Kind() == reflect.Slice || v.Kind() == reflect.Array) && v.Len() >= page.Size {
+		// a full page of unknown total may be followed by another one
+		links["next"] = link(number + 1)
+	}
+	return links
+}
@@ -0,0 +1,103 @@
+package content
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/jackwhelpton/fasthttp-routing/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+type apiPerson struct {
+	ID   string `jsonapi:"primary,people"`
+	Name string `jsonapi:"attr,name"`
+}
+
+type apiArticle struct {
+	ID     int        `jsonapi:"primary,articles"`
+	Title  string     `jsonapi:"attr,title"`
+	Author *apiPerson `jsonapi:"relation,author"`
+}
+
+func TestJSONAPIDataWriter(t *testing.T) {
+	w := &JSONAPIDataWriter{}
+	var ctx fasthttp.RequestCtx
+	ctx.Request.SetRequestURI("/articles/1?include=author")
+	w.SetHeader(&ctx.Response.Header)
+	assert.Nil(t, w.Write(&ctx, &apiArticle{1, "a", &apiPerson{"x", "X"}}))
+	assert.Equal(t, "application/vnd.api+json", string(ctx.Response.Header.ContentType()))
+	assert.Equal(t, `{"data":{"type":"articles","id":"1","attributes":{"title":"a"},"relationships":{"author":{"data":{"type":"people","id":"x"}}}},`+
+		`"included":[{"type":"people","id":"x","attributes":{"name":"X"}}]}`+"\n", string(ctx.Response.Body()))
+
+	ctx.Response.ResetBody()
+	ctx.Request.SetRequestURI("/articles/1?include=comments")
+	err := w.Write(&ctx, &apiArticle{ID: 1})
+	if assert.NotNil(t, err) {
+		assert.Equal(t, fasthttp.StatusBadRequest, err.(routing.HTTPError).StatusCode())
+		assert.Equal(t, `jsonapi: resource type "articles" has no relationship "comments"`, err.Error())
+	}
+	assert.Empty(t, ctx.Response.Body())
+
+	var buf bytes.Buffer
+	assert.Nil(t, w.Write(&buf, nil))
+	assert.Equal(t, `{"data":null}`+"\n", buf.String())
+
+	buf.Reset()
+	assert.Nil(t, w.Write(&buf, routing.NewHTTPError(fasthttp.StatusNotFound)))
+	assert.Equal(t, `{"errors":[{"status":"404","title":"Not Found"}]}`+"\n", buf.String())
+	buf.Reset()
+	assert.Nil(t, w.Write(&buf, routing.NewHTTPError(fasthttp.StatusConflict, "stale")))
+	assert.Equal(t, `{"errors":[{"status":"409","title":"Conflict","detail":"stale"}]}`+"\n", buf.String())
+}
+
+func TestJSONAPIPagination(t *testing.T) {
+	people := []apiPerson{{"a", "A"}, {"b", "B"}}
+	router := routing.New()
+	router.Use(TypeNegotiator(JSONAPI))
+	router.Get("/teams/<team>/people", func(c *routing.Context) error {
+		total := 5
+		if c.QueryArgs().Has("unknown") {
+			total = -1
+		}
+		number, _ := c.QueryArgs().GetUint("page[number]")
+		return c.Write(&JSONAPIPage{Data: people, Number: number, Size: 2, Total: total})
+	})
+
+	tests := []struct {
+		tag   string
+		uri   string
+		links string
+		meta  string
+	}{
+		{"t1", "/teams/x%20y/people", `"first":"/teams/x+y/people?page%5Bnumber%5D=1&page%5Bsize%5D=2",` +
+			`"last":"/teams/x+y/people?page%5Bnumber%5D=3&page%5Bsize%5D=2",` +
+			`"next":"/teams/x+y/people?page%5Bnumber%5D=2&page%5Bsize%5D=2",` +
+			`"self":"/teams/x+y/people?page%5Bnumber%5D=1&page%5Bsize%5D=2"`, `,"meta":{"total":5}`},
+		{"t2", "/teams/a/people?page[number]=3&sort=name", `"first":"/teams/a/people?page%5Bnumber%5D=1&sort=name&page%5Bsize%5D=2",` +
+			`"last":"/teams/a/people?page%5Bnumber%5D=3&sort=name&page%5Bsize%5D=2",` +
+			`"prev":"/teams/a/people?page%5Bnumber%5D=2&sort=name&page%5Bsize%5D=2",` +
+			`"self":"/teams/a/people?page%5Bnumber%5D=3&sort=name&page%5Bsize%5D=2"`, `,"meta":{"total":5}`},
+		{"t3", "/teams/a/people?unknown&page[number]=2", `"first":"/teams/a/people?unknown&page%5Bnumber%5D=1&page%5Bsize%5D=2",` +
+			`"next":"/teams/a/people?unknown&page%5Bnumber%5D=3&page%5Bsize%5D=2",` +
+			`"prev":"/teams/a/people?unknown&page%5Bnumber%5D=1&page%5Bsize%5D=2",` +
+			`"self":"/teams/a/people?unknown&page%5Bnumber%5D=2&page%5Bsize%5D=2"`, ``},
+	}
+	for _, test := range tests {
+		var ctx fasthttp.RequestCtx
+		ctx.Request.SetRequestURI(test.uri)
+		ctx.Request.Header.Set("Accept", JSONAPI)
+		router.HandleRequest(&ctx)
+		assert.Equal(t, fasthttp.StatusOK, ctx.Response.StatusCode(), test.tag)
+		assert.Equal(t, `{"data":[{"type":"people","id":"a","attributes":{"name":"A"}},{"type":"people","id":"b","attributes":{"name":"B"}}],`+
+			`"links":{`+test.links+`}`+test.meta+`}`+"\n", string(ctx.Response.Body()), test.tag)
+	}
+
+	// errors are written as error objects
+	var ctx fasthttp.RequestCtx
+	ctx.Request.SetRequestURI("/teams/a/people?include=team")
+	router.HandleRequest(&ctx)
+	assert.Equal(t, fasthttp.StatusBadRequest, ctx.Response.StatusCode())
+	assert.Equal(t, `{"errors":[{"status":"400","title":"Bad Request","detail":"jsonapi: resource type \"people\" has no relationship \"team\""}]}`+"\n",
+		string(ctx.Response.Body()))
+}
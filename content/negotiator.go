@@ -4,38 +4,14 @@
 package content
 
 import (
-	"strconv"
 	"strings"
 
+	"github.com/jackwhelpton/fasthttp-routing/v2/internal/accept"
 	"github.com/valyala/fasthttp"
 )
 
 // AcceptRange represents a media-range contained within an Accept header.
-type AcceptRange struct {
-	// Type represents the media type.
-	Type string
-	// Subtype represents the media subtype.
-	Subtype string
-	// Weight represents the weight (quality factor) of this range.
-	Weight float64
-	// Parameters represents the parameters that are applicable to this range.
-	Parameters map[string]string
-	raw        string
-}
-
-// RawString returns the raw string for this accept range.
-func (a AcceptRange) RawString() string {
-	return a.raw
-}
-
-// https://tools.ietf.org/html/rfc7231#section-5.3.2
-// Accept = #( media-range [ accept-params ] )
-//  media-range    = ( "*/*"
-//                   / ( type "/" "*" )
-//                   / ( type "/" subtype )
-//                   ) *( OWS ";" OWS parameter )
-//  accept-params  = weight *( accept-ext )
-//  accept-ext = OWS ";" OWS token [ "=" ( token / quoted-string ) ]
+type AcceptRange = accept.Range
 
 // AcceptMediaTypes returns the set of accepted media ranges.
 func AcceptMediaTypes(ctx *fasthttp.RequestCtx) []AcceptRange {
@@ -44,133 +20,50 @@ func AcceptMediaTypes(ctx *fasthttp.RequestCtx) []AcceptRange {
 
 // ParseAcceptRanges returns the set of accepted media ranges from an Accept header.
 func ParseAcceptRanges(accepts string) []AcceptRange {
-	result := []AcceptRange{}
-	remaining := accepts
-	for {
-		var accept string
-		accept, remaining = extractFieldAndSkipToken(remaining, ',')
-		result = append(result, ParseAcceptRange(accept))
-		if len(remaining) == 0 {
-			break
-		}
-	}
-	return result
+	return accept.ParseRanges(accepts)
 }
 
 // ParseAcceptRange returns the media range, params and quality factor (weight) from an Accept range.
-func ParseAcceptRange(accept string) AcceptRange {
-	typeAndSub, rawparams := extractFieldAndSkipToken(accept, ';')
-
-	tp, subtp := extractFieldAndSkipToken(typeAndSub, '/')
-	params := extractParams(rawparams)
-
-	w := extractWeight(params)
-	return AcceptRange{Type: tp, Subtype: subtp, Parameters: params, Weight: w, raw: accept}
+// The range may be matched against a media type with its Match method, which supports wildcards, as in "*/*" and
+// "text/*", structured syntax suffixes (RFC 6839), as in "application/*+json", and unversioned vendor media types,
+// such as "application/vnd.acme+json", which match every version of them (see ParseVendorType).
+func ParseAcceptRange(r string) AcceptRange {
+	return accept.ParseRange(r)
 }
 
-func extractWeight(params map[string]string) float64 {
-	if w, ok := params["q"]; ok {
-		res, err := strconv.ParseFloat(w, 64)
-		if err == nil {
-			return res
-		}
-	}
-	return 1 // default is 1
-}
-
-func extractParams(raw string) map[string]string {
-	params := map[string]string{}
-	rest := raw
-	for {
-		var p string
-		p, rest = extractFieldAndSkipToken(rest, ';')
-		if len(p) > 0 {
-			k, v := extractFieldAndSkipToken(p, '=')
-			params[k] = v
-		}
-		if len(rest) == 0 {
-			break
-		}
-	}
-
-	return params
-}
-
-func extractFieldAndSkipToken(s string, sep rune) (string, string) {
-	f, r := extractField(s, sep)
-	if len(r) > 0 {
-		r = r[1:]
+// NegotiateContentType returns the best possible response type from a set of options, based on the Accept header.
+// If none of the options is acceptable, the default option is returned.
+//
+// The weight of an option is given by the most specific media range matching it (RFC 7231, Section 5.3.2),
+// and an option of zero weight is not acceptable. Among options of the same weight, the one matched by the most
+// specific media range is preferred, followed by the first one. A request without an Accept header accepts any option.
+func NegotiateContentType(ctx *fasthttp.RequestCtx, offers []string, defaultOffer string) string {
+	if i := negotiateContentType(ctx, offers); i >= 0 {
+		return offers[i]
 	}
-	return f, r
+	return defaultOffer
 }
 
-func extractField(s string, sep rune) (field, rest string) {
-	field = s
-	for i, v := range s {
-		if v == sep {
-			field = strings.TrimSpace(s[:i])
-			rest = strings.TrimSpace(s[i:])
-			break
-		}
-	}
-	return
+// negotiateContentType returns the index of the best acceptable offer, or -1 if none of them is acceptable.
+func negotiateContentType(ctx *fasthttp.RequestCtx, offers []string) int {
+	return accept.Negotiate(string(ctx.Request.Header.Peek("Accept")), offers)
 }
 
-func compareParams(params1 map[string]string, params2 map[string]string) (count int) {
-	for k1, v1 := range params1 {
-		if v2, ok := params2[k1]; ok && v1 == v2 {
-			count++
+// offerFor returns the index of the first offer of the given MIME type, ignoring parameters, or -1 if there is none.
+// An offer with a structured syntax suffix, such as "application/vnd.acme.v2+json", is of the type named after
+// the suffix, such as "application/json", if no offer is of the given type.
+func offerFor(offers []string, mediaType string) int {
+	t := ParseAcceptRange(mediaType)
+	for i, offer := range offers {
+		if o := ParseAcceptRange(offer); strings.EqualFold(o.Type, t.Type) && strings.EqualFold(o.Subtype, t.Subtype) {
+			return i
 		}
 	}
-	return count
-}
-
-// NegotiateContentType returns the best possible response type from a set of options, based on the Accept header.
-func NegotiateContentType(ctx *fasthttp.RequestCtx, offers []string, defaultOffer string) string {
-	accepts := AcceptMediaTypes(ctx)
-	offerRanges := []AcceptRange{}
-	for _, off := range offers {
-		offerRanges = append(offerRanges, ParseAcceptRange(off))
-	}
-
-	return negotiateContentType(accepts, offerRanges, ParseAcceptRange(defaultOffer))
-}
-
-func negotiateContentType(accepts []AcceptRange, offers []AcceptRange, defaultOffer AcceptRange) string {
-	best := defaultOffer.RawString()
-	bestWeight := float64(0)
-	bestParams := 0
-
-	for _, offer := range offers {
-		for _, accept := range accepts {
-			// add a booster on the weights to prefer more exact matches to wildcards
-			// such that: */* = 0, x/* = 1, x/x = 2
-			booster := float64(0)
-			if accept.Type != "*" {
-				booster++
-				if accept.Subtype != "*" {
-					booster++
-				}
-			}
-
-			if bestWeight > (accept.Weight + booster) {
-				continue // we already have something better..
-			} else if accept.Type == "*" && accept.Subtype == "*" {
-				best = offer.RawString()
-				bestWeight = accept.Weight + booster
-			} else if accept.Subtype == "*" && offer.Type == accept.Type {
-				best = offer.RawString()
-				bestWeight = accept.Weight + booster
-			} else if accept.Type == offer.Type && accept.Subtype == offer.Subtype {
-				paramCount := compareParams(accept.Parameters, offer.Parameters)
-				if paramCount >= bestParams { // if it's equal this one must be better, since the weight was better..
-					best = offer.RawString()
-					bestWeight = accept.Weight + booster
-					bestParams = paramCount
-				}
-			}
+	for i, offer := range offers {
+		o := ParseAcceptRange(offer)
+		if j := strings.LastIndexByte(o.Subtype, '+'); j >= 0 && strings.EqualFold(o.Type, t.Type) && strings.EqualFold(o.Subtype[j+1:], t.Subtype) {
+			return i
 		}
 	}
-
-	return best
+	return -1
 }
@@ -94,3 +94,35 @@ func TestAcceptElaborate(t *testing.T) {
 	assert.Equal(t, "text", mtypes[3].Type)
 	assert.Equal(t, "x-c", mtypes[3].Subtype)
 }
+
+func TestNegotiateContentType(t *testing.T) {
+	offers := []string{"application/json", "application/xml", "text/html;level=1", "application/vnd.api+json"}
+	tests := []struct {
+		tag    string
+		accept string
+		result string
+	}{
+		{"t1", "", "application/json"},
+		{"t2", "application/xml;q=0.5, */*;q=0.1", "application/xml"},
+		{"t3", "application/json;q=0, application/*;q=0.5", "application/xml"},
+		{"t4", "*/*, application/*;q=0", "text/html;level=1"},
+		{"t5", "text/html, text/html;level=1;q=0", "text/pdf"},
+		{"t6", "text/*;q=0.5, application/json;q=0.4", "text/html;level=1"},
+		{"t7", "application/*+json", "application/vnd.api+json"},
+		{"t8", "application/*+json, application/json;q=0.9", "application/vnd.api+json"},
+		{"t9", "application/*;q=0.8, application/json;q=0.1, application/*+json;q=0.2", "application/xml"},
+		{"t10", "*/*, text/html", "text/html;level=1"},
+		{"t11", "text/html;level=2, image/png", "text/pdf"},
+		{"t12", "application/json; pretty=1", "application/json"},
+		{"t13", "application/*+xml", "text/pdf"},
+	}
+	for _, test := range tests {
+		var ctx fasthttp.RequestCtx
+		ctx.Request.Header.Set("Accept", test.accept)
+		assert.Equal(t, test.result, NegotiateContentType(&ctx, offers, "text/pdf"), test.tag)
+	}
+
+	assert.True(t, ParseAcceptRange("application/*+JSON").Match(ParseAcceptRange("application/problem+json")))
+	assert.False(t, ParseAcceptRange("application/*+json").Match(ParseAcceptRange("application/json")))
+	assert.True(t, ParseAcceptRange("text/plain;charset=utf-8").Match(ParseAcceptRange("text/plain;charset=UTF-8")))
+}
@@ -0,0 +1,213 @@
+package content
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"reflect"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// NDJSON is the MIME type of newline delimited JSON, where each line of the response is a JSON value.
+const NDJSON = "application/x-ndjson"
+
+// DefaultFlushInterval is the maximum period during which streamed items are buffered before being sent to the client.
+const DefaultFlushInterval = time.Second
+
+// StreamFunc produces the items of a streamed response by calling yield with each item in turn.
+// yield returns false when the stream must be stopped, for example because the client has disconnected,
+// in which case StreamFunc should return immediately. An error returned by StreamFunc is reported
+// via StreamOptions.OnError.
+//
+//     c.Write(content.StreamFunc(func(yield func(interface{}) bool) error {
+//         rows, err := db.Query("SELECT * FROM users")
+//         if err != nil {
+//             return err
+//         }
+//         defer rows.Close()
+//         for rows.Next() {
+//             var u User
+//             if err := rows.Scan(&u.ID, &u.Name); err != nil {
+//                 return err
+//             }
+//             if !yield(u) {
+//                 break
+//             }
+//         }
+//         return rows.Err()
+//     }))
+type StreamFunc func(yield func(item interface{}) bool) error
+
+// StreamOptions configures how JSONDataWriter and NDJSONDataWriter stream their items.
+type StreamOptions struct {
+	// FlushInterval is the maximum period during which items are buffered before being sent to the client.
+	// Buffered items are also sent whenever a channel has no item ready. Defaults to DefaultFlushInterval.
+	FlushInterval time.Duration
+	// OnError is called with the error that stopped a stream, if any. The error may come from encoding an item,
+	// from writing to a client that has disconnected, or from a StreamFunc.
+	// The stream runs after the handler has returned, so OnError must not use the routing.Context of the request.
+	OnError func(err error)
+}
+
+// NDJSONDataWriter sets the "Content-Type" response header as "application/x-ndjson" and writes the given data
+// as newline delimited JSON. Channels and StreamFunc values are streamed to the client (see StreamOptions);
+// their items, as well as the elements of slices and arrays, are written one per line.
+// Any other value is written as a single line.
+//
+// When a stream is stopped because the client has disconnected, the remaining items of a channel
+// are received and discarded in the background so that the goroutine sending them is not blocked.
+type NDJSONDataWriter struct {
+	StreamOptions
+}
+
+// SetHeader sets the "Content-Type" response header as "application/x-ndjson".
+func (w *NDJSONDataWriter) SetHeader(h *fasthttp.ResponseHeader) {
+	h.SetContentType(NDJSON)
+}
+
+// Write writes the given data as newline delimited JSON.
+func (w *NDJSONDataWriter) Write(res io.Writer, data interface{}) error {
+	if stream, ok := streamOf(data); ok {
+		return w.stream(res, stream, nil, nil, nil)
+	}
+	rv := reflect.ValueOf(data)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array || rv.Type().Elem().Kind() == reflect.Uint8 {
+		return encodeJSON(res, data)
+	}
+	for i := 0; i < rv.Len(); i++ {
+		if err := encodeJSON(res, rv.Index(i).Interface()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// stream writes the items produced by the given stream, preceded by prefix, separated by sep and followed by suffix.
+// The items are written through fasthttp.RequestCtx.SetBodyStreamWriter() if res is a request context,
+// or directly to res otherwise.
+func (o *StreamOptions) stream(res io.Writer, stream StreamFunc, prefix, sep, suffix []byte) error {
+	return o.run(res, func(bw *bufio.Writer) error {
+		return o.writeStream(bw, stream, prefix, sep, suffix)
+	})
+}
+
+// run calls write through fasthttp.RequestCtx.SetBodyStreamWriter() if res is a request context,
+// reporting the returned error via OnError, or calls write with a writer buffering res otherwise.
+func (o *StreamOptions) run(res io.Writer, write func(bw *bufio.Writer) error) error {
+	if ctx, ok := res.(*fasthttp.RequestCtx); ok {
+		ctx.SetBodyStreamWriter(func(bw *bufio.Writer) {
+			if err := write(bw); err != nil && o.OnError != nil {
+				o.OnError(err)
+			}
+		})
+		return nil
+	}
+	return write(bufio.NewWriter(res))
+}
+
+// flushInterval returns the maximum period during which items are buffered.
+func (o *StreamOptions) flushInterval() time.Duration {
+	if o.FlushInterval <= 0 {
+		return DefaultFlushInterval
+	}
+	return o.FlushInterval
+}
+
+func (o *StreamOptions) writeStream(bw *bufio.Writer, stream StreamFunc, prefix, sep, suffix []byte) (err error) {
+	interval := o.flushInterval()
+	lastFlush := time.Now()
+	buf := new(bytes.Buffer)
+	first := true
+
+	bw.Write(prefix)
+	streamErr := stream(func(item interface{}) bool {
+		if item == flushMarker {
+			err = bw.Flush()
+			lastFlush = time.Now()
+			return err == nil
+		}
+		buf.Reset()
+		if err = encodeJSON(buf, item); err != nil {
+			return false
+		}
+		if !first {
+			bw.Write(sep)
+		}
+		first = false
+		// the separator determines the layout, so the trailing newline added by the encoder is dropped
+		if _, err = bw.Write(bytes.TrimSuffix(buf.Bytes(), []byte{'\n'})); err != nil {
+			return false
+		}
+		if sep == nil {
+			bw.WriteByte('\n')
+		}
+		if time.Since(lastFlush) >= interval {
+			err = bw.Flush()
+			lastFlush = time.Now()
+		}
+		return err == nil
+	})
+	if err != nil {
+		return err
+	}
+	if streamErr != nil {
+		return streamErr
+	}
+	bw.Write(suffix)
+	return bw.Flush()
+}
+
+// flushMarker is yielded by channel streams to flush the buffered items when no item is ready.
+var flushMarker = &struct{}{}
+
+// streamOf returns the StreamFunc producing the items of the given data if the data can be streamed,
+// that is, if it is a StreamFunc or a channel that can be received from.
+func streamOf(data interface{}) (StreamFunc, bool) {
+	switch d := data.(type) {
+	case StreamFunc:
+		return d, true
+	case func(func(interface{}) bool) error:
+		return d, true
+	}
+	ch := reflect.ValueOf(data)
+	if ch.Kind() != reflect.Chan || ch.Type().ChanDir()&reflect.RecvDir == 0 {
+		return nil, false
+	}
+	return func(yield func(interface{}) bool) error {
+		for {
+			v, ok := ch.TryRecv()
+			if !ok && !v.IsValid() {
+				// no item is ready: send the buffered items before waiting
+				if !yield(flushMarker) {
+					break
+				}
+				if v, ok = ch.Recv(); !ok {
+					return nil
+				}
+			} else if !ok {
+				return nil
+			}
+			if !yield(v.Interface()) {
+				break
+			}
+		}
+		go func() {
+			for {
+				if _, ok := ch.Recv(); !ok {
+					return
+				}
+			}
+		}()
+		return nil
+	}, true
+}
+
+// encodeJSON writes the given data in JSON format followed by a newline, without escaping HTML characters.
+func encodeJSON(w io.Writer, data interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	return enc.Encode(data)
+}
@@ -0,0 +1,187 @@
+package content
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jackwhelpton/fasthttp-routing/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func TestNDJSONDataWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := &NDJSONDataWriter{}
+	assert.Nil(t, w.Write(&buf, []interface{}{1, "<a>", map[string]int{"b": 2}}))
+	assert.Equal(t, "1\n\"<a>\"\n{\"b\":2}\n", buf.String())
+
+	buf.Reset()
+	assert.Nil(t, w.Write(&buf, map[string]int{"a": 1}))
+	assert.Equal(t, "{\"a\":1}\n", buf.String())
+
+	buf.Reset()
+	ch := make(chan int, 3)
+	ch <- 1
+	ch <- 2
+	close(ch)
+	assert.Nil(t, w.Write(&buf, ch))
+	assert.Equal(t, "1\n2\n", buf.String())
+
+	var ctx fasthttp.RequestCtx
+	w.SetHeader(&ctx.Response.Header)
+	assert.Equal(t, NDJSON, string(ctx.Response.Header.ContentType()))
+}
+
+func TestJSONDataWriterStream(t *testing.T) {
+	router := routing.New()
+	router.Use(TypeNegotiator(JSON, NDJSON))
+	router.Get("/users", func(c *routing.Context) error {
+		ch := make(chan string)
+		go func() {
+			for _, name := range []string{"a", "b", "c"} {
+				ch <- name
+			}
+			close(ch)
+		}()
+		return c.Write(ch)
+	})
+	router.Get("/empty", func(c *routing.Context) error {
+		return c.Write(StreamFunc(func(yield func(interface{}) bool) error {
+			return nil
+		}))
+	})
+
+	tests := []struct {
+		tag, uri, accept, body string
+	}{
+		{"t1", "/users", JSON, `["a","b","c"]` + "\n"},
+		{"t2", "/users", NDJSON, "\"a\"\n\"b\"\n\"c\"\n"},
+		{"t3", "/empty", JSON, "[]\n"},
+		{"t4", "/empty", NDJSON, ""},
+	}
+	for _, test := range tests {
+		var ctx fasthttp.RequestCtx
+		ctx.Request.Header.SetMethod("GET")
+		ctx.Request.SetRequestURI(test.uri)
+		ctx.Request.Header.Set("Accept", test.accept)
+		router.HandleRequest(&ctx)
+		assert.True(t, ctx.IsBodyStream(), test.tag)
+		assert.Equal(t, test.accept, string(ctx.Response.Header.ContentType()), test.tag)
+		assert.Equal(t, test.body, string(ctx.Response.Body()), test.tag)
+	}
+}
+
+func TestStreamErrors(t *testing.T) {
+	var streamErr error
+	w := &JSONDataWriter{StreamOptions: StreamOptions{OnError: func(err error) { streamErr = err }}}
+
+	var ctx fasthttp.RequestCtx
+	assert.Nil(t, w.Write(&ctx, StreamFunc(func(yield func(interface{}) bool) error {
+		yield(1)
+		return errors.New("query failed")
+	})))
+	assert.Equal(t, "[1", string(ctx.Response.Body()))
+	assert.EqualError(t, streamErr, "query failed")
+
+	streamErr = nil
+	ctx.Response.Reset()
+	assert.Nil(t, w.Write(&ctx, StreamFunc(func(yield func(interface{}) bool) error {
+		if yield(func() {}) {
+			t.Error("the stream should stop on encoding errors")
+		}
+		return nil
+	})))
+	assert.Equal(t, "[", string(ctx.Response.Body()))
+	assert.NotNil(t, streamErr)
+}
+
+type failingWriter struct {
+	n int
+}
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	if w.n--; w.n < 0 {
+		return 0, errors.New("connection closed")
+	}
+	return len(p), nil
+}
+
+func TestStreamDisconnect(t *testing.T) {
+	o := &StreamOptions{FlushInterval: time.Nanosecond}
+
+	yielded := 0
+	err := o.writeStream(bufio.NewWriter(&failingWriter{n: 2}), func(yield func(interface{}) bool) error {
+		for i := 0; i < 100; i++ {
+			if !yield(i) {
+				break
+			}
+			yielded++
+		}
+		return nil
+	}, nil, nil, nil)
+	assert.EqualError(t, err, "connection closed")
+	assert.Equal(t, 2, yielded)
+
+	// the items of a channel are drained after a disconnection so that the sender does not block
+	ch := make(chan int)
+	done := make(chan bool)
+	go func() {
+		for i := 0; i < 100; i++ {
+			ch <- i
+		}
+		close(ch)
+		done <- true
+	}()
+	stream, _ := streamOf(ch)
+	err = o.writeStream(bufio.NewWriter(&failingWriter{n: 1}), stream, nil, nil, nil)
+	assert.EqualError(t, err, "connection closed")
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Error("the channel sender is blocked")
+	}
+}
+
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestStreamFlush(t *testing.T) {
+	o := &StreamOptions{FlushInterval: time.Hour}
+	var out syncBuffer
+	ch := make(chan int)
+	go func() {
+		ch <- 1
+		// the buffered item is sent while the stream waits for the next one
+		for deadline := time.Now().Add(time.Second); out.String() != "1\n" && time.Now().Before(deadline); {
+			time.Sleep(time.Millisecond)
+		}
+		ch <- 2
+		close(ch)
+	}()
+	stream, _ := streamOf(ch)
+	assert.Nil(t, o.writeStream(bufio.NewWriter(&out), stream, nil, nil, nil))
+	assert.Equal(t, "1\n2\n", out.String())
+
+	_, ok := streamOf([]int{1})
+	assert.False(t, ok)
+	_, ok = streamOf(make(chan<- int))
+	assert.False(t, ok)
+}
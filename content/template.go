@@ -0,0 +1,234 @@
+package content
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html"
+	"html/template"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/jackwhelpton/fasthttp-routing/v2"
+	"github.com/valyala/fasthttp"
+)
+
+// Template is a route tag selecting the template rendered by TemplateDataWriter for the data written by the route.
+//
+//     router.Get("/users", content.TypeNegotiator(content.HTML, content.JSON), listUsers).Tag(content.Template("users/index"))
+type Template string
+
+// TemplateOptions specifies how TemplateDataWriter loads and renders templates.
+type TemplateOptions struct {
+	// Extension is the file extension of the templates. Defaults to ".html".
+	Extension string
+	// Layout is the name of the layout wrapping every page, such as "layouts/main".
+	// The layout renders the page with {{template "content" .}}. If empty, pages are rendered on their own.
+	Layout string
+	// ErrorTemplate is the name of the page rendering the errors written via routing.Context.WriteError().
+	// The page receives a routing.HTTPError. If empty, the error message is written as escaped text.
+	ErrorTemplate string
+	// Funcs are additional functions made available to the templates.
+	Funcs template.FuncMap
+	// Reload parses the templates again before rendering each page, so that changes to the template files
+	// are visible without restarting the application. It should only be enabled during development.
+	Reload bool
+}
+
+// TemplateDataWriter sets the "Content-Type" response header as "text/html; charset=UTF-8" and renders the given data
+// with html/template templates.
+//
+// The templates are loaded from a file system in which each file is a page, named after its path without extension
+// (for example, "users/index" for "users/index.html"). The files under the "layouts" directory are layouts, and the files
+// under the "partials" directory are partials that every page can include, such as {{template "partials/nav" .}}.
+//
+// The page rendered for a request is the one given to routing.Context.Render(), or the one tagged on the route
+// with a Template value when the data is written via routing.Context.Write(). If there is neither, the data is written
+// as it would be by HTMLDataWriter. Besides the functions given in TemplateOptions.Funcs, templates can call:
+//
+//   - url: builds the URL of a named route, as in {{url "user" "id" .ID}}
+//   - lang: returns the language negotiated by LanguageNegotiator, if any
+type TemplateDataWriter struct {
+	fsys    fs.FS
+	options TemplateOptions
+
+	mu    sync.RWMutex
+	pages map[string]*template.Template
+}
+
+// NewTemplateDataWriter creates a TemplateDataWriter rendering the templates found in the given file system.
+// An error is returned if the templates cannot be parsed.
+func NewTemplateDataWriter(fsys fs.FS, opts ...TemplateOptions) (*TemplateDataWriter, error) {
+	w := &TemplateDataWriter{fsys: fsys}
+	if len(opts) > 0 {
+		w.options = opts[0]
+	}
+	if w.options.Extension == "" {
+		w.options.Extension = ".html"
+	}
+	if err := w.load(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// LoadTemplates creates a TemplateDataWriter rendering the templates found in the given directory.
+//
+//     templates, err := content.LoadTemplates("views", content.TemplateOptions{Layout: "layouts/main"})
+//     if err != nil {
+//         panic(err)
+//     }
+//     content.DataWriters[content.HTML] = templates
+func LoadTemplates(dir string, opts ...TemplateOptions) (*TemplateDataWriter, error) {
+	return NewTemplateDataWriter(os.DirFS(dir), opts...)
+}
+
+// SetHeader sets the "Content-Type" response header as "text/html; charset=UTF-8".
+func (w *TemplateDataWriter) SetHeader(h *fasthttp.ResponseHeader) {
+	h.SetContentType(HTML + "; charset=UTF-8")
+}
+
+// Write calls routing.DefaultDataWriter to write the given data to the response, as no page is selected
+// when the data is written without a routing.Context.
+func (w *TemplateDataWriter) Write(res io.Writer, data interface{}) error {
+	return routing.DefaultDataWriter.Write(res, data)
+}
+
+// WriteView renders the named page with the given data. If name is empty, the page tagged on the route is rendered.
+func (w *TemplateDataWriter) WriteView(c *routing.Context, name string, data interface{}) error {
+	if he, ok := data.(routing.HTTPError); ok && name == "" {
+		if name = w.options.ErrorTemplate; name == "" {
+			_, err := c.WriteString(html.EscapeString(he.Error()))
+			return err
+		}
+	}
+	if name == "" {
+		name = routeTemplate(c.Route())
+	}
+	if name == "" {
+		return w.Write(c.RequestCtx, data)
+	}
+
+	if w.options.Reload {
+		if err := w.load(); err != nil {
+			return err
+		}
+	}
+	w.mu.RLock()
+	page, ok := w.pages[name]
+	w.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("content: template %q not found", name)
+	}
+	t, err := page.Clone()
+	if err != nil {
+		return err
+	}
+	t.Funcs(contextFuncs(c))
+
+	var buf bytes.Buffer
+	if w.options.Layout != "" {
+		err = t.ExecuteTemplate(&buf, w.options.Layout, data)
+	} else {
+		err = t.ExecuteTemplate(&buf, "content", data)
+	}
+	if err != nil {
+		return err
+	}
+	_, err = c.RequestCtx.Write(buf.Bytes())
+	return err
+}
+
+// load parses the templates found in the file system. Each page is parsed together with the layouts and partials
+// into its own template set, in which the page is named "content".
+func (w *TemplateDataWriter) load() error {
+	var pages, shared []string
+	sources := map[string]string{}
+	err := fs.WalkDir(w.fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || path.Ext(p) != w.options.Extension {
+			return err
+		}
+		source, err := fs.ReadFile(w.fsys, p)
+		if err != nil {
+			return err
+		}
+		name := strings.TrimSuffix(p, w.options.Extension)
+		sources[name] = string(source)
+		if strings.HasPrefix(name, "layouts/") || strings.HasPrefix(name, "partials/") {
+			shared = append(shared, name)
+		} else {
+			pages = append(pages, name)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	funcs := contextFuncs(nil)
+	for name, f := range w.options.Funcs {
+		funcs[name] = f
+	}
+	set := make(map[string]*template.Template, len(pages))
+	for _, page := range pages {
+		t := template.New("content").Funcs(funcs)
+		for _, name := range shared {
+			if _, err := t.New(name).Parse(sources[name]); err != nil {
+				return fmt.Errorf("content: parsing template %q: %v", name, err)
+			}
+		}
+		if _, err := t.Parse(sources[page]); err != nil {
+			return fmt.Errorf("content: parsing template %q: %v", page, err)
+		}
+		if w.options.Layout != "" && t.Lookup(w.options.Layout) == nil {
+			return fmt.Errorf("content: layout %q not found", w.options.Layout)
+		}
+		set[page] = t
+	}
+
+	w.mu.Lock()
+	w.pages = set
+	w.mu.Unlock()
+	return nil
+}
+
+// contextFuncs returns the template functions depending on the given request context.
+// If the context is nil, the functions are placeholders used when parsing the templates.
+func contextFuncs(c *routing.Context) template.FuncMap {
+	return template.FuncMap{
+		"url": func(name string, pairs ...interface{}) (string, error) {
+			if c == nil || c.Router() == nil {
+				return "", errors.New("url: no router is handling the request")
+			}
+			route := c.Router().Route(name)
+			if route == nil {
+				return "", fmt.Errorf("url: route %q not found", name)
+			}
+			return route.URL(pairs...), nil
+		},
+		"lang": func() string {
+			if c == nil {
+				return ""
+			}
+			language, _ := c.Get(Language).(string)
+			return language
+		},
+	}
+}
+
+// routeTemplate returns the template tagged on the given route, if any.
+func routeTemplate(route *routing.Route) string {
+	if route == nil {
+		return ""
+	}
+	for _, tag := range route.Tags() {
+		if t, ok := tag.(Template); ok {
+			return string(t)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,146 @@
+package content
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/jackwhelpton/fasthttp-routing/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func newTemplateFS() fstest.MapFS {
+	return fstest.MapFS{
+		"layouts/main.html": {Data: []byte(`<html lang="{{lang}}">{{template "partials/nav" .}}{{template "content" .}}</html>`)},
+		"partials/nav.html": {Data: []byte(`<a href="{{url "user" "id" 1}}">me</a>`)},
+		"users/show.html":   {Data: []byte(`<p>{{.Name}}</p>`)},
+		"users/index.html":  {Data: []byte(`{{range .}}<li>{{.}}</li>{{end}}`)},
+		"errors/http.html":  {Data: []byte(`<h1>{{.StatusCode}}</h1><p>{{.Error}}</p>`)},
+		"assets/readme.txt": {Data: []byte(`not a template`)},
+	}
+}
+
+func TestTemplateDataWriter(t *testing.T) {
+	w, err := NewTemplateDataWriter(newTemplateFS(), TemplateOptions{Layout: "layouts/main", ErrorTemplate: "errors/http"})
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	router := routing.New()
+	router.Use(LanguageNegotiator("en", "fr"), TypeNegotiator(HTML, JSON))
+	codecs := NewCodecs()
+	codecs.Writers[HTML] = w
+	router.SetCodecs(codecs)
+	router.Get("/users/<id>", func(c *routing.Context) error {
+		return c.Render("users/show", map[string]string{"Name": "<John>"})
+	}).Name("user")
+	router.Get("/users", func(c *routing.Context) error {
+		return c.Write([]string{"a", "b"})
+	}).Tag(Template("users/index"))
+	router.Get("/plain", func(c *routing.Context) error {
+		return c.Write("<b>plain</b>")
+	})
+	router.Get("/forbidden", func(c *routing.Context) error {
+		return routing.NewHTTPError(fasthttp.StatusForbidden, "<no>")
+	})
+	router.Get("/missing", func(c *routing.Context) error {
+		return c.Render("users/missing", nil)
+	})
+
+	tests := []struct {
+		tag, uri, accept, language string
+		status                     int
+		body                       string
+	}{
+		{"t1", "/users/1", "text/html", "fr", fasthttp.StatusOK, `<html lang="fr"><a href="/users/1">me</a><p>&lt;John&gt;</p></html>`},
+		{"t2", "/users", "text/html", "", fasthttp.StatusOK, `<html lang="en"><a href="/users/1">me</a><li>a</li><li>b</li></html>`},
+		{"t3", "/users/1", "application/json", "", fasthttp.StatusOK, `{"Name":"<John>"}` + "\n"},
+		{"t4", "/plain", "text/html", "", fasthttp.StatusOK, `<b>plain</b>`},
+		{"t5", "/forbidden", "text/html", "", fasthttp.StatusForbidden, `<html lang="en"><a href="/users/1">me</a><h1>403</h1><p>&lt;no&gt;</p></html>`},
+		{"t6", "/missing", "text/html", "", fasthttp.StatusInternalServerError, `<html lang="en"><a href="/users/1">me</a><h1>500</h1><p>Internal Server Error</p></html>`},
+	}
+	for _, test := range tests {
+		var ctx fasthttp.RequestCtx
+		ctx.Request.SetRequestURI(test.uri)
+		ctx.Request.Header.Set("Accept", test.accept)
+		ctx.Request.Header.Set("Accept-Language", test.language)
+		router.HandleRequest(&ctx)
+		assert.Equal(t, test.status, ctx.Response.StatusCode(), test.tag)
+		assert.Equal(t, test.body, string(ctx.Response.Body()), test.tag)
+	}
+}
+
+func TestTemplateDataWriterErrors(t *testing.T) {
+	// errors are written as escaped text when there is no error template
+	w, err := NewTemplateDataWriter(newTemplateFS())
+	if !assert.Nil(t, err) {
+		return
+	}
+	var ctx fasthttp.RequestCtx
+	c := routing.NewContext(&ctx)
+	c.SetDataWriter(w)
+	assert.Nil(t, c.WriteError(routing.NewHTTPError(fasthttp.StatusNotFound, "<gone>")))
+	assert.Equal(t, "text/html; charset=UTF-8", string(ctx.Response.Header.ContentType()))
+	assert.Equal(t, "&lt;gone&gt;", string(ctx.Response.Body()))
+
+	// partials are not pages, and url fails without a router
+	ctx.Response.Reset()
+	assert.NotNil(t, c.Render("partials/nav", nil))
+	fsys := newTemplateFS()
+	fsys["link.html"] = &fstest.MapFile{Data: []byte(`{{url "user" "id" 1}}`)}
+	w, err = NewTemplateDataWriter(fsys)
+	if assert.Nil(t, err) {
+		c.SetDataWriter(w)
+		assert.NotNil(t, c.Render("link", nil))
+	}
+
+	fsys = newTemplateFS()
+	fsys["users/broken.html"] = &fstest.MapFile{Data: []byte(`{{.Name`)}
+	_, err = NewTemplateDataWriter(fsys)
+	if assert.NotNil(t, err) {
+		assert.True(t, strings.Contains(err.Error(), `"users/broken"`), err.Error())
+	}
+	_, err = NewTemplateDataWriter(newTemplateFS(), TemplateOptions{Layout: "layouts/other"})
+	assert.NotNil(t, err)
+}
+
+func TestTemplateDataWriterReload(t *testing.T) {
+	fsys := fstest.MapFS{"hello.tmpl": {Data: []byte(`Hello {{.}}`)}}
+	render := func(w *TemplateDataWriter) string {
+		var ctx fasthttp.RequestCtx
+		c := routing.NewContext(&ctx)
+		c.SetDataWriter(w)
+		assert.Nil(t, c.Render("hello", "<you>"))
+		return string(ctx.Response.Body())
+	}
+
+	static, err := NewTemplateDataWriter(fsys, TemplateOptions{Extension: ".tmpl"})
+	assert.Nil(t, err)
+	reloaded, err := NewTemplateDataWriter(fsys, TemplateOptions{Extension: ".tmpl", Reload: true})
+	assert.Nil(t, err)
+	assert.Equal(t, "Hello &lt;you&gt;", render(static))
+
+	fsys["hello.tmpl"].Data = []byte(`Bye {{.}}`)
+	assert.Equal(t, "Hello &lt;you&gt;", render(static))
+	assert.Equal(t, "Bye &lt;you&gt;", render(reloaded))
+}
+
+func TestLoadTemplates(t *testing.T) {
+	dir := t.TempDir()
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "index.html"), []byte(`<p>{{upper .}}</p>`), 0644))
+	w, err := LoadTemplates(dir, TemplateOptions{Funcs: map[string]interface{}{"upper": strings.ToUpper}})
+	if !assert.Nil(t, err) {
+		return
+	}
+	var ctx fasthttp.RequestCtx
+	c := routing.NewContext(&ctx)
+	c.SetDataWriter(w)
+	assert.Nil(t, c.Render("index", "x"))
+	assert.Equal(t, "<p>X</p>", string(ctx.Response.Body()))
+
+	_, err = LoadTemplates(filepath.Join(dir, "missing"))
+	assert.NotNil(t, err)
+}
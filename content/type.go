@@ -6,9 +6,9 @@
 package content
 
 import (
-	"encoding/json"
 	"encoding/xml"
 	"io"
+	"strings"
 
 	"github.com/jackwhelpton/fasthttp-routing/v2"
 	"github.com/valyala/fasthttp"
@@ -23,54 +23,195 @@ const (
 )
 
 // DataWriters lists all supported content types and the corresponding data writers.
-// By default, JSON, XML, and HTML are supported. You may modify this variable before calling TypeNegotiator
+// By default, JSON, XML, HTML, NDJSON, CSV, TSV, MessagePack, CBOR and JSON:API are supported. You may modify this variable before calling TypeNegotiator
 // to customize supported data writers.
+//
+// DataWriters serves as the default registry. When a routing.Codecs registry is attached to the router
+// or the route group serving a request, TypeNegotiator looks up the data writers in that registry instead.
 var DataWriters = map[string]routing.DataWriter{
-	JSON: &JSONDataWriter{},
-	XML:  &XMLDataWriter{},
-	XML2: &XMLDataWriter{},
-	HTML: &HTMLDataWriter{},
+	JSON:     &JSONDataWriter{},
+	XML:      &XMLDataWriter{},
+	XML2:     &XMLDataWriter{},
+	HTML:     &HTMLDataWriter{},
+	NDJSON:   &NDJSONDataWriter{},
+	CSV:      &CSVDataWriter{},
+	TSV:      &TSVDataWriter{},
+	MsgPack:  &MsgPackDataWriter{},
+	MsgPack2: &MsgPackDataWriter{},
+	CBOR:     &CBORDataWriter{},
+	JSONAPI:  &JSONAPIDataWriter{},
+}
+
+// Extensions maps URL extensions to the MIME types of DataWriters. Assign a copy of it, as returned by
+// NewExtensions(), to routing.Router.Extensions so that the format of a response can be chosen with the extension
+// of the request path, as in "/reports/42.csv" (see TypeNegotiator).
+var Extensions = map[string]string{
+	"json":    JSON,
+	"xml":     XML,
+	"html":    HTML,
+	"ndjson":  NDJSON,
+	"csv":     CSV,
+	"tsv":     TSV,
+	"msgpack": MsgPack,
+	"cbor":    CBOR,
+}
+
+// NewExtensions returns a copy of Extensions, to which the extensions of other formats can be added
+// without affecting other routers.
+//
+//     router.Extensions = content.NewExtensions()
+//     router.Extensions["pdf"] = "application/pdf"
+func NewExtensions() map[string]string {
+	extensions := make(map[string]string, len(Extensions))
+	for ext, mediaType := range Extensions {
+		extensions[ext] = mediaType
+	}
+	return extensions
+}
+
+// NewCodecs creates a new routing.Codecs registry initialized with a copy of routing.DataReaders and DataWriters.
+func NewCodecs() *routing.Codecs {
+	codecs := routing.NewCodecs()
+	for t, w := range DataWriters {
+		codecs.Writers[t] = w
+	}
+	return codecs
 }
 
 // TypeNegotiator returns a content type negotiation handler.
 //
 // The method takes a list of response MIME types that are supported by the application.
-// The negotiator will determine the best response MIME type to use by checking the "Accept" HTTP header.
-// If no match is found, the first MIME type will be used.
+// The negotiator will determine the best response MIME type to use by checking the "Accept" HTTP header
+// as specified by RFC 7231 (see NegotiateContentType). If no match is found, the first MIME type will be used.
 //
 // The negotiator will set the "Content-Type" response header as the chosen MIME type. It will call routing.Context.SetDataWriter()
 // to set the appropriate data writer that can write data in the negotiated format.
+// The data writers are looked up in the routing.Codecs registry in effect for the request, or in DataWriters if there is none.
+// A MIME type with a structured syntax suffix and no data writer of its own, such as "application/vnd.acme.v2+json",
+// is written by the data writer of its suffix, such as "application/json", and echoed in the "Content-Type" header.
+// MIME types without a data writer are not offered; if none of them has one, a 500 HTTP error is returned.
+// When more than one MIME type is offered, "Accept" is added to the "Vary" response header.
+//
+// If the route was matched without the extension of the request path (see routing.Router.Extensions),
+// the MIME type of the extension is chosen regardless of the "Accept" header, and a 406 HTTP error listing
+// the supported MIME types is returned if it is not one of them.
+//
+// The version of the chosen vendor media type, such as "v2", is stored in routing.Context under the Version key,
+// and the versioned MIME types not listed in the Versions tags of the route, if any, are not offered.
 //
 // If you do not specify any supported MIME types, the negotiator will use "text/html" as the response MIME type.
+// The method panics if a MIME type is malformed.
 func TypeNegotiator(formats ...string) routing.Handler {
+	return typeNegotiator(false, formats)
+}
+
+// StrictTypeNegotiator returns a content type negotiation handler that works like TypeNegotiator,
+// except that a 406 HTTP error listing the supported MIME types is returned if none of them is acceptable.
+func StrictTypeNegotiator(formats ...string) routing.Handler {
+	return typeNegotiator(true, formats)
+}
+
+func typeNegotiator(strict bool, formats []string) routing.Handler {
 	if len(formats) == 0 {
 		formats = []string{HTML}
 	}
 	for _, format := range formats {
-		if _, ok := DataWriters[format]; !ok {
+		if r := ParseAcceptRange(format); r.Type == "" || r.Subtype == "" {
 			panic(format + " is not supported")
 		}
 	}
 
 	return func(c *routing.Context) error {
-		format := NegotiateContentType(c.RequestCtx, formats, formats[0])
-		c.SetDataWriter(DataWriters[format])
+		writers := DataWriters
+		if codecs := c.Codecs(); codecs != nil {
+			writers = codecs.Writers
+		}
+		offers := make([]string, 0, len(formats))
+		for _, format := range formats {
+			if w, _ := lookupDataWriter(writers, format); w != nil && servesVersion(c.Route(), format) {
+				offers = append(offers, format)
+			}
+		}
+		if len(offers) == 0 {
+			return routing.NewHTTPError(fasthttp.StatusInternalServerError, "no data writer is registered for "+strings.Join(formats, ", "))
+		}
+		if len(offers) > 1 || strict {
+			addVary(&c.Response.Header, "Accept")
+		}
+		var i int
+		if format := c.Format(); format != "" {
+			if i = offerFor(offers, c.Router().Extensions[format]); i < 0 {
+				return routing.NewHTTPError(fasthttp.StatusNotAcceptable, "the available types are "+strings.Join(offers, ", "))
+			}
+		} else if i = negotiateContentType(c.RequestCtx, offers); i < 0 {
+			if strict {
+				return routing.NewHTTPError(fasthttp.StatusNotAcceptable, "the available types are "+strings.Join(offers, ", "))
+			}
+			i = 0
+		}
+		format := offers[i]
+		writer, exact := lookupDataWriter(writers, format)
+		c.SetDataWriter(writer)
+		if !exact {
+			setMediaType(&c.Response.Header, format)
+		}
+		vt, _ := ParseVendorType(format)
+		c.Set(Version, vt.Version)
 		return nil
 	}
 }
 
 // JSONDataWriter sets the "Content-Type" response header as "application/json" and writes the given data in JSON format to the response.
-type JSONDataWriter struct{}
+// Channels and StreamFunc values are streamed to the client as a JSON array that is encoded incrementally (see StreamOptions).
+//
+// The output can be shaped by the client when the corresponding options are enabled:
+//
+//   - Pretty: the output is indented if the request has a "pretty" query parameter with a true value (as in "?pretty=1"),
+//     or if the "Accept" header has a "pretty" parameter (as in "application/json; pretty=1"). Streamed data is never indented.
+//   - SparseFields: the "fields" query parameter lists the comma-separated paths of the object fields to be written,
+//     as in "?fields=id,name,owner.email". The paths apply to the objects in arrays, such as the items of a list.
+//     Errors written via routing.Context.WriteError() are not filtered.
+//   - JSONP: the output is wrapped in a call to the JavaScript function named by the query parameter CallbackParam
+//     and served as "application/javascript". The name must be made of JavaScript identifiers separated by dots;
+//     otherwise a 400 HTTP error is returned.
+//
+// The options only take effect when the data is written to a fasthttp.RequestCtx, as routing.Context.Write() does.
+type JSONDataWriter struct {
+	StreamOptions
+	// Pretty allows clients to request indented output.
+	Pretty bool
+	// Indent is the string used to indent each level of pretty output. Defaults to two spaces.
+	Indent string
+	// SparseFields allows clients to select the fields to be written with the "fields" query parameter.
+	SparseFields bool
+	// JSONP allows clients to request the output to be wrapped in a call to a JavaScript function.
+	JSONP bool
+	// CallbackParam is the name of the query parameter holding the JSONP callback name. Defaults to "callback".
+	CallbackParam string
+}
 
 // SetHeader sets the "Content-Type" response header as "application/json".
 func (w *JSONDataWriter) SetHeader(h *fasthttp.ResponseHeader) {
 	h.SetContentType(JSON)
 }
 
+// Write writes the given data in JSON format to the response.
 func (w *JSONDataWriter) Write(res io.Writer, data interface{}) (err error) {
-	enc := json.NewEncoder(res)
-	enc.SetEscapeHTML(false)
-	return enc.Encode(data)
+	shape, err := w.shapeOf(res, data)
+	if err != nil {
+		return err
+	}
+	if stream, ok := streamOf(data); ok {
+		prefix, suffix := []byte{'['}, []byte("]\n")
+		if shape.callback != "" {
+			prefix, suffix = []byte(jsonpPrefix+shape.callback+"(["), []byte("]);\n")
+		}
+		return w.stream(res, shape.filterStream(stream), prefix, []byte{','}, suffix)
+	}
+	if shape.empty() {
+		return encodeJSON(res, data)
+	}
+	return shape.write(res, data)
 }
 
 // XMLDataWriter sets the "Content-Type" response header as "application/xml; charset=UTF-8" and writes the given data in XML format to the response.
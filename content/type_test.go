@@ -112,8 +112,10 @@ func TestTypeNegotiatorWithVersion(t *testing.T) {
 	assert.Equal(t, "text/html; charset=UTF-8", string(c.Response.Header.ContentType()))
 	assert.Equal(t, "xyz", string(c.Response.Body()))
 
-	DataWriters[v1JSON] = &JSONDataWriter1{}
-	DataWriters[v2JSON] = &JSONDataWriter2{}
+	codecs := NewCodecs()
+	codecs.Writers[v1JSON] = &JSONDataWriter1{}
+	codecs.Writers[v2JSON] = &JSONDataWriter2{}
+	c.SetCodecs(codecs)
 
 	// test format chosen based on Accept
 	ctx.Response.Reset()
@@ -134,4 +136,153 @@ func TestTypeNegotiatorWithVersion(t *testing.T) {
 	assert.Panics(t, func() {
 		TypeNegotiator("unknown")
 	})
+
+	// test versioned formats are not available outside the registry
+	c.SetCodecs(nil)
+	ctx.Response.Reset()
+	assert.Nil(t, h(c))
+	assert.Nil(t, c.Write("xyz"))
+	assert.Equal(t, "application/xml; charset=UTF-8", string(c.Response.Header.ContentType()))
+	_, ok := DataWriters[v1JSON]
+	assert.False(t, ok)
+
+	h = TypeNegotiator(v2JSON, v1JSON)
+	err := h(c)
+	if assert.NotNil(t, err) {
+		assert.Equal(t, fasthttp.StatusInternalServerError, err.(routing.HTTPError).StatusCode())
+	}
+}
+
+func TestTypeNegotiatorWithRouterCodecs(t *testing.T) {
+	router := routing.New()
+	codecs := NewCodecs()
+	codecs.Writers[v1JSON] = &JSONDataWriter1{}
+	api := router.Group("/api")
+	api.SetCodecs(codecs)
+	handler := func(c *routing.Context) error {
+		return c.Write("xyz")
+	}
+	api.Get("/users", TypeNegotiator(v1JSON, HTML), handler)
+	router.Get("/users", TypeNegotiator(v1JSON, HTML), handler)
+
+	var ctx fasthttp.RequestCtx
+	ctx.Request.Header.SetMethod("GET")
+	ctx.Request.SetRequestURI("/api/users")
+	ctx.Request.Header.Set("Accept", v1JSON)
+	router.HandleRequest(&ctx)
+	assert.Equal(t, v1JSON, string(ctx.Response.Header.ContentType()))
+	assert.Equal(t, "\"xyz\"\n", string(ctx.Response.Body()))
+
+	ctx.Response.Reset()
+	ctx.Request.SetRequestURI("/users")
+	router.HandleRequest(&ctx)
+	assert.Equal(t, "text/html; charset=UTF-8", string(ctx.Response.Header.ContentType()))
+	assert.Equal(t, "xyz", string(ctx.Response.Body()))
+}
+
+func TestTypeNegotiatorErrors(t *testing.T) {
+	router := routing.New()
+	router.Use(TypeNegotiator(JSON, XML, CSV))
+	router.Get("/users", func(c *routing.Context) error {
+		c.Write("partial")
+		return routing.NewHTTPError(fasthttp.StatusForbidden, "no access")
+	})
+
+	tests := []struct {
+		tag         string
+		method      string
+		uri         string
+		accept      string
+		status      int
+		contentType string
+		body        string
+	}{
+		{"t1", "GET", "/users", "application/json", fasthttp.StatusForbidden, "application/json", `{"status":403,"message":"no access"}` + "\n"},
+		{"t2", "GET", "/users", "application/xml", fasthttp.StatusForbidden, "application/xml; charset=UTF-8", `<error><status>403</status><message>no access</message></error>`},
+		{"t3", "GET", "/posts", "application/json", fasthttp.StatusNotFound, "application/json", `{"status":404,"message":"Not Found"}` + "\n"},
+		{"t4", "PUT", "/users", "application/json", fasthttp.StatusMethodNotAllowed, "application/json", `{"status":405,"message":"Method Not Allowed"}` + "\n"},
+		{"t5", "GET", "/users", "text/csv", fasthttp.StatusForbidden, "text/csv; charset=UTF-8", "status,message\n403,no access\n"},
+	}
+	for _, test := range tests {
+		var ctx fasthttp.RequestCtx
+		ctx.Request.Header.SetMethod(test.method)
+		ctx.Request.SetRequestURI(test.uri)
+		ctx.Request.Header.Set("Accept", test.accept)
+		router.HandleRequest(&ctx)
+		assert.Equal(t, test.status, ctx.Response.StatusCode(), test.tag)
+		assert.Equal(t, test.contentType, string(ctx.Response.Header.ContentType()), test.tag)
+		assert.Equal(t, test.body, string(ctx.Response.Body()), test.tag)
+	}
+}
+
+func TestStrictTypeNegotiator(t *testing.T) {
+	router := routing.New()
+	router.Get("/users", StrictTypeNegotiator(JSON, XML), func(c *routing.Context) error {
+		return c.Write("xyz")
+	})
+	router.Get("/single", TypeNegotiator(JSON), func(c *routing.Context) error {
+		return c.Write("xyz")
+	})
+
+	tests := []struct {
+		tag    string
+		uri    string
+		accept string
+		status int
+		body   string
+		vary   string
+	}{
+		{"t1", "/users", "application/xml", fasthttp.StatusOK, "<string>xyz</string>", "Accept"},
+		{"t2", "/users", "", fasthttp.StatusOK, `"xyz"` + "\n", "Accept"},
+		{"t3", "/users", "text/html, application/json;q=0", fasthttp.StatusNotAcceptable,
+			"the available types are application/json, application/xml", "Accept"},
+		{"t4", "/single", "text/html", fasthttp.StatusOK, `"xyz"` + "\n", ""},
+	}
+	for _, test := range tests {
+		var ctx fasthttp.RequestCtx
+		ctx.Request.SetRequestURI(test.uri)
+		ctx.Request.Header.Set("Accept", test.accept)
+		router.HandleRequest(&ctx)
+		assert.Equal(t, test.status, ctx.Response.StatusCode(), test.tag)
+		assert.Equal(t, test.body, string(ctx.Response.Body()), test.tag)
+		assert.Equal(t, test.vary, string(ctx.Response.Header.Peek("Vary")), test.tag)
+	}
+}
+
+func TestTypeNegotiatorWithExtensions(t *testing.T) {
+	router := routing.New()
+	router.Extensions = NewExtensions()
+	router.Extensions["pdf"] = "application/pdf"
+	_, ok := Extensions["pdf"]
+	assert.False(t, ok)
+	router.Use(TypeNegotiator(JSON, CSV, "application/vnd.acme.v2+xml"))
+	router.Get("/reports/<id>", func(c *routing.Context) error {
+		return c.Write([]string{c.Param("id"), c.Route().URL("id", c.Param("id"), "format", "csv")})
+	})
+
+	tests := []struct {
+		tag         string
+		uri         string
+		accept      string
+		status      int
+		contentType string
+		body        string
+	}{
+		{"t1", "/reports/42.csv", "application/json", fasthttp.StatusOK, "text/csv; charset=UTF-8", "42,/reports/42.csv\n"},
+		{"t2", "/reports/42", "text/csv", fasthttp.StatusOK, "text/csv; charset=UTF-8", "42,/reports/42.csv\n"},
+		{"t3", "/reports/42.json", "text/csv", fasthttp.StatusOK, "application/json", `["42","/reports/42.csv"]` + "\n"},
+		{"t4", "/reports/42.xml", "", fasthttp.StatusOK, "application/vnd.acme.v2+xml; charset=UTF-8",
+			"<string>42</string><string>/reports/42.csv</string>"},
+		{"t5", "/reports/42.html", "", fasthttp.StatusNotAcceptable, "text/plain; charset=utf-8",
+			"the available types are application/json, text/csv, application/vnd.acme.v2+xml"},
+	}
+	for _, test := range tests {
+		var ctx fasthttp.RequestCtx
+		ctx.Request.SetRequestURI(test.uri)
+		ctx.Request.Header.Set("Accept", test.accept)
+		router.HandleRequest(&ctx)
+		assert.Equal(t, test.status, ctx.Response.StatusCode(), test.tag)
+		assert.Equal(t, test.contentType, string(ctx.Response.Header.ContentType()), test.tag)
+		assert.Equal(t, test.body, string(ctx.Response.Body()), test.tag)
+	}
 }
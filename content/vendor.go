@@ -0,0 +1,72 @@
+package content
+
+import (
+	"strings"
+
+	"github.com/jackwhelpton/fasthttp-routing/v2"
+	"github.com/jackwhelpton/fasthttp-routing/v2/internal/accept"
+	"github.com/valyala/fasthttp"
+)
+
+// Version is the key used to store and retrieve the version of the negotiated vendor media type in routing.Context.
+// The version is empty if the negotiated MIME type is not versioned.
+const Version = "Version"
+
+// Versions is a route tag listing the versions of the vendor media types served by the route.
+// TypeNegotiator does not offer the versioned MIME types whose version is not listed.
+//
+//     router.Use(content.TypeNegotiator("application/vnd.acme.v2+json", "application/vnd.acme.v1+json"))
+//     router.Get("/reports", listReports).Tag(content.Versions{"v2"})
+type Versions []string
+
+// VendorType is a media type of the vendor tree (RFC 6838, Section 3.2), such as "application/vnd.acme.v2+json".
+type VendorType = accept.VendorType
+
+// ParseVendorType parses a media type of the vendor tree. The version is the last dot-separated part of the name
+// if it is made of a "v" followed by digits. False is returned if the media type is not in the vendor tree.
+func ParseVendorType(mediaType string) (VendorType, bool) {
+	return accept.ParseVendorType(mediaType)
+}
+
+// lookupDataWriter returns the data writer registered for the given MIME type. If there is none, a MIME type with
+// a structured syntax suffix, such as "application/vnd.acme.v2+json", is written by the data writer of the type
+// named after the suffix, such as "application/json", and false is returned as the second value.
+func lookupDataWriter(writers map[string]routing.DataWriter, format string) (writer routing.DataWriter, exact bool) {
+	if w, ok := writers[format]; ok {
+		return w, true
+	}
+	r := ParseAcceptRange(format)
+	if i := strings.LastIndexByte(r.Subtype, '+'); i >= 0 {
+		return writers[r.Type+"/"+strings.ToLower(r.Subtype[i+1:])], false
+	}
+	return nil, false
+}
+
+// setMediaType replaces the media type of the "Content-Type" response header, keeping its parameters.
+func setMediaType(h *fasthttp.ResponseHeader, mediaType string) {
+	contentType := string(h.ContentType())
+	if i := strings.IndexByte(contentType, ';'); i >= 0 {
+		mediaType += contentType[i:]
+	}
+	h.SetContentType(mediaType)
+}
+
+// servesVersion checks if the route serves the given MIME type according to its Versions tags.
+func servesVersion(route *routing.Route, format string) bool {
+	vt, ok := ParseVendorType(format)
+	if !ok || vt.Version == "" || route == nil {
+		return true
+	}
+	tagged := false
+	for _, tag := range route.Tags() {
+		if versions, ok := tag.(Versions); ok {
+			tagged = true
+			for _, v := range versions {
+				if strings.EqualFold(v, vt.Version) {
+					return true
+				}
+			}
+		}
+	}
+	return !tagged
+}
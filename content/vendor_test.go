@@ -0,0 +1,84 @@
+package content
+
+import (
+	"testing"
+
+	"github.com/jackwhelpton/fasthttp-routing/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func TestParseVendorType(t *testing.T) {
+	tests := []struct {
+		mediaType string
+		ok        bool
+		result    VendorType
+	}{
+		{"application/vnd.acme.v2+json", true, VendorType{Name: "acme", Version: "v2", Suffix: "json"}},
+		{"application/vnd.github.v3.raw+json; charset=utf-8", true, VendorType{Name: "github.v3.raw", Suffix: "json"}},
+		{"application/VND.Acme+XML", true, VendorType{Name: "acme", Suffix: "xml"}},
+		{"application/vnd.ms-excel", true, VendorType{Name: "ms-excel"}},
+		{"application/vnd.acme.vx", true, VendorType{Name: "acme.vx"}},
+		{"application/json", false, VendorType{}},
+		{"application/vnd.", false, VendorType{}},
+	}
+	for _, test := range tests {
+		vt, ok := ParseVendorType(test.mediaType)
+		assert.Equal(t, test.ok, ok, test.mediaType)
+		assert.Equal(t, test.result, vt, test.mediaType)
+	}
+}
+
+func TestVendorTypeNegotiation(t *testing.T) {
+	v1, v2 := "application/vnd.acme.v1+json", "application/vnd.acme.v2+json"
+	xml := "application/vnd.acme.v2+xml"
+	router := routing.New()
+	router.Use(TypeNegotiator(v2, v1, xml, "application/vnd.acme.v2+yaml"))
+	handler := func(c *routing.Context) error {
+		return c.Write(c.Get(Version))
+	}
+	router.Get("/users", handler)
+	router.Get("/reports", handler).Tag(Versions{"v1"})
+
+	tests := []struct {
+		tag         string
+		uri         string
+		accept      string
+		contentType string
+		body        string
+	}{
+		{"t1", "/users", v1, v1, `"v1"` + "\n"},
+		{"t2", "/users", "application/vnd.acme+json", v2, `"v2"` + "\n"},
+		{"t3", "/users", "application/vnd.acme+xml", xml + "; charset=UTF-8", "<string>v2</string>"},
+		{"t4", "/users", "application/vnd.acme.v3+json", v2, `"v2"` + "\n"},
+		{"t5", "/users", "application/*+json;q=0.5, application/vnd.acme.v1+json;q=0.6", v1, `"v1"` + "\n"},
+		{"t6", "/reports", "application/vnd.acme+json", v1, `"v1"` + "\n"},
+		{"t7", "/reports", v2, v1, `"v1"` + "\n"},
+	}
+	for _, test := range tests {
+		var ctx fasthttp.RequestCtx
+		ctx.Request.SetRequestURI(test.uri)
+		ctx.Request.Header.Set("Accept", test.accept)
+		router.HandleRequest(&ctx)
+		assert.Equal(t, fasthttp.StatusOK, ctx.Response.StatusCode(), test.tag)
+		assert.Equal(t, test.contentType, string(ctx.Response.Header.ContentType()), test.tag)
+		assert.Equal(t, test.body, string(ctx.Response.Body()), test.tag)
+	}
+
+	// a data writer registered for a vendor media type takes precedence
+	codecs := NewCodecs()
+	codecs.Writers[v1] = &JSONDataWriter1{}
+	router.SetCodecs(codecs)
+	var ctx fasthttp.RequestCtx
+	ctx.Request.SetRequestURI("/users")
+	ctx.Request.Header.Set("Accept", v1)
+	router.HandleRequest(&ctx)
+	assert.Equal(t, v1JSON, string(ctx.Response.Header.ContentType()))
+
+	// offers without a data writer are not offered rather than causing a panic
+	c := routing.NewContext(&fasthttp.RequestCtx{})
+	err := TypeNegotiator("application/vnd.acme.v1+yaml", "application/pdf")(c)
+	if assert.NotNil(t, err) {
+		assert.Equal(t, fasthttp.StatusInternalServerError, err.(routing.HTTPError).StatusCode())
+	}
+}
@@ -5,6 +5,10 @@
 package routing
 
 import (
+	"errors"
+	"strconv"
+	"strings"
+
 	"github.com/valyala/fasthttp"
 )
 
@@ -13,12 +17,16 @@ type Context struct {
 	*fasthttp.RequestCtx
 
 	router   *Router
+	route    *Route                 // the route matching the current request
+	format   string                 // the extension removed from the request path to match the route
+	codecs   *Codecs                // the data readers and writers in effect for the current request
 	pnames   []string               // list of route parameter names
 	pvalues  []string               // list of parameter values corresponding to pnames
 	data     map[string]interface{} // data items managed by Get and Set
 	index    int                    // the index of the currently executing handler in handlers
 	handlers []Handler              // the handlers associated with the current route
 	writer   DataWriter
+	readers  map[string]DataReader // data readers set via SetDataReader, taking precedence over DataReaders
 }
 
 // NewContext creates a new Context object with the given request context, and the handlers.
@@ -34,6 +42,32 @@ func (c *Context) Router() *Router {
 	return c.router
 }
 
+// Route returns the route matching the incoming HTTP request.
+// Nil is returned if no route matches the request, for example when the handlers registered via
+// Router.NotFound() are being executed.
+func (c *Context) Route() *Route {
+	return c.route
+}
+
+// Format returns the extension removed from the request path to match the route, such as "csv" for "/reports/42.csv",
+// or an empty string if there is none. See Router.Extensions.
+func (c *Context) Format() string {
+	return c.format
+}
+
+// Codecs returns the registry of data readers and writers in effect for the current request.
+// This is the registry attached to the route group of the matching route (or to the router if no route matches).
+// Nil is returned if there is no such registry, in which case the package-level defaults are used.
+func (c *Context) Codecs() *Codecs {
+	return c.codecs
+}
+
+// SetCodecs sets the registry of data readers and writers to be used for the current request.
+// This method is primarily provided for writing unit tests for handlers.
+func (c *Context) SetCodecs(codecs *Codecs) {
+	c.codecs = codecs
+}
+
 // Param returns the named parameter value that is found in the URL path matching the current route.
 // If the named parameter cannot be found, an empty string will be returned.
 func (c *Context) Param(name string) string {
@@ -45,6 +79,15 @@ func (c *Context) Param(name string) string {
 	return ""
 }
 
+// Params returns the names and values of the parameters found in the URL path matching the current route.
+func (c *Context) Params() map[string]string {
+	params := make(map[string]string, len(c.pnames))
+	for i, n := range c.pnames {
+		params[n] = c.pvalues[i]
+	}
+	return params
+}
+
 // SetParam sets the named parameter value.
 // This method is primarily provided for writing unit tests.
 func (c *Context) SetParam(name, value string) {
@@ -64,6 +107,15 @@ func (c *Context) Get(name string) interface{} {
 	return c.data[name]
 }
 
+// Data returns a copy of all data items registered with the context by calling Set.
+func (c *Context) Data() map[string]interface{} {
+	data := make(map[string]interface{}, len(c.data))
+	for k, v := range c.data {
+		data[k] = v
+	}
+	return data
+}
+
 // Set stores the named data item in the context so that it can be retrieved later.
 func (c *Context) Set(name string, value interface{}) {
 	if c.data == nil {
@@ -158,13 +210,22 @@ func (c *Context) URL(route string, pairs ...interface{}) string {
 
 // Read populates the given struct variable with the data from the current request.
 // If the request is NOT a GET request, it will check the "Content-Type" header
-// and find a matching reader from DataReaders to read the request data.
+// and find a matching reader from those set via SetDataReader() or, failing that, from DataReaders
+// to read the request data.
 // If there is no match or if the request is a GET request, it will use DefaultFormDataReader
 // to read the request data.
+//
+// A request body in a charset other than UTF-8, as declared by the charset parameter of the "Content-Type" header
+// (e.g. "application/xml; charset=ISO-8859-1"), is converted to UTF-8 before being read. An unknown charset
+// results in a 415 HTTP error.
+//
+// Compressed request bodies are read as they are, unless the Decompressor handler is used. See ReadWith().
 func (c *Context) Read(data interface{}) error {
 	if !c.IsGet() {
-		t := getContentType(c.RequestCtx)
-		if reader, ok := DataReaders[t]; ok {
+		if err := decodeCharset(c.RequestCtx); err != nil {
+			return err
+		}
+		if reader := c.dataReader(getContentType(c.RequestCtx)); reader != nil {
 			return reader.Read(c.RequestCtx, data)
 		}
 	}
@@ -172,13 +233,178 @@ func (c *Context) Read(data interface{}) error {
 	return DefaultFormDataReader.Read(c.RequestCtx, data)
 }
 
+// ReadWith populates the given struct variable with the data from the current request like Read(),
+// decoding a request body sent with a "Content-Encoding" of gzip or deflate first, in the same way as done
+// by Decompressor with the given options.
+func (c *Context) ReadWith(data interface{}, opts DecompressOptions) error {
+	if err := decompressBody(c.RequestCtx, opts.MaxBytes); err != nil {
+		return err
+	}
+	return c.Read(data)
+}
+
+// ApplyPatch applies the patch document in the request body to the given data, which should hold
+// the current state of the resource being patched.
+// The "Content-Type" header must be either "application/json-patch+json" (RFC 6902)
+// or "application/merge-patch+json" (RFC 7386); otherwise a 415 HTTP error is returned.
+// See JSONPatchDataReader and MergePatchDataReader for the errors reported when the patch cannot be applied.
+func (c *Context) ApplyPatch(data interface{}) error {
+	t := getContentType(c.RequestCtx)
+	if t == MIME_JSON_PATCH || t == MIME_MERGE_PATCH {
+		if reader := c.dataReader(t); reader != nil {
+			return reader.Read(c.RequestCtx, data)
+		}
+	}
+	return NewHTTPError(fasthttp.StatusUnsupportedMediaType)
+}
+
+// dataReader returns the data reader for the given content type, or nil if there is none.
+func (c *Context) dataReader(contentType string) DataReader {
+	if reader, ok := c.readers[contentType]; ok {
+		return reader
+	}
+	if c.codecs != nil {
+		reader, _ := c.codecs.Reader(contentType)
+		return reader
+	}
+	return DataReaders[contentType]
+}
+
+// SetDataReader sets the data reader that will be used by Read() for the given content type.
+// It takes precedence over the reader registered for the same content type in DataReaders.
+func (c *Context) SetDataReader(contentType string, reader DataReader) {
+	if c.readers == nil {
+		c.readers = make(map[string]DataReader)
+	}
+	c.readers[contentType] = reader
+}
+
 // Write writes the given data of arbitrary type to the response.
 // The method calls the data writer set via SetDataWriter() to do the actual writing.
-// By default, the DefaultDataWriter will be used.
+// By default, the DefaultWriter of the Codecs in effect will be used, or DefaultDataWriter
+// if there is none.
 func (c *Context) Write(data interface{}) error {
+	if vw, ok := c.dataWriter().(ViewWriter); ok {
+		return vw.WriteView(c, "", data)
+	}
+	return c.writer.Write(c.RequestCtx, data)
+}
+
+// Render writes the given data to the response using the named view (template) if the current data writer
+// is a ViewWriter. Otherwise, the data is written as it would be by Write(), so that a handler can render
+// an HTML page and still serve other negotiated formats.
+//
+//     c.Render("users/show", user)
+func (c *Context) Render(name string, data interface{}) error {
+	if vw, ok := c.dataWriter().(ViewWriter); ok {
+		return vw.WriteView(c, name, data)
+	}
 	return c.writer.Write(c.RequestCtx, data)
 }
 
+// dataWriter returns the data writer used by Write(), setting the default one if none has been set.
+func (c *Context) dataWriter() DataWriter {
+	if c.writer == nil {
+		if c.codecs != nil && c.codecs.DefaultWriter != nil {
+			c.SetDataWriter(c.codecs.DefaultWriter)
+		} else {
+			c.writer = DefaultDataWriter
+		}
+	}
+	return c.writer
+}
+
+// WriteStatus sets the HTTP status code of the response and writes the given data to the response
+// using the current data writer (see Write()).
+func (c *Context) WriteStatus(status int, data interface{}) error {
+	c.Response.SetStatusCode(status)
+	return c.Write(data)
+}
+
+// WriteError writes the given error to the response using the current data writer (see Write()),
+// replacing any response body written so far, and sets the HTTP status code accordingly.
+//
+// The error is converted by AsHTTPError(); errors that cannot be converted are reported with
+// fasthttp.StatusInternalServerError and its status text, so that their messages are not disclosed.
+// The converted error is written as an envelope holding its status code and public message, which every data writer
+// can serialize (for example, {"status":404,"message":"Not Found"} in JSON).
+// Errors of type *Problem are written by WriteProblem() instead.
+func (c *Context) WriteError(err error) error {
+	he, ok := AsHTTPError(err)
+	if !ok {
+		he = WrapHTTPError(fasthttp.StatusInternalServerError, err, "")
+	}
+	if problem, ok := he.(*Problem); ok {
+		return WriteProblem(c.RequestCtx, problem)
+	}
+	if _, ok := he.(*httpError); !ok {
+		he = WrapHTTPError(he.StatusCode(), he, he.Error())
+	}
+	c.Response.ResetBody()
+	if c.writer == nil {
+		c.Response.Header.SetContentType("text/plain; charset=utf-8")
+	}
+	return c.WriteStatus(he.StatusCode(), he)
+}
+
+// NoContent sets the HTTP status code of the response as fasthttp.StatusNoContent (204) and clears the response body.
+func (c *Context) NoContent() error {
+	c.Response.SetStatusCode(fasthttp.StatusNoContent)
+	c.Response.ResetBody()
+	return nil
+}
+
+// Created sets the HTTP status code of the response as fasthttp.StatusCreated (201), sets the "Location" header
+// to the URL of the newly created resource, and writes the given data to the response using the current data writer.
+// The URL is created using the named route and the parameter pairs (see URL()).
+// An error is returned if the named route cannot be found.
+func (c *Context) Created(route string, pairs []interface{}, data interface{}) error {
+	location, err := c.routeURL(route, pairs)
+	if err != nil {
+		return err
+	}
+	c.Response.Header.Set("Location", location)
+	return c.WriteStatus(fasthttp.StatusCreated, data)
+}
+
+// RedirectTo redirects the client to the URL created using the named route and the parameter pairs (see URL()).
+// GET and HEAD requests are redirected with fasthttp.StatusFound (302), while other requests are redirected with
+// fasthttp.StatusSeeOther (303) so that the client follows the redirection with a GET request.
+// An error is returned if the named route cannot be found.
+func (c *Context) RedirectTo(route string, pairs ...interface{}) error {
+	location, err := c.routeURL(route, pairs)
+	if err != nil {
+		return err
+	}
+	status := fasthttp.StatusSeeOther
+	if c.IsGet() || c.IsHead() {
+		status = fasthttp.StatusFound
+	}
+	// c.Redirect turns the URL into an absolute one, so the Location header and status code are set explicitly instead.
+	c.Response.Header.Set("Location", location)
+	c.Response.SetStatusCode(status)
+	return nil
+}
+
+// Attachment sets the "Content-Disposition" header so that the response is downloaded as a file with the given name,
+// and writes the given data to the response using the current data writer.
+// The header follows RFC 6266: non-ASCII file names are sent using the "filename*" parameter, together with
+// an ASCII fallback in the "filename" parameter.
+func (c *Context) Attachment(name string, data interface{}) error {
+	c.Response.Header.Set("Content-Disposition", contentDisposition("attachment", name))
+	return c.Write(data)
+}
+
+// routeURL creates a URL using the named route and the parameter pairs.
+func (c *Context) routeURL(route string, pairs []interface{}) (string, error) {
+	if c.router != nil {
+		if r := c.router.namedRoutes[route]; r != nil {
+			return r.URL(pairs...), nil
+		}
+	}
+	return "", errors.New("route " + strconv.Quote(route) + " not found")
+}
+
 // SetDataWriter sets the data writer that will be used by Write().
 func (c *Context) SetDataWriter(writer DataWriter) {
 	c.writer = writer
@@ -190,7 +416,11 @@ func (c *Context) init(ctx *fasthttp.RequestCtx) {
 	c.RequestCtx = ctx
 	c.data = nil
 	c.index = -1
-	c.writer = DefaultDataWriter
+	c.writer = nil
+	c.readers = nil
+	c.route = nil
+	c.format = ""
+	c.codecs = nil
 }
 
 func getContentType(ctx *fasthttp.RequestCtx) string {
@@ -202,3 +432,43 @@ func getContentType(ctx *fasthttp.RequestCtx) string {
 	}
 	return t
 }
+
+// contentDisposition builds a "Content-Disposition" header value according to RFC 6266.
+func contentDisposition(disposition, filename string) string {
+	var fallback []byte
+	ascii := true
+	for i := 0; i < len(filename); i++ {
+		switch b := filename[i]; {
+		case b >= 0x80:
+			ascii = false
+			if b >= 0xC0 {
+				// one replacement character for each non-ASCII rune
+				fallback = append(fallback, '_')
+			}
+		case b < 0x20 || b == 0x7F, b == '"', b == '\\':
+			fallback = append(fallback, '_')
+		default:
+			fallback = append(fallback, b)
+		}
+	}
+	v := disposition + `; filename="` + string(fallback) + `"`
+	if !ascii {
+		v += "; filename*=UTF-8''" + encodeRFC5987(filename)
+	}
+	return v
+}
+
+// encodeRFC5987 percent-encodes a string to be used as an ext-value (RFC 5987).
+func encodeRFC5987(s string) string {
+	const hex = "0123456789ABCDEF"
+	var buf []byte
+	for i := 0; i < len(s); i++ {
+		b := s[i]
+		if 'a' <= b && b <= 'z' || 'A' <= b && b <= 'Z' || '0' <= b && b <= '9' || strings.IndexByte("!#$&+-.^_`|~", b) >= 0 {
+			buf = append(buf, b)
+		} else {
+			buf = append(buf, '%', hex[b>>4], hex[b&15])
+		}
+	}
+	return string(buf)
+}
@@ -51,6 +51,19 @@ func TestContextInit(t *testing.T) {
 	assert.Nil(t, c.data)
 }
 
+func TestContextParamsData(t *testing.T) {
+	c := NewContext(nil)
+	assert.Equal(t, map[string]string{}, c.Params())
+	assert.Equal(t, map[string]interface{}{}, c.Data())
+	c.SetParam("id", "1")
+	c.Set("user", 2)
+	assert.Equal(t, map[string]string{"id": "1"}, c.Params())
+	data := c.Data()
+	assert.Equal(t, map[string]interface{}{"user": 2}, data)
+	data["user"] = 3
+	assert.Equal(t, 2, c.Get("user"))
+}
+
 func TestContextURL(t *testing.T) {
 	router := New()
 	router.Get("/users/<id:\\d+>/<action>/*").Name("users")
@@ -167,3 +180,121 @@ func testNormalHandler(tag string) Handler {
 		return nil
 	}
 }
+
+func TestContextResponseHelpers(t *testing.T) {
+	router := New()
+	router.Get("/users/<id>").Name("user")
+
+	var ctx fasthttp.RequestCtx
+	ctx.Request.Header.SetMethod("POST")
+	ctx.Request.SetRequestURI("/users")
+	c := router.pool.New().(*Context)
+	c.init(&ctx)
+
+	assert.Nil(t, c.WriteStatus(fasthttp.StatusAccepted, "abc"))
+	assert.Equal(t, fasthttp.StatusAccepted, ctx.Response.StatusCode())
+	assert.Equal(t, "abc", string(ctx.Response.Body()))
+
+	assert.Nil(t, c.NoContent())
+	assert.Equal(t, fasthttp.StatusNoContent, ctx.Response.StatusCode())
+	assert.Equal(t, "", string(ctx.Response.Body()))
+
+	ctx.Response.Reset()
+	c.SetDataWriter(&tagDataWriter{})
+	assert.Nil(t, c.Created("user", []interface{}{"id", 12}, "created"))
+	assert.Equal(t, fasthttp.StatusCreated, ctx.Response.StatusCode())
+	assert.Equal(t, "/users/12", string(ctx.Response.Header.Peek("Location")))
+	assert.Equal(t, "text/x-tag", string(ctx.Response.Header.ContentType()))
+	assert.Equal(t, "<created>", string(ctx.Response.Body()))
+	assert.NotNil(t, c.Created("unknown", nil, "created"))
+
+	ctx.Response.Reset()
+	assert.Nil(t, c.RedirectTo("user", "id", "a b"))
+	assert.Equal(t, fasthttp.StatusSeeOther, ctx.Response.StatusCode())
+	assert.Equal(t, "/users/a+b", string(ctx.Response.Header.Peek("Location")))
+	ctx.Request.Header.SetMethod("GET")
+	assert.Nil(t, c.RedirectTo("user", "id", 1))
+	assert.Equal(t, fasthttp.StatusFound, ctx.Response.StatusCode())
+	assert.NotNil(t, c.RedirectTo("unknown"))
+
+	ctx.Response.Reset()
+	assert.Nil(t, c.Attachment("report.csv", "data"))
+	assert.Equal(t, `attachment; filename="report.csv"`, string(ctx.Response.Header.Peek("Content-Disposition")))
+	assert.Equal(t, "<data>", string(ctx.Response.Body()))
+}
+
+func TestContentDisposition(t *testing.T) {
+	assert.Equal(t, `attachment; filename="a.txt"`, contentDisposition("attachment", "a.txt"))
+	assert.Equal(t, `attachment; filename="a_b_.txt"`, contentDisposition("attachment", "a\"b\\.txt"))
+	assert.Equal(t, `attachment; filename="_t_.pdf"; filename*=UTF-8''%C3%A9t%C3%A9.pdf`, contentDisposition("attachment", "été.pdf"))
+	assert.Equal(t, `inline; filename="__ 1.txt"; filename*=UTF-8''%E6%97%A5%E6%9C%AC%201.txt`, contentDisposition("inline", "日本 1.txt"))
+}
+
+type customHTTPError struct{}
+
+func (e customHTTPError) Error() string   { return "custom" }
+func (e customHTTPError) StatusCode() int { return fasthttp.StatusTeapot }
+
+func TestContextWriteError(t *testing.T) {
+	var ctx fasthttp.RequestCtx
+	c := NewContext(&ctx)
+	c.Write("partial")
+	assert.Nil(t, c.WriteError(errors.New("abc")))
+	assert.Equal(t, fasthttp.StatusInternalServerError, ctx.Response.StatusCode())
+	assert.Equal(t, "text/plain; charset=utf-8", string(ctx.Response.Header.ContentType()))
+	assert.Equal(t, "Internal Server Error", string(ctx.Response.Body()))
+
+	ctx.Response.Reset()
+	c = NewContext(&ctx)
+	c.SetDataWriter(&tagDataWriter{})
+	assert.Nil(t, c.WriteError(NewHTTPError(fasthttp.StatusNotFound)))
+	assert.Equal(t, fasthttp.StatusNotFound, ctx.Response.StatusCode())
+	assert.Equal(t, "text/x-tag", string(ctx.Response.Header.ContentType()))
+	assert.Equal(t, "<Not Found>", string(ctx.Response.Body()))
+
+	ctx.Response.Reset()
+	c = NewContext(&ctx)
+	assert.Nil(t, c.WriteError(fmt.Errorf("wrapped: %w", customHTTPError{})))
+	assert.Equal(t, fasthttp.StatusTeapot, ctx.Response.StatusCode())
+	assert.Equal(t, "custom", string(ctx.Response.Body()))
+
+	ctx.Response.Reset()
+	c = NewContext(&ctx)
+	assert.Nil(t, c.WriteError(NewProblem(fasthttp.StatusConflict)))
+	assert.Equal(t, MIME_PROBLEM_JSON, string(ctx.Response.Header.ContentType()))
+}
+
+type testViewWriter struct {
+	tagDataWriter
+}
+
+func (w *testViewWriter) WriteView(c *Context, name string, data interface{}) error {
+	_, err := fmt.Fprintf(c.RequestCtx, "%v(%v)", name, data)
+	return err
+}
+
+func TestContextRender(t *testing.T) {
+	var ctx fasthttp.RequestCtx
+	c := NewContext(&ctx)
+	assert.Nil(t, c.Render("users", "abc"))
+	assert.Equal(t, "abc", string(ctx.Response.Body()))
+
+	ctx.Response.Reset()
+	c.SetDataWriter(&tagDataWriter{})
+	assert.Nil(t, c.Render("users", "abc"))
+	assert.Equal(t, "<abc>", string(ctx.Response.Body()))
+
+	ctx.Response.Reset()
+	c.SetDataWriter(&testViewWriter{})
+	assert.Nil(t, c.Render("users", "abc"))
+	assert.Nil(t, c.Write("xyz"))
+	assert.Equal(t, "users(abc)(xyz)", string(ctx.Response.Body()))
+
+	ctx.Response.Reset()
+	codecs := NewCodecs()
+	codecs.DefaultWriter = &testViewWriter{}
+	c = NewContext(&ctx)
+	c.SetCodecs(codecs)
+	assert.Nil(t, c.Render("users", "abc"))
+	assert.Equal(t, "users(abc)", string(ctx.Response.Body()))
+}
@@ -0,0 +1,346 @@
+package routing
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/jackwhelpton/fasthttp-routing/v2/internal/codec"
+	"github.com/jackwhelpton/fasthttp-routing/v2/internal/jsonapi"
+	"github.com/valyala/fasthttp"
+)
+
+// DecodeOptions specifies how JSONDataReader, XMLDataReader, MsgPackDataReader, CBORDataReader and JSONAPIDataReader
+// decode the request body.
+// The zero value imposes no restriction, which is the behavior of the default readers in DataReaders.
+type DecodeOptions struct {
+	// DisallowUnknownFields causes an error to be returned when the body contains a field (or, for XML,
+	// an element, and for MessagePack and CBOR, a map key) that does not match any exported field of the destination struct.
+	DisallowUnknownFields bool
+	// UseNumber causes JSON numbers decoded into an interface{} to be stored as json.Number instead of float64.
+	// It has no effect on other formats.
+	UseNumber bool
+	// MaxBodyBytes is the maximum size of the request body in bytes. Larger bodies are rejected with
+	// a 413 HTTP error. Zero means no limit.
+	// As fasthttp reads the whole body into memory before a request is handled, this limit only lets routes
+	// accept smaller bodies than others; it does not bound the memory used to read them.
+	// Use fasthttp.Server.MaxRequestBodySize for that purpose.
+	MaxBodyBytes int
+	// MaxDepth is the maximum nesting depth of objects and arrays (or, for XML, of elements).
+	// Deeper documents are rejected with a 400 HTTP error. Zero means no limit.
+	MaxDepth int
+}
+
+// ReadOptions returns a handler that makes Context.Read use JSON, XML, MessagePack, CBOR and JSON:API readers
+// configured with the given options.
+// It can be registered with a route group to apply the options to all routes in the group, or with individual routes:
+//
+//     api := router.Group("/api", routing.ReadOptions(routing.DecodeOptions{
+//         DisallowUnknownFields: true,
+//         MaxBodyBytes:          1 << 20,
+//     }))
+func ReadOptions(opts DecodeOptions) Handler {
+	jsonReader := &JSONDataReader{opts}
+	xmlReader := &XMLDataReader{opts}
+	msgpackReader := &MsgPackDataReader{opts}
+	cborReader := &CBORDataReader{opts}
+	jsonapiReader := &JSONAPIDataReader{opts}
+	return func(c *Context) error {
+		c.SetDataReader(MIME_JSON, jsonReader)
+		c.SetDataReader(MIME_XML, xmlReader)
+		c.SetDataReader(MIME_XML2, xmlReader)
+		c.SetDataReader(MIME_MSGPACK, msgpackReader)
+		c.SetDataReader(MIME_MSGPACK2, msgpackReader)
+		c.SetDataReader(MIME_CBOR, cborReader)
+		c.SetDataReader(MIME_JSONAPI, jsonapiReader)
+		return nil
+	}
+}
+
+// checkBodySize returns a 413 HTTP error if the body exceeds the configured limit.
+func (o *DecodeOptions) checkBodySize(body []byte) error {
+	if o.MaxBodyBytes > 0 && len(body) > o.MaxBodyBytes {
+		return NewHTTPError(fasthttp.StatusRequestEntityTooLarge, "request body exceeds "+strconv.Itoa(o.MaxBodyBytes)+" bytes")
+	}
+	return nil
+}
+
+// decodeError converts an error occurring at the given position of the body into a 400 HTTP error.
+// The error is kept as the cause, while the message sent to the client is given by decodeMessage().
+func decodeError(err error, line, column int) HTTPError {
+	return WrapHTTPError(fasthttp.StatusBadRequest, err, fmt.Sprintf("%v (line %d, column %d)", decodeMessage(err), line, column))
+}
+
+// decodeMessage returns the message of a decoding error that can be sent to clients.
+// The messages of the errors reporting a value that does not fit the destination name Go types and struct fields,
+// so these errors are reported as invalid values instead, naming the JSON member at fault if it is known.
+func decodeMessage(err error) string {
+	switch e := err.(type) {
+	case *json.UnmarshalTypeError:
+		if e.Field != "" {
+			return "json: invalid value for " + strconv.Quote(e.Field)
+		}
+		return "json: invalid value"
+	case *strconv.NumError:
+		return "invalid value " + strconv.Quote(e.Num)
+	case *codec.DecodeError:
+		if e.Mismatch {
+			return strings.SplitN(e.Error(), ":", 2)[0] + ": invalid value"
+		}
+	}
+	return err.Error()
+}
+
+// jsonPosition returns the line and column numbers of the given offset in a JSON body.
+func jsonPosition(body []byte, offset int64) (line, column int) {
+	if offset > int64(len(body)) {
+		offset = int64(len(body))
+	}
+	before := body[:offset]
+	line = bytes.Count(before, []byte{'\n'}) + 1
+	column = len(before) - bytes.LastIndexByte(before, '\n')
+	return
+}
+
+// checkJSONDepth reports the offset at which the nesting depth of the JSON body exceeds max.
+// It returns -1 if the depth stays within the limit.
+func checkJSONDepth(body []byte, max int) int64 {
+	depth, inString, escaped := 0, false, false
+	for i, b := range body {
+		switch {
+		case inString:
+			if escaped {
+				escaped = false
+			} else if b == '\\' {
+				escaped = true
+			} else if b == '"' {
+				inString = false
+			}
+		case b == '"':
+			inString = true
+		case b == '{' || b == '[':
+			if depth++; depth > max {
+				return int64(i)
+			}
+		case b == '}' || b == ']':
+			depth--
+		}
+	}
+	return -1
+}
+
+func (r *JSONDataReader) decode(body []byte, data interface{}) error {
+	if err := r.checkBodySize(body); err != nil {
+		return err
+	}
+	if r.MaxDepth > 0 {
+		if offset := checkJSONDepth(body, r.MaxDepth); offset >= 0 {
+			line, column := jsonPosition(body, offset)
+			return decodeError(errors.New("json: maximum nesting depth of "+strconv.Itoa(r.MaxDepth)+" exceeded"), line, column)
+		}
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(body))
+	if r.DisallowUnknownFields {
+		dec.DisallowUnknownFields()
+	}
+	if r.UseNumber {
+		dec.UseNumber()
+	}
+	err := dec.Decode(data)
+	if err == nil {
+		if _, err = dec.Token(); err == io.EOF {
+			return nil
+		}
+		if err == nil {
+			err = errors.New("json: invalid data after top-level value")
+		}
+	}
+
+	offset := dec.InputOffset()
+	switch e := err.(type) {
+	case *json.SyntaxError:
+		// the offset of a syntax error is that of the byte following the offending one
+		if offset = e.Offset; offset > 0 {
+			offset--
+		}
+	case *json.UnmarshalTypeError:
+		offset = e.Offset
+	}
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		err = errors.New("json: unexpected end of JSON input")
+	}
+	line, column := jsonPosition(body, offset)
+	return decodeError(err, line, column)
+}
+
+func (r *XMLDataReader) decode(body []byte, data interface{}, charsetReader func(string, io.Reader) (io.Reader, error)) error {
+	if err := r.checkBodySize(body); err != nil {
+		return err
+	}
+	if r.MaxDepth > 0 || r.DisallowUnknownFields {
+		rt := reflect.TypeOf(data)
+		if !r.DisallowUnknownFields {
+			rt = nil
+		}
+		dec := xml.NewDecoder(bytes.NewReader(body))
+		dec.CharsetReader = charsetReader
+		if err := r.checkXML(dec, rt); err != nil {
+			line, column := dec.InputPos()
+			return decodeError(err, line, column)
+		}
+	}
+
+	dec := xml.NewDecoder(bytes.NewReader(body))
+	dec.CharsetReader = charsetReader
+	if err := dec.Decode(data); err != nil {
+		line, column := dec.InputPos()
+		if e, ok := err.(*xml.SyntaxError); ok {
+			line = e.Line
+		}
+		return decodeError(err, line, column)
+	}
+	return nil
+}
+
+// decodeBinary decodes a MessagePack or CBOR body with the given function.
+func (o *DecodeOptions) decodeBinary(body []byte, data interface{}, unmarshal func([]byte, interface{}, codec.DecodeOptions) error) error {
+	if err := o.checkBodySize(body); err != nil {
+		return err
+	}
+	err := unmarshal(body, data, codec.DecodeOptions{
+		DisallowUnknownFields: o.DisallowUnknownFields,
+		MaxDepth:              o.MaxDepth,
+	})
+	if err == nil {
+		return nil
+	}
+	offset := 0
+	if e, ok := err.(*codec.DecodeError); ok {
+		offset = e.Offset
+	}
+	return WrapHTTPError(fasthttp.StatusBadRequest, err, fmt.Sprintf("%v (offset %d)", decodeMessage(err), offset))
+}
+
+func (r *JSONAPIDataReader) decode(body []byte, data interface{}) error {
+	if err := r.checkBodySize(body); err != nil {
+		return err
+	}
+	if r.MaxDepth > 0 {
+		if offset := checkJSONDepth(body, r.MaxDepth); offset >= 0 {
+			line, column := jsonPosition(body, offset)
+			return decodeError(errors.New("json: maximum nesting depth of "+strconv.Itoa(r.MaxDepth)+" exceeded"), line, column)
+		}
+	}
+	err := jsonapi.Unmarshal(body, data, r.DisallowUnknownFields)
+	if _, ok := err.(*jsonapi.TypeError); ok {
+		return NewHTTPError(fasthttp.StatusConflict, err.Error())
+	} else if err != nil {
+		return WrapHTTPError(fasthttp.StatusBadRequest, err, decodeMessage(err))
+	}
+	return nil
+}
+
+// checkXML walks through the elements of an XML document to verify that the nesting depth stays within
+// the configured limit and, if rt is not nil, that each element maps to a field of the corresponding Go type.
+func (r *XMLDataReader) checkXML(dec *xml.Decoder, rt reflect.Type) error {
+	// types holds the Go type of each open element; nil means the element content is not checked
+	types := []reflect.Type{}
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if r.MaxDepth > 0 && len(types) >= r.MaxDepth {
+				return errors.New("xml: maximum nesting depth of " + strconv.Itoa(r.MaxDepth) + " exceeded")
+			}
+			var et reflect.Type
+			if len(types) == 0 {
+				et = xmlElemType(rt)
+			} else if parent := types[len(types)-1]; parent != nil {
+				var known bool
+				if et, known = xmlChildType(parent, t.Name.Local); !known {
+					return errors.New("xml: unknown element <" + t.Name.Local + ">")
+				}
+			}
+			types = append(types, et)
+		case xml.EndElement:
+			types = types[:len(types)-1]
+		}
+	}
+}
+
+// xmlElemType returns the struct type whose children should be checked for an element decoded into t.
+// Nil is returned if t is not a struct type.
+func xmlElemType(t reflect.Type) reflect.Type {
+	for t != nil && (t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice && t.Elem().Kind() != reflect.Uint8) {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct || t.Implements(xmlUnmarshalerType) || reflect.PtrTo(t).Implements(xmlUnmarshalerType) {
+		return nil
+	}
+	return t
+}
+
+var xmlUnmarshalerType = reflect.TypeOf((*xml.Unmarshaler)(nil)).Elem()
+
+// xmlChildType finds the field of the struct type t that receives the child element with the given name.
+// It returns false if no such field exists.
+func xmlChildType(t reflect.Type, name string) (reflect.Type, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" && !field.Anonymous {
+			continue
+		}
+		tag := field.Tag.Get("xml")
+		if tag == "-" {
+			continue
+		}
+		fname, opts := tag, ""
+		if j := strings.IndexByte(tag, ','); j >= 0 {
+			fname, opts = tag[:j], tag[j+1:]
+		}
+		if strings.Contains(","+opts+",", ",any,") || strings.Contains(","+opts+",", ",innerxml,") {
+			return nil, true
+		}
+		if strings.Contains(","+opts+",", ",attr,") || strings.Contains(","+opts+",", ",chardata,") ||
+			strings.Contains(","+opts+",", ",cdata,") || strings.Contains(","+opts+",", ",comment,") {
+			continue
+		}
+		if field.Anonymous && fname == "" {
+			if ft := xmlElemType(field.Type); ft != nil {
+				if ct, ok := xmlChildType(ft, name); ok {
+					return ct, true
+				}
+			}
+			continue
+		}
+		if k := strings.LastIndexByte(fname, ' '); k >= 0 {
+			fname = fname[k+1:]
+		}
+		if j := strings.IndexByte(fname, '>'); j >= 0 {
+			// a path such as "a>b" matches the element "a"; its descendants are not checked
+			if fname[:j] == name {
+				return nil, true
+			}
+			continue
+		}
+		if fname == "" {
+			fname = field.Name
+		}
+		if fname == name {
+			return xmlElemType(field.Type), true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,160 @@
+package routing
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func newReadContext(contentType, body string) *Context {
+	var ctx fasthttp.RequestCtx
+	ctx.Request.Header.SetMethod("POST")
+	ctx.Request.SetRequestURI("/test")
+	ctx.Request.Header.SetContentType(contentType)
+	ctx.Request.SetBodyString(body)
+	return NewContext(&ctx)
+}
+
+func assertHTTPError(t *testing.T, err error, status int, message string) {
+	if assert.NotNil(t, err) {
+		if e, ok := err.(HTTPError); assert.True(t, ok, err.Error()) {
+			assert.Equal(t, status, e.StatusCode())
+			if message != "" {
+				assert.Equal(t, message, e.Error())
+			}
+		}
+	}
+}
+
+func TestJSONDataReaderOptions(t *testing.T) {
+	var data FA
+	c := newReadContext(MIME_JSON, "{\n  \"A1\": \"abc\",\n  \"A2\": \"x\"\n}")
+	assertHTTPError(t, c.Read(&data), fasthttp.StatusBadRequest,
+		`json: invalid value for "A2" (line 3, column 12)`)
+
+	c = newReadContext(MIME_JSON, "{\"A1\": \"abc\",\n \"A2\" 1}")
+	assertHTTPError(t, c.Read(&data), fasthttp.StatusBadRequest,
+		"invalid character '1' after object key (line 2, column 7)")
+
+	c = newReadContext(MIME_JSON, `{"A1":"abc"} {}`)
+	assertHTTPError(t, c.Read(&data), fasthttp.StatusBadRequest, "")
+
+	c = newReadContext(MIME_JSON, "")
+	assertHTTPError(t, c.Read(&data), fasthttp.StatusBadRequest, "json: unexpected end of JSON input (line 1, column 1)")
+
+	c = newReadContext(MIME_JSON, `{"A1":"abc","A3":1}`)
+	assert.Nil(t, c.Read(&data))
+	c.SetDataReader(MIME_JSON, &JSONDataReader{DecodeOptions{DisallowUnknownFields: true}})
+	assertHTTPError(t, c.Read(&data), fasthttp.StatusBadRequest, `json: unknown field "A3" (line 1, column 20)`)
+
+	var v map[string]interface{}
+	c = newReadContext(MIME_JSON, `{"a":{"b":[1]}}`)
+	ReadOptions(DecodeOptions{UseNumber: true, MaxDepth: 3})(c)
+	assert.Nil(t, c.Read(&v))
+	assert.Equal(t, json.Number("1"), v["a"].(map[string]interface{})["b"].([]interface{})[0])
+
+	c = newReadContext(MIME_JSON, `{"a":"[[[","b":{"c":[1]}}`)
+	ReadOptions(DecodeOptions{MaxDepth: 2})(c)
+	assertHTTPError(t, c.Read(&v), fasthttp.StatusBadRequest, "json: maximum nesting depth of 2 exceeded (line 1, column 21)")
+
+	c = newReadContext(MIME_JSON, `{"A1":"abc"}`)
+	ReadOptions(DecodeOptions{MaxBodyBytes: 10})(c)
+	assertHTTPError(t, c.Read(&data), fasthttp.StatusRequestEntityTooLarge, "request body exceeds 10 bytes")
+}
+
+func TestXMLDataReaderOptions(t *testing.T) {
+	type item struct {
+		Name string   `xml:"name,attr"`
+		Tags []string `xml:"tags>tag"`
+	}
+	var data struct {
+		FA
+		Items []item `xml:"item"`
+	}
+
+	body := "<data>\n<A1>abc</A1><item name=\"x\"><tags><tag>t</tag></tags></item>\n<A3/></data>"
+	c := newReadContext(MIME_XML, body)
+	assert.Nil(t, c.Read(&data))
+	assert.Equal(t, "abc", data.A1)
+	assert.Equal(t, []string{"t"}, data.Items[0].Tags)
+
+	c = newReadContext(MIME_XML, body)
+	ReadOptions(DecodeOptions{DisallowUnknownFields: true})(c)
+	assertHTTPError(t, c.Read(&data), fasthttp.StatusBadRequest, "xml: unknown element <A3> (line 3, column 6)")
+
+	c = newReadContext(MIME_XML2, "<data><item><size/></item></data>")
+	ReadOptions(DecodeOptions{DisallowUnknownFields: true})(c)
+	assertHTTPError(t, c.Read(&data), fasthttp.StatusBadRequest, "xml: unknown element <size> (line 1, column 20)")
+
+	c = newReadContext(MIME_XML, body)
+	ReadOptions(DecodeOptions{MaxDepth: 3})(c)
+	assertHTTPError(t, c.Read(&data), fasthttp.StatusBadRequest, "xml: maximum nesting depth of 3 exceeded (line 2, column 39)")
+
+	c = newReadContext(MIME_XML, "<data><A2>abc</A2></data>")
+	assertHTTPError(t, c.Read(&data), fasthttp.StatusBadRequest, `invalid value "abc" (line 1, column 19)`)
+
+	c = newReadContext(MIME_XML, "<data><A1>abc</A2></data>")
+	assertHTTPError(t, c.Read(&data), fasthttp.StatusBadRequest, "XML syntax error on line 1: element <A1> closed by </A2> (line 1, column 19)")
+
+	c = newReadContext(MIME_XML, body)
+	ReadOptions(DecodeOptions{MaxBodyBytes: 10})(c)
+	assertHTTPError(t, c.Read(&data), fasthttp.StatusRequestEntityTooLarge, "")
+}
+
+func TestBinaryDataReaders(t *testing.T) {
+	var data struct {
+		Name string `json:"name"`
+		Age  int    `json:"age"`
+	}
+
+	// {"name": "abc", "age": 30, "x": true}
+	c := newReadContext(MIME_MSGPACK, "\x83\xa4name\xa3abc\xa3age\x1e\xa1x\xc3")
+	assert.Nil(t, c.Read(&data))
+	assert.Equal(t, "abc", data.Name)
+	assert.Equal(t, 30, data.Age)
+
+	c = newReadContext(MIME_MSGPACK2, "\x81\xa3age\xa1x")
+	assertHTTPError(t, c.Read(&data), fasthttp.StatusBadRequest,
+		"msgpack: invalid value (offset 7)")
+
+	c = newReadContext(MIME_CBOR, "\xa3\x64name\x63xyz\x63age\x18\x2a\x61x\xf5")
+	assert.Nil(t, c.Read(&data))
+	assert.Equal(t, "xyz", data.Name)
+	assert.Equal(t, 42, data.Age)
+
+	c = newReadContext(MIME_CBOR, "\xa3\x64name\x63xyz\x63age\x18\x2a\x61x\xf5")
+	ReadOptions(DecodeOptions{DisallowUnknownFields: true})(c)
+	assertHTTPError(t, c.Read(&data), fasthttp.StatusBadRequest, `cbor: unknown field "x" (offset 18)`)
+
+	c = newReadContext(MIME_CBOR, "\x9f\x9f\x9f\xff\xff\xff")
+	ReadOptions(DecodeOptions{MaxDepth: 2})(c)
+	var v interface{}
+	assertHTTPError(t, c.Read(&v), fasthttp.StatusBadRequest, "cbor: maximum nesting depth of 2 exceeded (offset 3)")
+
+	c = newReadContext(MIME_MSGPACK, "\x83\xa4name\xa3abc\xa3age\x1e\xa1x\xc3")
+	ReadOptions(DecodeOptions{MaxBodyBytes: 10})(c)
+	assertHTTPError(t, c.Read(&data), fasthttp.StatusRequestEntityTooLarge, "request body exceeds 10 bytes")
+}
+
+func TestJSONAPIDataReader(t *testing.T) {
+	type person struct {
+		ID   int    `jsonapi:"primary,people"`
+		Name string `jsonapi:"attr,name"`
+	}
+	var p person
+	c := newReadContext(MIME_JSONAPI, `{"data":{"type":"people","id":"1","attributes":{"name":"abc","age":3}}}`)
+	assert.Nil(t, c.Read(&p))
+	assert.Equal(t, person{1, "abc"}, p)
+
+	ReadOptions(DecodeOptions{DisallowUnknownFields: true})(c)
+	assertHTTPError(t, c.Read(&p), fasthttp.StatusBadRequest, `jsonapi: unknown attribute "age"`)
+
+	c = newReadContext(MIME_JSONAPI, `{"data":{"type":"articles"}}`)
+	assertHTTPError(t, c.Read(&p), fasthttp.StatusConflict, `jsonapi: resource type "articles" does not match "people"`)
+
+	c = newReadContext(MIME_JSONAPI, `{"data":{"type":"people","attributes":{"name":[[1]]}}}`)
+	ReadOptions(DecodeOptions{MaxDepth: 3})(c)
+	assertHTTPError(t, c.Read(&p), fasthttp.StatusBadRequest, "json: maximum nesting depth of 3 exceeded (line 1, column 47)")
+}
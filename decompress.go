@@ -0,0 +1,100 @@
+package routing
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+)
+
+// DefaultMaxDecompressedBytes is the maximum size of a decompressed request body used when
+// DecompressOptions.MaxBytes is not set.
+var DefaultMaxDecompressedBytes = 10 << 20
+
+// DecompressOptions specifies how compressed request bodies are decoded.
+type DecompressOptions struct {
+	// MaxBytes is the maximum size of a decompressed request body in bytes. Bodies that would inflate
+	// beyond this size are rejected with a 413 HTTP error. If not set, DefaultMaxDecompressedBytes is used.
+	MaxBytes int
+}
+
+// Decompressor returns a handler that decodes request bodies sent with a "Content-Encoding" of gzip or deflate,
+// so that the following handlers, including Context.Read(), see the original content.
+// The "Content-Encoding" header is removed once the body has been decoded.
+//
+// A request using any other content coding is rejected with a 415 HTTP error and an "Accept-Encoding"
+// response header listing the supported codings. A body that cannot be decoded results in a 400 HTTP error.
+//
+//     r := routing.New()
+//     r.Use(routing.Decompressor(routing.DecompressOptions{MaxBytes: 50 << 20}))
+func Decompressor(opts ...DecompressOptions) Handler {
+	var options DecompressOptions
+	if len(opts) > 0 {
+		options = opts[0]
+	}
+	return func(c *Context) error {
+		return decompressBody(c.RequestCtx, options.MaxBytes)
+	}
+}
+
+// decompressBody replaces the request body with its decoded content according to the "Content-Encoding" header.
+func decompressBody(ctx *fasthttp.RequestCtx, maxBytes int) error {
+	ce := strings.TrimSpace(string(ctx.Request.Header.Peek("Content-Encoding")))
+	if ce == "" {
+		return nil
+	}
+	if maxBytes <= 0 {
+		maxBytes = DefaultMaxDecompressedBytes
+	}
+
+	body := ctx.PostBody()
+	codings := strings.Split(ce, ",")
+	// codings are listed in the order in which they were applied, so they are undone in reverse
+	for i := len(codings) - 1; i >= 0; i-- {
+		coding := strings.ToLower(strings.TrimSpace(codings[i]))
+		var (
+			r   io.Reader
+			err error
+		)
+		switch coding {
+		case "identity":
+			continue
+		case "gzip", "x-gzip":
+			r, err = gzip.NewReader(bytes.NewReader(body))
+		case "deflate":
+			r, err = newDeflateReader(body)
+		default:
+			ctx.Response.Header.Set("Accept-Encoding", "gzip, deflate")
+			return NewHTTPError(fasthttp.StatusUnsupportedMediaType, "unsupported Content-Encoding: "+coding)
+		}
+		if err != nil {
+			return NewHTTPError(fasthttp.StatusBadRequest, "invalid "+coding+" request body: "+err.Error())
+		}
+		if body, err = ioutil.ReadAll(io.LimitReader(r, int64(maxBytes)+1)); err != nil {
+			return NewHTTPError(fasthttp.StatusBadRequest, "invalid "+coding+" request body: "+err.Error())
+		}
+		if len(body) > maxBytes {
+			return NewHTTPError(fasthttp.StatusRequestEntityTooLarge, "decompressed request body exceeds "+strconv.Itoa(maxBytes)+" bytes")
+		}
+	}
+
+	ctx.Request.SetBody(body)
+	ctx.Request.Header.Del("Content-Encoding")
+	return nil
+}
+
+// newDeflateReader returns a reader for a deflate-encoded body.
+// As some clients send raw DEFLATE data instead of the zlib format required by RFC 7230, both are accepted.
+func newDeflateReader(body []byte) (io.Reader, error) {
+	if r, err := zlib.NewReader(bytes.NewReader(body)); err == nil {
+		return r, nil
+	}
+	return flate.NewReader(bufio.NewReader(bytes.NewReader(body))), nil
+}
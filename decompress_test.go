@@ -0,0 +1,79 @@
+package routing
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func gzipData(data string) string {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	w.Write([]byte(data))
+	w.Close()
+	return buf.String()
+}
+
+func TestDecompressor(t *testing.T) {
+	var zbuf, fbuf bytes.Buffer
+	zw := zlib.NewWriter(&zbuf)
+	zw.Write([]byte(`{"A1":"abc","A2":100}`))
+	zw.Close()
+	fw, _ := flate.NewWriter(&fbuf, flate.DefaultCompression)
+	fw.Write([]byte(`{"A1":"abc","A2":100}`))
+	fw.Close()
+
+	tests := []struct {
+		tag      string
+		encoding string
+		body     string
+	}{
+		{"t1", "", `{"A1":"abc","A2":100}`},
+		{"t2", "gzip", gzipData(`{"A1":"abc","A2":100}`)},
+		{"t3", "deflate", zbuf.String()},
+		{"t4", "deflate", fbuf.String()},
+		{"t5", "gzip, identity", gzipData(`{"A1":"abc","A2":100}`)},
+		{"t6", "gzip, gzip", gzipData(gzipData(`{"A1":"abc","A2":100}`))},
+	}
+	h := Decompressor()
+	for _, test := range tests {
+		var data FA
+		c := newReadContext(MIME_JSON, test.body)
+		c.Request.Header.Set("Content-Encoding", test.encoding)
+		assert.Nil(t, h(c), test.tag)
+		assert.Equal(t, "", string(c.Request.Header.Peek("Content-Encoding")), test.tag)
+		assert.Nil(t, c.Read(&data), test.tag)
+		assert.Equal(t, FA{"abc", 100}, data, test.tag)
+	}
+
+	c := newReadContext(MIME_JSON, "abc")
+	c.Request.Header.Set("Content-Encoding", "br")
+	assertHTTPError(t, h(c), fasthttp.StatusUnsupportedMediaType, "unsupported Content-Encoding: br")
+	assert.Equal(t, "gzip, deflate", string(c.Response.Header.Peek("Accept-Encoding")))
+
+	c = newReadContext(MIME_JSON, "abc")
+	c.Request.Header.Set("Content-Encoding", "gzip")
+	assertHTTPError(t, h(c), fasthttp.StatusBadRequest, "")
+
+	c = newReadContext(MIME_JSON, gzipData(string(bytes.Repeat([]byte("a"), 101))))
+	c.Request.Header.Set("Content-Encoding", "gzip")
+	assertHTTPError(t, Decompressor(DecompressOptions{MaxBytes: 100})(c), fasthttp.StatusRequestEntityTooLarge,
+		"decompressed request body exceeds 100 bytes")
+}
+
+func TestContextReadDecompress(t *testing.T) {
+	var data FA
+	c := newReadContext(MIME_JSON, gzipData(`{"A1":"abc","A2":100}`))
+	c.Request.Header.Set("Content-Encoding", "gzip")
+	assert.Nil(t, c.ReadWith(&data, DecompressOptions{}))
+	assert.Equal(t, FA{"abc", 100}, data)
+
+	c = newReadContext(MIME_JSON, gzipData(`{"A1":"abc","A2":100}`))
+	c.Request.Header.Set("Content-Encoding", "gzip")
+	assertHTTPError(t, c.ReadWith(&data, DecompressOptions{MaxBytes: 10}), fasthttp.StatusRequestEntityTooLarge, "")
+}
@@ -5,6 +5,10 @@
 package routing
 
 import (
+	"encoding/xml"
+	"errors"
+	"sync"
+
 	"github.com/valyala/fasthttp"
 )
 
@@ -17,18 +21,78 @@ type HTTPError interface {
 
 // Error contains the error information reported by calling Context.Error().
 type httpError struct {
-	Status  int    `json:"status" xml:"status"`
-	Message string `json:"message" xml:"message"`
+	XMLName xml.Name `json:"-" xml:"error" csv:"-"`
+	Status  int      `json:"status" xml:"status" csv:"status"`
+	Message string   `json:"message" xml:"message" csv:"message"`
+	cause   error
+}
+
+type errorStatus struct {
+	target  error
+	status  int
+	message string
 }
 
+var (
+	errorStatusesMu sync.RWMutex
+	errorStatuses   []errorStatus
+)
+
 // NewHTTPError creates a new HttpError instance.
 // If the error message is not given, fasthttp.StatusMessage() will be called
 // to generate the message based on the status code.
 func NewHTTPError(status int, message ...string) HTTPError {
 	if len(message) > 0 {
-		return &httpError{status, message[0]}
+		return &httpError{Status: status, Message: message[0]}
 	}
-	return &httpError{status, fasthttp.StatusMessage(status)}
+	return &httpError{Status: status, Message: fasthttp.StatusMessage(status)}
+}
+
+// WrapHTTPError creates a new HTTPError instance that wraps the given cause.
+// The public message is the only error message sent to clients, while the cause remains available
+// to errors.Is(), errors.As() and errors.Unwrap() for logging and inspection.
+// If the public message is empty, fasthttp.StatusMessage() will be called to generate it.
+func WrapHTTPError(status int, cause error, publicMessage string) HTTPError {
+	if publicMessage == "" {
+		publicMessage = fasthttp.StatusMessage(status)
+	}
+	return &httpError{Status: status, Message: publicMessage, cause: cause}
+}
+
+// RegisterErrorStatus maps the errors matching target (as reported by errors.Is()) to the given HTTP status code.
+// The optional public message replaces the error message sent to clients; if not given,
+// fasthttp.StatusMessage() will be called to generate it, so that the details of the error are not disclosed.
+// Errors are matched against the registered targets in the order the targets are registered.
+//
+//     routing.RegisterErrorStatus(sql.ErrNoRows, fasthttp.StatusNotFound)
+func RegisterErrorStatus(target error, status int, publicMessage ...string) {
+	s := errorStatus{target: target, status: status}
+	if len(publicMessage) > 0 {
+		s.message = publicMessage[0]
+	}
+	errorStatusesMu.Lock()
+	errorStatuses = append(errorStatuses, s)
+	errorStatusesMu.Unlock()
+}
+
+// AsHTTPError converts the given error into an HTTPError.
+// If an error in the chain of err implements HTTPError, that error is returned.
+// Otherwise, if err matches a target registered via RegisterErrorStatus(), err is wrapped in
+// an HTTPError with the registered status code and public message.
+// The second return value is false if err cannot be converted.
+func AsHTTPError(err error) (HTTPError, bool) {
+	var httpErr HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr, true
+	}
+	errorStatusesMu.RLock()
+	defer errorStatusesMu.RUnlock()
+	for _, s := range errorStatuses {
+		if errors.Is(err, s.target) {
+			return WrapHTTPError(s.status, err, s.message), true
+		}
+	}
+	return nil, false
 }
 
 // Error returns the error message.
@@ -40,3 +104,8 @@ func (e *httpError) Error() string {
 func (e *httpError) StatusCode() int {
 	return e.Status
 }
+
+// Unwrap returns the cause of the error, or nil if the error does not wrap another error.
+func (e *httpError) Unwrap() error {
+	return e.cause
+}
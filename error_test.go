@@ -5,7 +5,10 @@
 package routing
 
 import (
+	"database/sql"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"testing"
 
@@ -25,3 +28,62 @@ func TestNewHttpError(t *testing.T) {
 	s, _ := json.Marshal(e)
 	assert.Equal(t, `{"status":404,"message":"abc"}`, string(s))
 }
+
+func TestWrapHTTPError(t *testing.T) {
+	cause := errors.New("connection refused")
+	e := WrapHTTPError(http.StatusServiceUnavailable, cause, "try again later")
+	assert.Equal(t, fasthttp.StatusServiceUnavailable, e.StatusCode())
+	assert.Equal(t, "try again later", e.Error())
+	assert.True(t, errors.Is(e, cause))
+	assert.Equal(t, cause, errors.Unwrap(e))
+
+	s, _ := json.Marshal(e)
+	assert.Equal(t, `{"status":503,"message":"try again later"}`, string(s))
+
+	e = WrapHTTPError(http.StatusBadGateway, cause, "")
+	assert.Equal(t, fasthttp.StatusMessage(http.StatusBadGateway), e.Error())
+
+	var httpErr HTTPError
+	assert.True(t, errors.As(fmt.Errorf("loading users: %w", e), &httpErr))
+	assert.Equal(t, e, httpErr)
+}
+
+func TestAsHTTPError(t *testing.T) {
+	defer func(s []errorStatus) { errorStatuses = s }(errorStatuses)
+	errorStatuses = nil
+
+	RegisterErrorStatus(sql.ErrNoRows, http.StatusNotFound)
+	RegisterErrorStatus(sql.ErrTxDone, http.StatusConflict, "transaction is closed")
+
+	e, ok := AsHTTPError(fmt.Errorf("user 1: %w", sql.ErrNoRows))
+	if assert.True(t, ok) {
+		assert.Equal(t, fasthttp.StatusNotFound, e.StatusCode())
+		assert.Equal(t, "Not Found", e.Error())
+		assert.True(t, errors.Is(e, sql.ErrNoRows))
+	}
+
+	e, ok = AsHTTPError(sql.ErrTxDone)
+	if assert.True(t, ok) {
+		assert.Equal(t, fasthttp.StatusConflict, e.StatusCode())
+		assert.Equal(t, "transaction is closed", e.Error())
+	}
+
+	httpErr := NewHTTPError(http.StatusForbidden)
+	e, ok = AsHTTPError(fmt.Errorf("access: %w", httpErr))
+	assert.True(t, ok)
+	assert.Equal(t, httpErr, e)
+
+	_, ok = AsHTTPError(errors.New("abc"))
+	assert.False(t, ok)
+
+	router := New()
+	router.Get("/users", func(c *Context) error {
+		return fmt.Errorf("user 1: %w", sql.ErrNoRows)
+	})
+	var ctx fasthttp.RequestCtx
+	ctx.Request.Header.SetMethod("GET")
+	ctx.Request.SetRequestURI("/users")
+	router.HandleRequest(&ctx)
+	assert.Equal(t, fasthttp.StatusNotFound, ctx.Response.StatusCode())
+	assert.Equal(t, "Not Found", string(ctx.Response.Body()))
+}
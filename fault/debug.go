@@ -0,0 +1,176 @@
+package fault
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"os"
+
+	"github.com/jackwhelpton/fasthttp-routing/v2"
+	"github.com/jackwhelpton/fasthttp-routing/v2/content"
+	"github.com/valyala/fasthttp"
+)
+
+// debugSourceLines is the number of source lines shown before and after the line of each stack frame.
+const debugSourceLines = 5
+
+type (
+	debugInfo struct {
+		Status  int               `json:"status"`
+		Message string            `json:"message"`
+		Error   string            `json:"error"`
+		Panic   string            `json:"panic,omitempty"`
+		Stack   []debugFrame      `json:"stack,omitempty"`
+		Method  string            `json:"method"`
+		URI     string            `json:"uri"`
+		Route   string            `json:"route,omitempty"`
+		Headers map[string]string `json:"headers"`
+		Params  map[string]string `json:"params"`
+		Data    map[string]string `json:"data"`
+	}
+
+	debugFrame struct {
+		StackFrame
+		Source []sourceLine `json:"source,omitempty"`
+	}
+
+	sourceLine struct {
+		Line    int    `json:"line"`
+		Code    string `json:"code"`
+		Current bool   `json:"current,omitempty"`
+	}
+)
+
+// writeDebugError writes the debug page of an error to the response.
+// The err parameter is the error returned by the handlers, while converted is the result of
+// the error conversion function, which determines the HTTP status and the message of the page.
+func writeDebugError(c *routing.Context, err, converted error) {
+	info := newDebugInfo(c, err, converted)
+	c.Response.ResetBody()
+	c.Response.SetStatusCode(info.Status)
+	if prefersJSON(c.RequestCtx) {
+		c.SetContentType("application/json")
+		enc := json.NewEncoder(c.RequestCtx)
+		enc.SetIndent("", "  ")
+		enc.Encode(info)
+		return
+	}
+	c.SetContentType("text/html; charset=utf-8")
+	debugTemplate.Execute(c.RequestCtx, info)
+}
+
+func newDebugInfo(c *routing.Context, err, converted error) *debugInfo {
+	info := &debugInfo{
+		Status:  fasthttp.StatusInternalServerError,
+		Message: converted.Error(),
+		Error:   errorChain(err),
+		Method:  string(c.Method()),
+		URI:     string(c.RequestURI()),
+		Headers: make(map[string]string),
+		Params:  c.Params(),
+		Data:    make(map[string]string),
+	}
+	if httpError, ok := routing.AsHTTPError(converted); ok {
+		info.Status = httpError.StatusCode()
+		info.Message = httpError.Error()
+	}
+	if route := c.Route(); route != nil {
+		info.Route = route.String()
+	}
+	c.Request.Header.VisitAll(func(key, value []byte) {
+		if v, ok := info.Headers[string(key)]; ok {
+			info.Headers[string(key)] = v + ", " + string(value)
+		} else {
+			info.Headers[string(key)] = string(value)
+		}
+	})
+	for k, v := range c.Data() {
+		info.Data[k] = fmt.Sprintf("%+v", v)
+	}
+	var pe *PanicError
+	if errors.As(err, &pe) {
+		info.Panic = fmt.Sprintf("%+v", pe.Value)
+		for _, frame := range pe.Stack {
+			info.Stack = append(info.Stack, debugFrame{frame, readSource(frame.File, frame.Line)})
+		}
+	}
+	return info
+}
+
+// readSource returns the lines of the given source file around the given line.
+// Nil is returned if the file cannot be read.
+func readSource(file string, line int) []sourceLine {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var lines []sourceLine
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan() && n <= line+debugSourceLines; n++ {
+		if n >= line-debugSourceLines {
+			lines = append(lines, sourceLine{n, scanner.Text(), n == line})
+		}
+	}
+	return lines
+}
+
+// debugOffers lists the media types of the debug information, the HTML page being preferred among equally acceptable ones.
+var debugOffers = []string{"text/html", "application/json", "application/xhtml+xml"}
+
+// prefersJSON checks if the "Accept" header of the request prefers JSON to HTML.
+func prefersJSON(ctx *fasthttp.RequestCtx) bool {
+	return content.NegotiateContentType(ctx, debugOffers, "text/html") == "application/json"
+}
+
+var debugTemplate = template.Must(template.New("debug").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Status}} {{.Message}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #333; }
+h1 { color: #c00; }
+h2 { border-bottom: 1px solid #ccc; }
+table { border-collapse: collapse; }
+td { padding: 2px 12px 2px 0; vertical-align: top; font-family: monospace; }
+pre { background: #f6f6f6; padding: 8px; overflow-x: auto; }
+.frame { margin-bottom: 1em; }
+.current { background: #fdd; font-weight: bold; }
+</style>
+</head>
+<body>
+<h1>{{.Status}} {{.Message}}</h1>
+<pre>{{.Error}}</pre>
+{{if .Panic}}<h2>Panic</h2>
+<pre>{{.Panic}}</pre>
+{{end}}{{if .Stack}}<h2>Stack</h2>
+{{range .Stack}}<div class="frame">
+<div><code>{{.Function}}</code><br><code>{{.File}}:{{.Line}}</code></div>
+{{if .Source}}<pre>{{range .Source}}<span{{if .Current}} class="current"{{end}}>{{printf "%5d" .Line}}  {{.Code}}</span>
+{{end}}</pre>{{end}}
+</div>
+{{end}}{{end}}<h2>Request</h2>
+<table>
+<tr><td>Method</td><td>{{.Method}}</td></tr>
+<tr><td>URI</td><td>{{.URI}}</td></tr>
+<tr><td>Route</td><td>{{.Route}}</td></tr>
+</table>
+<h2>Headers</h2>
+<table>
+{{range $k, $v := .Headers}}<tr><td>{{$k}}</td><td>{{$v}}</td></tr>
+{{end}}</table>
+<h2>Route Parameters</h2>
+<table>
+{{range $k, $v := .Params}}<tr><td>{{$k}}</td><td>{{$v}}</td></tr>
+{{end}}</table>
+<h2>Context Data</h2>
+<table>
+{{range $k, $v := .Data}}<tr><td>{{$k}}</td><td>{{$v}}</td></tr>
+{{end}}</table>
+</body>
+</html>
+`))
@@ -0,0 +1,113 @@
+package fault
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/jackwhelpton/fasthttp-routing/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func TestDebug(t *testing.T) {
+	var buf bytes.Buffer
+	router := routing.New()
+	router.Use(RecoveryWith(getLogger(&buf), Options{Debug: true}))
+	router.Get("/users/<id>", func(c *routing.Context) error {
+		c.Set("user", "<admin>")
+		panic("xyz")
+	})
+	router.Get("/posts", func(c *routing.Context) error {
+		return routing.WrapHTTPError(fasthttp.StatusConflict, errors.New("duplicate key"), "post exists")
+	})
+
+	var ctx fasthttp.RequestCtx
+	ctx.Request.Header.SetMethod("GET")
+	ctx.Request.SetRequestURI("/users/1")
+	ctx.Request.Header.Set("X-Test", "abc")
+	router.HandleRequest(&ctx)
+	assert.Equal(t, fasthttp.StatusInternalServerError, ctx.Response.StatusCode())
+	assert.Equal(t, "text/html; charset=utf-8", string(ctx.Response.Header.ContentType()))
+	body := string(ctx.Response.Body())
+	assert.Contains(t, body, "<h1>500 xyz</h1>")
+	assert.Contains(t, body, "debug_test.go")
+	assert.Contains(t, body, `panic(&#34;xyz&#34;)`)
+	assert.Contains(t, body, "<td>X-Test</td><td>abc</td>")
+	assert.Contains(t, body, "<td>id</td><td>1</td>")
+	assert.Contains(t, body, "<td>user</td><td>&lt;admin&gt;</td>")
+	assert.Contains(t, body, "GET /users/&lt;id&gt;")
+
+	ctx.Response.Reset()
+	ctx.Request.Header.Set("Accept", "text/html;q=0.9, application/json")
+	router.HandleRequest(&ctx)
+	assert.Equal(t, "application/json", string(ctx.Response.Header.ContentType()))
+	var info debugInfo
+	if assert.Nil(t, json.Unmarshal(ctx.Response.Body(), &info)) {
+		assert.Equal(t, fasthttp.StatusInternalServerError, info.Status)
+		assert.Equal(t, "xyz", info.Panic)
+		assert.Equal(t, "GET /users/<id>", info.Route)
+		assert.Equal(t, map[string]string{"id": "1"}, info.Params)
+		assert.Equal(t, map[string]string{"user": "<admin>"}, info.Data)
+		assert.Equal(t, "abc", info.Headers["X-Test"])
+		if assert.NotEmpty(t, info.Stack) {
+			frame := info.Stack[0]
+			assert.Contains(t, frame.File, "debug_test.go")
+			for _, line := range frame.Source {
+				assert.Equal(t, line.Line == frame.Line, line.Current)
+			}
+			assert.Len(t, frame.Source, 2*debugSourceLines+1)
+		}
+	}
+
+	ctx.Response.Reset()
+	ctx.Request.SetRequestURI("/posts")
+	router.HandleRequest(&ctx)
+	assert.Equal(t, fasthttp.StatusConflict, ctx.Response.StatusCode())
+	info = debugInfo{}
+	if assert.Nil(t, json.Unmarshal(ctx.Response.Body(), &info)) {
+		assert.Equal(t, "post exists", info.Message)
+		assert.Equal(t, "post exists: duplicate key", info.Error)
+		assert.Empty(t, info.Stack)
+	}
+
+	ctx.Response.Reset()
+	router = routing.New()
+	router.Use(ErrorHandlerWith(nil, Options{Debug: true}))
+	router.Get("/posts", func(c *routing.Context) error {
+		return routing.WrapHTTPError(fasthttp.StatusConflict, errors.New("duplicate key"), "post exists")
+	})
+	router.HandleRequest(&ctx)
+	assert.Equal(t, fasthttp.StatusConflict, ctx.Response.StatusCode())
+	assert.Contains(t, string(ctx.Response.Body()), `"error": "post exists: duplicate key"`)
+
+	ctx.Response.Reset()
+	router = routing.New()
+	router.Use(ErrorHandlerWith(nil, Options{}))
+	router.Get("/posts", func(c *routing.Context) error {
+		return routing.WrapHTTPError(fasthttp.StatusConflict, errors.New("duplicate key"), "post exists")
+	})
+	router.HandleRequest(&ctx)
+	assert.Equal(t, "post exists", string(ctx.Response.Body()))
+}
+
+func Test_prefersJSON(t *testing.T) {
+	tests := []struct {
+		accept string
+		result bool
+	}{
+		{"", false},
+		{"*/*", false},
+		{"text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", false},
+		{"application/json", true},
+		{"application/*, text/html;q=0.5", true},
+		{"application/json;q=0.5, text/html", false},
+		{"application/vnd.api+json", false},
+	}
+	for _, test := range tests {
+		var ctx fasthttp.RequestCtx
+		ctx.Request.Header.Set("Accept", test.accept)
+		assert.Equal(t, test.result, prefersJSON(&ctx), test.accept)
+	}
+}
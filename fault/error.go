@@ -6,20 +6,28 @@
 package fault
 
 import (
+	"errors"
+	"strings"
+
 	"github.com/jackwhelpton/fasthttp-routing/v2"
 	"github.com/valyala/fasthttp"
 )
 
 // ErrorHandler returns a handler that handles errors returned by the handlers following this one.
-// If the error implements routing.HTTPError, the handler will set the HTTP status code accordingly.
+// If the error can be converted by routing.AsHTTPError(), the handler will set the HTTP status code accordingly.
 // Otherwise the HTTP status is set as fasthttp.StatusInternalServerError. The handler will also write the error
-// as the response body.
+// as the response body using the data writer negotiated for the request (see routing.Context.WriteError());
+// for a converted error, only its public message is written. Errors of type *routing.Problem are written as
+// "application/problem+json" or "application/problem+xml" documents.
 //
-// A log function can be provided to log a message whenever an error is handled. If nil, no message will be logged.
+// A log function can be provided to log a message whenever an error is handled. The message includes the causes
+// wrapped by the error. If nil, no message will be logged.
 //
 // An optional error conversion function can also be provided to convert an error into a normalized one
 // before sending it to the response.
 //
+// Use ErrorHandlerWith to enable the development-mode error pages.
+//
 //     import (
 //         "log"
 //         "github.com/jackwhelpton/fasthttp-routing/v2"
@@ -30,6 +38,13 @@ import (
 //     r.Use(fault.ErrorHandler(log.Printf))
 //     r.Use(fault.PanicHandler(log.Printf))
 func ErrorHandler(logf LogFunc, errorf ...ConvertErrorFunc) routing.Handler {
+	return ErrorHandlerWith(logf, newOptions(errorf))
+}
+
+// ErrorHandlerWith returns a handler like ErrorHandler, configured with the given options.
+//
+//     r.Use(fault.ErrorHandlerWith(log.Printf, fault.Options{Debug: true}))
+func ErrorHandlerWith(logf LogFunc, opts Options) routing.Handler {
 	return func(c *routing.Context) error {
 		err := c.Next()
 		if err == nil {
@@ -37,28 +52,41 @@ func ErrorHandler(logf LogFunc, errorf ...ConvertErrorFunc) routing.Handler {
 		}
 
 		if logf != nil {
-			logf("%v", err)
-		}
-
-		if len(errorf) > 0 {
-			err = errorf[0](c, err)
+			logf("%v", errorChain(err))
 		}
 
-		writeError(c, err)
+		opts.write(c, err)
 		c.Abort()
 
 		return nil
 	}
 }
 
-// writeError writes the error to the response.
-// If the error implements HTTPError, it will set the HTTP status as the result of the StatusCode() call of the error.
-// Otherwise, the HTTP status will be set as fasthttp.StatusInternalServerError.
+// writeError writes the error to the response using the data writer negotiated for the request.
+// See routing.Context.WriteError() for details.
 func writeError(c *routing.Context, err error) {
-	if httpError, ok := err.(routing.HTTPError); ok {
-		c.Response.Header.SetStatusCode(httpError.StatusCode())
+	if c.WriteError(err) == nil {
+		return
+	}
+	if httpError, ok := routing.AsHTTPError(err); ok {
+		c.Error(httpError.Error(), httpError.StatusCode())
 	} else {
-		c.Response.Header.SetStatusCode(fasthttp.StatusInternalServerError)
+		c.Error(fasthttp.StatusMessage(fasthttp.StatusInternalServerError), fasthttp.StatusInternalServerError)
+	}
+}
+
+// errorChain returns the messages of the given error and the errors it wraps, separated by colons.
+// A message is omitted if it is already included in the message of the error wrapping it.
+func errorChain(err error) string {
+	msg := err.Error()
+	for last := msg; ; {
+		if err = errors.Unwrap(err); err == nil {
+			return msg
+		}
+		m := err.Error()
+		if !strings.Contains(last, m) {
+			msg += ": " + m
+		}
+		last = m
 	}
-	c.Write(err)
 }
@@ -3,6 +3,7 @@ package fault
 import (
 	"bytes"
 	"errors"
+	"fmt"
 	"testing"
 
 	"github.com/jackwhelpton/fasthttp-routing/v2"
@@ -20,7 +21,7 @@ func TestErrorHandler(t *testing.T) {
 	c := routing.NewContext(&ctx, h, handler1, handler2)
 	assert.Nil(t, c.Next())
 	assert.Equal(t, fasthttp.StatusInternalServerError, c.Response.StatusCode())
-	assert.Equal(t, "abc", string(c.Response.Body()))
+	assert.Equal(t, "Internal Server Error", string(c.Response.Body()))
 	assert.Equal(t, "abc", buf.String())
 
 	buf.Reset()
@@ -37,7 +38,7 @@ func TestErrorHandler(t *testing.T) {
 	c = routing.NewContext(&ctx, h, handler1, handler2)
 	assert.Nil(t, c.Next())
 	assert.Equal(t, fasthttp.StatusInternalServerError, c.Response.StatusCode())
-	assert.Equal(t, "123", string(c.Response.Body()))
+	assert.Equal(t, "Internal Server Error", string(c.Response.Body()))
 	assert.Equal(t, "abc", buf.String())
 
 	buf.Reset()
@@ -46,7 +47,7 @@ func TestErrorHandler(t *testing.T) {
 	c = routing.NewContext(&ctx, h, handler1, handler2)
 	assert.Nil(t, c.Next())
 	assert.Equal(t, fasthttp.StatusInternalServerError, c.Response.StatusCode())
-	assert.Equal(t, "abc", string(c.Response.Body()))
+	assert.Equal(t, "Internal Server Error", string(c.Response.Body()))
 	assert.Equal(t, "", buf.String())
 }
 
@@ -57,12 +58,36 @@ func Test_writeError(t *testing.T) {
 	c := routing.NewContext(&ctx)
 	writeError(c, errors.New("abc"))
 	assert.Equal(t, fasthttp.StatusInternalServerError, ctx.Response.StatusCode())
-	assert.Equal(t, "abc", string(ctx.Response.Body()))
+	assert.Equal(t, "Internal Server Error", string(ctx.Response.Body()))
 
 	ctx.Response.Reset()
 	writeError(c, routing.NewHTTPError(fasthttp.StatusNotFound, "xyz"))
 	assert.Equal(t, fasthttp.StatusNotFound, ctx.Response.StatusCode())
 	assert.Equal(t, "xyz", string(ctx.Response.Body()))
+
+	ctx.Response.Reset()
+	ctx.Request.Header.Set("Accept", "application/xml")
+	writeError(c, routing.NewProblem(fasthttp.StatusConflict, "xyz"))
+	assert.Equal(t, fasthttp.StatusConflict, ctx.Response.StatusCode())
+	assert.Equal(t, routing.MIME_PROBLEM_XML+"; charset=UTF-8", string(ctx.Response.Header.ContentType()))
+	assert.Equal(t, `<problem xmlns="urn:ietf:rfc:7807"><detail>xyz</detail><status>409</status><title>Conflict</title></problem>`, string(ctx.Response.Body()))
+}
+
+func TestErrorHandlerWrappedError(t *testing.T) {
+	var buf bytes.Buffer
+	h := ErrorHandler(getLogger(&buf))
+
+	var ctx fasthttp.RequestCtx
+	ctx.Request.Header.SetMethod("GET")
+	ctx.Request.SetRequestURI("/users/")
+	c := routing.NewContext(&ctx, h, func(c *routing.Context) error {
+		cause := fmt.Errorf("query users: %w", errors.New("connection refused"))
+		return fmt.Errorf("list users: %w", routing.WrapHTTPError(fasthttp.StatusServiceUnavailable, cause, "try again later"))
+	})
+	assert.Nil(t, c.Next())
+	assert.Equal(t, fasthttp.StatusServiceUnavailable, c.Response.StatusCode())
+	assert.Equal(t, "try again later", string(c.Response.Body()))
+	assert.Equal(t, "list users: try again later: query users: connection refused", buf.String())
 }
 
 func convertError(c *routing.Context, err error) error {
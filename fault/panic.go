@@ -4,15 +4,16 @@ import (
 	"bytes"
 	"fmt"
 	"runtime"
+	"strings"
 
 	"github.com/jackwhelpton/fasthttp-routing/v2"
 )
 
 // PanicHandler returns a handler that recovers from panics happened in the handlers following this one.
-// When a panic is recovered, it will be converted into an error and returned to the parent handlers.
+// When a panic is recovered, it will be converted into a *PanicError and returned to the parent handlers.
 //
 // A log function can be provided to log the panic call stack information. If the log function is nil,
-// no message will be logged.
+// no message will be logged. The panic is also reported to the reporters registered via RegisterPanicReporter().
 //
 //     import (
 //         "log"
@@ -27,13 +28,12 @@ func PanicHandler(logf LogFunc) routing.Handler {
 	return func(c *routing.Context) (err error) {
 		defer func() {
 			if e := recover(); e != nil {
+				stack := getCallStack(3)
+				reportPanic(c, e, stack)
 				if logf != nil {
-					logf("recovered from panic:%v", getCallStack(4))
-				}
-				var ok bool
-				if err, ok = e.(error); !ok {
-					err = fmt.Errorf("%v", e)
+					logf("recovered from panic:%v", formatCallStack(stack))
 				}
+				err = &PanicError{Value: e, Stack: stack}
 			}
 		}()
 
@@ -41,16 +41,61 @@ func PanicHandler(logf LogFunc) routing.Handler {
 	}
 }
 
-// getCallStack returns the current call stack information as a string.
+// PanicError is the error returned by PanicHandler when it recovers from a panic.
+// If the panic value is an error, PanicError wraps it, so that it can be found by errors.Is() and errors.As().
+type PanicError struct {
+	// Value is the value passed to panic().
+	Value interface{}
+	// Stack is the call stack of the goroutine when it panicked.
+	Stack []StackFrame
+}
+
+// Error returns the panic value formatted as a string.
+func (e *PanicError) Error() string {
+	if err, ok := e.Value.(error); ok {
+		return err.Error()
+	}
+	return fmt.Sprintf("%v", e.Value)
+}
+
+// Unwrap returns the panic value if it is an error, or nil otherwise.
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// StackFrame describes a function call in a call stack.
+type StackFrame struct {
+	Function string `json:"function"`
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+}
+
+// getCallStack returns the current call stack.
 // The skip parameter specifies how many top frames should be skipped.
-func getCallStack(skip int) string {
-	buf := new(bytes.Buffer)
-	for i := skip; ; i++ {
-		_, file, line, ok := runtime.Caller(i)
-		if !ok {
+// Frames belonging to the runtime itself are omitted, so that the result does
+// not depend on how a particular Go version dispatches deferred calls and panics.
+func getCallStack(skip int) []StackFrame {
+	var stack []StackFrame
+	pcs := make([]uintptr, 64)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(skip, pcs)])
+	for {
+		frame, more := frames.Next()
+		if !strings.HasPrefix(frame.Function, "runtime.") {
+			stack = append(stack, StackFrame{frame.Function, frame.File, frame.Line})
+		}
+		if !more {
 			break
 		}
-		fmt.Fprintf(buf, "\n%s:%d", file, line)
+	}
+	return stack
+}
+
+// formatCallStack returns the call stack information as a string, with one frame per line.
+func formatCallStack(stack []StackFrame) string {
+	buf := new(bytes.Buffer)
+	for _, frame := range stack {
+		fmt.Fprintf(buf, "\n%s:%d", frame.File, frame.Line)
 	}
 	return buf.String()
 }
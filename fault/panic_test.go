@@ -35,7 +35,7 @@ func TestPanicHandler(t *testing.T) {
 	c = routing.NewContext(&ctx, h2, h, handler3, handler2)
 	assert.Nil(t, c.Next())
 	assert.Equal(t, fasthttp.StatusInternalServerError, ctx.Response.StatusCode())
-	assert.Equal(t, "xyz", string(ctx.Response.Body()))
+	assert.Equal(t, "Internal Server Error", string(ctx.Response.Body()))
 	assert.Contains(t, buf.String(), "recovery_test.go")
 	assert.Contains(t, buf.String(), "xyz")
 }
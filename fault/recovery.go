@@ -15,21 +15,38 @@ type (
 
 	// ConvertErrorFunc converts an error into a different format so that it is more appropriate for rendering purpose.
 	ConvertErrorFunc func(*routing.Context, error) error
+
+	// Options configures the handlers created by ErrorHandlerWith and RecoveryWith.
+	Options struct {
+		// ConvertError converts an error into a normalized one before sending it to the response. Optional.
+		ConvertError ConvertErrorFunc
+		// Debug enables the development-mode error pages.
+		//
+		// When Debug is true, errors are rendered as an HTML page showing the error, the panic value and call stack
+		// (with the source code around each frame) if the error was caused by a panic, the request headers,
+		// the route parameters, the data stored in the context via routing.Context.Set() and the matched route.
+		// Clients that prefer JSON to HTML according to the "Accept" header receive the same information as a JSON object.
+		//
+		// Debug should never be enabled in production, as the pages disclose source code and request data.
+		Debug bool
+	}
 )
 
 // Recovery returns a handler that handles both panics and errors occurred while servicing an HTTP request.
 // Recovery can be considered as a combination of ErrorHandler and PanicHandler.
 //
 // The handler will recover from panics and render the recovered error or the error returned by a handler.
-// If the error implements routing.HTTPError, the handler will set the HTTP status code accordingly.
+// If the error can be converted by routing.AsHTTPError(), the handler will set the HTTP status code accordingly.
 // Otherwise the HTTP status is set as fasthttp.StatusInternalServerError. The handler will also write the error
-// as the response body.
+// as the response body using the data writer negotiated for the request (see routing.Context.WriteError()).
 //
 // A log function can be provided to log a message whenever an error is handled. If nil, no message will be logged.
 //
 // An optional error conversion function can also be provided to convert an error into a normalized one
 // before sending it to the response.
 //
+// Use RecoveryWith to enable the development-mode error pages.
+//
 //     import (
 //         "log"
 //         "github.com/jackwhelpton/fasthttp-routing/v2"
@@ -39,18 +56,44 @@ type (
 //     r := routing.New()
 //     r.Use(fault.Recovery(log.Printf))
 func Recovery(logf LogFunc, errorf ...ConvertErrorFunc) routing.Handler {
+	return RecoveryWith(logf, newOptions(errorf))
+}
+
+// RecoveryWith returns a handler like Recovery, configured with the given options.
+//
+//     r.Use(fault.RecoveryWith(log.Printf, fault.Options{Debug: true}))
+func RecoveryWith(logf LogFunc, opts Options) routing.Handler {
 	handlePanic := PanicHandler(logf)
 	return func(c *routing.Context) error {
 		if err := handlePanic(c); err != nil {
 			if logf != nil {
-				logf("%v", err)
-			}
-			if len(errorf) > 0 {
-				err = errorf[0](c, err)
+				logf("%v", errorChain(err))
 			}
-			writeError(c, err)
+			opts.write(c, err)
 			c.Abort()
 		}
 		return nil
 	}
 }
+
+// newOptions returns the options of the handlers created with an optional error conversion function.
+func newOptions(errorf []ConvertErrorFunc) Options {
+	var opts Options
+	if len(errorf) > 0 {
+		opts.ConvertError = errorf[0]
+	}
+	return opts
+}
+
+// write converts the error and writes it to the response, rendering a debug page if Debug is true.
+func (opts Options) write(c *routing.Context, err error) {
+	converted := err
+	if opts.ConvertError != nil {
+		converted = opts.ConvertError(c, err)
+	}
+	if opts.Debug {
+		writeDebugError(c, err, converted)
+	} else {
+		writeError(c, converted)
+	}
+}
@@ -25,7 +25,7 @@ func TestRecovery(t *testing.T) {
 	c := routing.NewContext(&ctx, h, handler1, handler2)
 	assert.Nil(t, c.Next())
 	assert.Equal(t, fasthttp.StatusInternalServerError, ctx.Response.StatusCode())
-	assert.Equal(t, "abc", string(ctx.Response.Body()))
+	assert.Equal(t, "Internal Server Error", string(ctx.Response.Body()))
 	assert.Equal(t, "abc", buf.String())
 
 	buf.Reset()
@@ -41,7 +41,7 @@ func TestRecovery(t *testing.T) {
 	c = routing.NewContext(&ctx, h, handler3, handler2)
 	assert.Nil(t, c.Next())
 	assert.Equal(t, fasthttp.StatusInternalServerError, ctx.Response.StatusCode())
-	assert.Equal(t, "xyz", string(ctx.Response.Body()))
+	assert.Equal(t, "Internal Server Error", string(ctx.Response.Body()))
 	assert.Contains(t, buf.String(), "recovery_test.go")
 	assert.Contains(t, buf.String(), "xyz")
 
@@ -60,7 +60,7 @@ func TestRecovery(t *testing.T) {
 	c = routing.NewContext(&ctx, h, handler3, handler2)
 	assert.Nil(t, c.Next())
 	assert.Equal(t, fasthttp.StatusInternalServerError, ctx.Response.StatusCode())
-	assert.Equal(t, "123", string(ctx.Response.Body()))
+	assert.Equal(t, "Internal Server Error", string(ctx.Response.Body()))
 	assert.Contains(t, buf.String(), "recovery_test.go")
 	assert.Contains(t, buf.String(), "xyz")
 
@@ -70,7 +70,7 @@ func TestRecovery(t *testing.T) {
 	c = routing.NewContext(&ctx, h, handler1, handler2)
 	assert.Nil(t, c.Next())
 	assert.Equal(t, fasthttp.StatusInternalServerError, ctx.Response.StatusCode())
-	assert.Equal(t, "123", string(ctx.Response.Body()))
+	assert.Equal(t, "Internal Server Error", string(ctx.Response.Body()))
 	assert.Equal(t, "abc", buf.String())
 }
 
@@ -0,0 +1,233 @@
+package fault
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/jackwhelpton/fasthttp-routing/v2"
+)
+
+// PanicReport describes a panic recovered by PanicHandler or Recovery.
+type PanicReport struct {
+	// Time is the time when the panic was recovered.
+	Time time.Time `json:"time"`
+	// Value is the value passed to panic().
+	Value interface{} `json:"-"`
+	// Type is the type of the panic value, such as "string" or "*errors.errorString".
+	Type string `json:"type"`
+	// Message is the panic value formatted as a string.
+	Message string `json:"message"`
+	// Stack is the stack trace of the goroutine that panicked, as returned by runtime/debug.Stack().
+	Stack string `json:"stack"`
+	// Method is the HTTP method of the request.
+	Method string `json:"method"`
+	// URL is the URL of the request.
+	URL string `json:"url"`
+	// Route is the route matching the request, such as "GET /users/<id>". It is empty if no route matches the request.
+	Route string `json:"route,omitempty"`
+	// ClientIP is the IP address of the client.
+	ClientIP string `json:"client_ip"`
+	// RequestID is the value of the "X-Request-ID" header of the request or, if missing, of the response.
+	RequestID string `json:"request_id,omitempty"`
+	// Count is the number of occurrences of the panic represented by this report. It is greater than 1 if
+	// previous occurrences were suppressed by deduplication (see ReportOptions).
+	Count int `json:"count"`
+}
+
+// PanicReporter receives reports of the panics recovered by PanicHandler and Recovery.
+// Reporters are registered via RegisterPanicReporter(). ReportPanic should be thread safe.
+type PanicReporter interface {
+	// ReportPanic reports a panic.
+	ReportPanic(report *PanicReport)
+}
+
+// PanicReporterFunc is an adapter allowing an ordinary function to be used as a PanicReporter.
+type PanicReporterFunc func(report *PanicReport)
+
+// ReportPanic calls f(report).
+func (f PanicReporterFunc) ReportPanic(report *PanicReport) {
+	f(report)
+}
+
+// ReportOptions controls which panics are sent to a PanicReporter.
+type ReportOptions struct {
+	// Dedup is the period during which repeated panics are not reported again once a panic has been reported.
+	// Panics are considered repeated if their values have the same type and they are raised at the same location.
+	// The number of suppressed occurrences is included in the next report of the panic, unless the panic does not
+	// occur again within a period of its last occurrence, in which case it is forgotten. Zero disables deduplication.
+	Dedup time.Duration
+	// SampleRate is the fraction of panics that are reported, between 0 and 1. Zero means all panics are reported.
+	SampleRate float64
+}
+
+type panicReporter struct {
+	reporter PanicReporter
+	options  ReportOptions
+
+	mu     sync.Mutex
+	seen   map[string]*panicOccurrence
+	pruned time.Time // when the expired occurrences were last removed from seen
+}
+
+type panicOccurrence struct {
+	reported   time.Time // when the panic was last reported
+	last       time.Time // when the panic last occurred
+	suppressed int
+}
+
+var (
+	panicReportersMu sync.RWMutex
+	panicReporters   []*panicReporter
+
+	// sample returns a pseudo-random number in [0.0,1.0) to decide whether a panic is sampled.
+	sample = rand.Float64
+)
+
+// RegisterPanicReporter registers a reporter that receives a report for every panic recovered by
+// PanicHandler and Recovery. Several reporters can be registered, each with its own options.
+//
+//     reporter, _ := fault.OpenFileReporter("/var/log/app/panics.jsonl")
+//     fault.RegisterPanicReporter(reporter, fault.ReportOptions{Dedup: time.Minute})
+func RegisterPanicReporter(reporter PanicReporter, opts ...ReportOptions) {
+	r := &panicReporter{reporter: reporter, seen: make(map[string]*panicOccurrence)}
+	if len(opts) > 0 {
+		r.options = opts[0]
+	}
+	panicReportersMu.Lock()
+	panicReporters = append(panicReporters, r)
+	panicReportersMu.Unlock()
+}
+
+// reportPanic sends a report of the given panic to the registered reporters.
+// The reporters are called without holding the lock, so that they may register other reporters.
+func reportPanic(c *routing.Context, e interface{}, stack []StackFrame) {
+	panicReportersMu.RLock()
+	reporters := make([]*panicReporter, len(panicReporters))
+	copy(reporters, panicReporters)
+	panicReportersMu.RUnlock()
+	if len(reporters) == 0 {
+		return
+	}
+
+	report := newPanicReport(c, e)
+	key := report.Type
+	if len(stack) > 0 {
+		key = fmt.Sprintf("%s@%s:%d", key, stack[0].File, stack[0].Line)
+	}
+	for _, r := range reporters {
+		if count, ok := r.accept(key, report.Time); ok {
+			rep := *report
+			rep.Count = count
+			r.reporter.ReportPanic(&rep)
+		}
+	}
+}
+
+func newPanicReport(c *routing.Context, e interface{}) *PanicReport {
+	report := &PanicReport{
+		Time:      time.Now(),
+		Value:     e,
+		Type:      fmt.Sprintf("%T", e),
+		Message:   fmt.Sprintf("%v", e),
+		Stack:     string(debug.Stack()),
+		Method:    string(c.Method()),
+		URL:       c.URI().String(),
+		ClientIP:  c.RemoteIP().String(),
+		RequestID: string(c.Request.Header.Peek("X-Request-ID")),
+	}
+	if report.RequestID == "" {
+		report.RequestID = string(c.Response.Header.Peek("X-Request-ID"))
+	}
+	if route := c.Route(); route != nil {
+		report.Route = route.String()
+	}
+	return report
+}
+
+// accept determines whether a panic with the given deduplication key should be reported.
+// If so, it returns the number of occurrences represented by the report.
+func (r *panicReporter) accept(key string, now time.Time) (int, bool) {
+	if r.options.SampleRate > 0 && sample() >= r.options.SampleRate {
+		return 0, false
+	}
+	if r.options.Dedup <= 0 {
+		return 1, true
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.prune(now)
+	o := r.seen[key]
+	if o == nil {
+		o = &panicOccurrence{}
+		r.seen[key] = o
+	}
+	o.last = now
+	if !o.reported.IsZero() && now.Sub(o.reported) < r.options.Dedup {
+		o.suppressed++
+		return 0, false
+	}
+	count := o.suppressed + 1
+	o.reported, o.suppressed = now, 0
+	return count, true
+}
+
+// prune removes the panics that have not occurred for a deduplication period, so that seen does not grow
+// with every distinct panic. To keep the cost of accept() constant on average, it runs at most once per period.
+func (r *panicReporter) prune(now time.Time) {
+	if now.Sub(r.pruned) < r.options.Dedup {
+		return
+	}
+	for key, o := range r.seen {
+		if now.Sub(o.last) >= r.options.Dedup {
+			delete(r.seen, key)
+		}
+	}
+	r.pruned = now
+}
+
+// JSONReporter is a PanicReporter that writes each report as a line of JSON (the JSON Lines format),
+// so that the reports can be collected and aggregated by log processing tools.
+type JSONReporter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewJSONReporter creates a JSONReporter writing to the given writer.
+func NewJSONReporter(w io.Writer) *JSONReporter {
+	return &JSONReporter{w: w}
+}
+
+// OpenFileReporter opens the named file for appending, creating it if necessary, and returns a JSONReporter
+// writing to the file. Call Close() to close the file.
+func OpenFileReporter(name string) (*JSONReporter, error) {
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return NewJSONReporter(f), nil
+}
+
+// Close closes the underlying writer if it implements io.Closer.
+func (r *JSONReporter) Close() error {
+	if closer, ok := r.w.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// ReportPanic writes the report as a line of JSON.
+func (r *JSONReporter) ReportPanic(report *PanicReport) {
+	data, err := json.Marshal(report)
+	if err != nil {
+		return
+	}
+	r.mu.Lock()
+	r.w.Write(append(data, '\n'))
+	r.mu.Unlock()
+}
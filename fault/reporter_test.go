@@ -0,0 +1,136 @@
+package fault
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackwhelpton/fasthttp-routing/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func TestRegisterPanicReporter(t *testing.T) {
+	defer func(r []*panicReporter) { panicReporters = r }(panicReporters)
+	panicReporters = nil
+
+	var all, deduped []*PanicReport
+	RegisterPanicReporter(PanicReporterFunc(func(r *PanicReport) { all = append(all, r) }))
+	RegisterPanicReporter(PanicReporterFunc(func(r *PanicReport) { deduped = append(deduped, r) }), ReportOptions{Dedup: time.Hour})
+
+	router := routing.New()
+	router.Use(Recovery(nil))
+	router.Get("/users/<id>", handler3)
+
+	for i := 0; i < 3; i++ {
+		var ctx fasthttp.RequestCtx
+		ctx.Request.Header.SetMethod("GET")
+		ctx.Request.SetRequestURI("/users/1?x=y")
+		ctx.Request.Header.Set("X-Request-ID", "req-1")
+		router.HandleRequest(&ctx)
+		assert.Equal(t, fasthttp.StatusInternalServerError, ctx.Response.StatusCode())
+	}
+
+	assert.Len(t, all, 3)
+	if assert.Len(t, deduped, 1) {
+		r := deduped[0]
+		assert.Equal(t, "xyz", r.Value)
+		assert.Equal(t, "string", r.Type)
+		assert.Equal(t, "xyz", r.Message)
+		assert.Equal(t, "GET", r.Method)
+		assert.Equal(t, "http:///users/1?x=y", r.URL)
+		assert.Equal(t, "GET /users/<id>", r.Route)
+		assert.Equal(t, "0.0.0.0", r.ClientIP)
+		assert.Equal(t, "req-1", r.RequestID)
+		assert.Equal(t, 1, r.Count)
+		assert.Contains(t, r.Stack, "recovery_test.go")
+	}
+}
+
+func Test_panicReporterAccept(t *testing.T) {
+	defer func(f func() float64) { sample = f }(sample)
+
+	r := &panicReporter{options: ReportOptions{Dedup: time.Minute}, seen: make(map[string]*panicOccurrence)}
+	now := time.Now()
+	count, ok := r.accept("a", now)
+	assert.True(t, ok)
+	assert.Equal(t, 1, count)
+	_, ok = r.accept("a", now.Add(time.Second))
+	assert.False(t, ok)
+	_, ok = r.accept("a", now.Add(2*time.Second))
+	assert.False(t, ok)
+	count, ok = r.accept("b", now.Add(2*time.Second))
+	assert.True(t, ok)
+	assert.Equal(t, 1, count)
+	count, ok = r.accept("a", now.Add(time.Minute))
+	assert.True(t, ok)
+	assert.Equal(t, 3, count)
+
+	// the panics that have not occurred for a period are forgotten
+	count, ok = r.accept("c", now.Add(3*time.Minute))
+	assert.True(t, ok)
+	assert.Equal(t, 1, count)
+	assert.Len(t, r.seen, 1)
+
+	r = &panicReporter{options: ReportOptions{SampleRate: 0.5}}
+	sample = func() float64 { return 0.7 }
+	_, ok = r.accept("a", now)
+	assert.False(t, ok)
+	sample = func() float64 { return 0.2 }
+	_, ok = r.accept("a", now)
+	assert.True(t, ok)
+}
+
+func TestRegisterPanicReporterReentrant(t *testing.T) {
+	defer func(r []*panicReporter) { panicReporters = r }(panicReporters)
+	panicReporters = nil
+
+	var reports int
+	RegisterPanicReporter(PanicReporterFunc(func(r *PanicReport) {
+		// registering a reporter while reporting must not deadlock
+		RegisterPanicReporter(PanicReporterFunc(func(r *PanicReport) { reports++ }))
+	}))
+
+	router := routing.New()
+	router.Use(Recovery(nil))
+	router.Get("/users/<id>", handler3)
+	for i := 0; i < 2; i++ {
+		var ctx fasthttp.RequestCtx
+		ctx.Request.Header.SetMethod("GET")
+		ctx.Request.SetRequestURI("/users/1")
+		router.HandleRequest(&ctx)
+	}
+	assert.Equal(t, 1, reports)
+	assert.Len(t, panicReporters, 3)
+}
+
+func TestJSONReporter(t *testing.T) {
+	var buf bytes.Buffer
+	r := NewJSONReporter(&buf)
+	r.ReportPanic(&PanicReport{Time: time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC), Value: 1, Type: "int", Message: "1", Method: "GET", URL: "http://localhost/", ClientIP: "127.0.0.1", Count: 1})
+	r.ReportPanic(&PanicReport{Type: "string", Count: 2})
+	lines := strings.Split(buf.String(), "\n")
+	if assert.Len(t, lines, 3) {
+		assert.Equal(t, `{"time":"2020-01-02T03:04:05Z","type":"int","message":"1","stack":"","method":"GET","url":"http://localhost/","client_ip":"127.0.0.1","count":1}`, lines[0])
+		var report PanicReport
+		assert.Nil(t, json.Unmarshal([]byte(lines[1]), &report))
+		assert.Equal(t, 2, report.Count)
+		assert.Equal(t, "", lines[2])
+	}
+	assert.Nil(t, r.Close())
+
+	name := filepath.Join(t.TempDir(), "panics.jsonl")
+	r, err := OpenFileReporter(name)
+	if assert.Nil(t, err) {
+		r.ReportPanic(&PanicReport{Type: "string"})
+		assert.Nil(t, r.Close())
+		data, _ := os.ReadFile(name)
+		assert.Equal(t, `{"time":"0001-01-01T00:00:00Z","type":"string","message":"","stack":"","method":"","url":"","client_ip":"","count":0}`+"\n", string(data))
+	}
+	_, err = OpenFileReporter(filepath.Join(name, "x"))
+	assert.NotNil(t, err)
+}
@@ -11,6 +11,8 @@ type RouteGroup struct {
 	prefix   string
 	router   *Router
 	handlers []Handler
+	codecs   *Codecs
+	parent   *RouteGroup
 }
 
 // newRouteGroup creates a new RouteGroup with the given path prefix, router, and handlers.
@@ -96,7 +98,29 @@ func (rg *RouteGroup) Group(prefix string, handlers ...Handler) *RouteGroup {
 		handlers = make([]Handler, len(rg.handlers))
 		copy(handlers, rg.handlers)
 	}
-	return newRouteGroup(rg.prefix+prefix, rg.router, handlers)
+	group := newRouteGroup(rg.prefix+prefix, rg.router, handlers)
+	group.parent = rg
+	return group
+}
+
+// SetCodecs attaches a registry of data readers and writers to the route group.
+// The registry will be used by all routes in this group, as well as by its subgroups that have no registry of their own.
+// If the registry is nil, the registry of the parent group (or, ultimately, of the router) will be used,
+// falling back to the package-level defaults (such as DataReaders) if there is none.
+func (rg *RouteGroup) SetCodecs(codecs *Codecs) {
+	rg.codecs = codecs
+}
+
+// Codecs returns the registry of data readers and writers in effect for the route group.
+// This is the registry attached to the group itself or else to its closest ancestor, or nil if there is none.
+// The registry is resolved when called, so it reflects any registry attached to an ancestor afterwards.
+func (rg *RouteGroup) Codecs() *Codecs {
+	for g := rg; g != nil; g = g.parent {
+		if g.codecs != nil {
+			return g.codecs
+		}
+	}
+	return nil
 }
 
 // Use registers one or multiple handlers to the current route group.
@@ -0,0 +1,193 @@
+// Package accept implements the parsing and matching of the media ranges of Accept headers (RFC 7231, Section 5.3.2)
+// shared by the ozzo routing package and its content package.
+package accept
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Range represents a media-range contained within an Accept header.
+type Range struct {
+	// Type represents the media type.
+	Type string
+	// Subtype represents the media subtype.
+	Subtype string
+	// Weight represents the weight (quality factor) of this range.
+	Weight float64
+	// Parameters represents the parameters that are applicable to this range.
+	Parameters map[string]string
+	raw        string
+}
+
+// RawString returns the raw string for this accept range.
+func (a Range) RawString() string {
+	return a.raw
+}
+
+// https://tools.ietf.org/html/rfc7231#section-5.3.2
+// Accept = #( media-range [ accept-params ] )
+//  media-range    = ( "*/*"
+//                   / ( type "/" "*" )
+//                   / ( type "/" subtype )
+//                   ) *( OWS ";" OWS parameter )
+//  accept-params  = weight *( accept-ext )
+//  accept-ext = OWS ";" OWS token [ "=" ( token / quoted-string ) ]
+
+// ParseRanges returns the set of accepted media ranges from an Accept header.
+func ParseRanges(accepts string) []Range {
+	result := []Range{}
+	remaining := accepts
+	for {
+		var accept string
+		accept, remaining = extractFieldAndSkipToken(remaining, ',')
+		result = append(result, ParseRange(accept))
+		if len(remaining) == 0 {
+			break
+		}
+	}
+	return result
+}
+
+// ParseRange returns the media range, params and quality factor (weight) from an Accept range.
+func ParseRange(accept string) Range {
+	typeAndSub, rawparams := extractFieldAndSkipToken(accept, ';')
+
+	tp, subtp := extractFieldAndSkipToken(typeAndSub, '/')
+	params := extractParams(rawparams)
+
+	w := extractWeight(params)
+	return Range{Type: tp, Subtype: subtp, Parameters: params, Weight: w, raw: accept}
+}
+
+func extractWeight(params map[string]string) float64 {
+	if w, ok := params["q"]; ok {
+		res, err := strconv.ParseFloat(w, 64)
+		if err == nil {
+			return res
+		}
+	}
+	return 1 // default is 1
+}
+
+func extractParams(raw string) map[string]string {
+	params := map[string]string{}
+	rest := raw
+	for {
+		var p string
+		p, rest = extractFieldAndSkipToken(rest, ';')
+		if len(p) > 0 {
+			k, v := extractFieldAndSkipToken(p, '=')
+			params[k] = v
+		}
+		if len(rest) == 0 {
+			break
+		}
+	}
+
+	return params
+}
+
+func extractFieldAndSkipToken(s string, sep rune) (string, string) {
+	f, r := extractField(s, sep)
+	if len(r) > 0 {
+		r = r[1:]
+	}
+	return f, r
+}
+
+func extractField(s string, sep rune) (field, rest string) {
+	field = strings.TrimSpace(s)
+	for i, v := range s {
+		if v == sep {
+			field = strings.TrimSpace(s[:i])
+			rest = strings.TrimSpace(s[i:])
+			break
+		}
+	}
+	return
+}
+
+// Match reports whether the media range matches the given media type. The range may use wildcards, as in "*/*" and
+// "text/*", or a structured syntax suffix (RFC 6839), as in "application/*+json", which matches "application/vnd.api+json".
+// An unversioned vendor media type, such as "application/vnd.acme+json", matches every version of it,
+// such as "application/vnd.acme.v2+json" (see ParseVendorType).
+// The parameters of the range, except for the weight, must have the same values in the media type,
+// unless the media type does not define them.
+func (a Range) Match(mediaType Range) bool {
+	return a.specificity(mediaType) >= 0
+}
+
+// specificity returns how specifically the range matches the given media type, or -1 if it does not match.
+// Wildcards are less specific than suffixes and unversioned vendor media types, which are less specific than subtypes. Among ranges matching the same
+// subtype, those with more parameters in common with the media type are more specific.
+func (a Range) specificity(mediaType Range) int {
+	var level int
+	switch {
+	case a.Type == "*" && a.Subtype == "*":
+		level = 0
+	case !strings.EqualFold(a.Type, mediaType.Type):
+		return -1
+	case a.Subtype == "*":
+		level = 1
+	case strings.HasPrefix(a.Subtype, "*+"):
+		if !hasSuffix(mediaType.Subtype, a.Subtype[1:]) {
+			return -1
+		}
+		level = 2
+	case matchVersion(a.Subtype, mediaType.Subtype):
+		level = 3
+	case strings.EqualFold(a.Subtype, mediaType.Subtype):
+		level = 4
+	default:
+		return -1
+	}
+
+	params := 0
+	for k, v := range a.Parameters {
+		if k == "q" {
+			continue
+		}
+		if mv, ok := mediaType.Parameters[k]; ok {
+			if mv != v && (k != "charset" || !strings.EqualFold(mv, v)) {
+				return -1
+			}
+			params++
+		}
+	}
+	return level<<16 | params
+}
+
+// hasSuffix checks if the subtype ends with the given structured syntax suffix, such as "+json", ignoring case.
+func hasSuffix(subtype, suffix string) bool {
+	return len(subtype) > len(suffix) && strings.EqualFold(subtype[len(subtype)-len(suffix):], suffix)
+}
+
+// Negotiate returns the index of the best offer acceptable according to the given Accept header,
+// or -1 if none of them is acceptable.
+//
+// The weight of an offer is given by the most specific media range matching it (RFC 7231, Section 5.3.2),
+// and an offer of zero weight is not acceptable. Among offers of the same weight, the one matched by the most
+// specific media range is preferred, followed by the first one. An empty Accept header accepts any offer.
+func Negotiate(header string, offers []string) int {
+	accepts := []Range{{Type: "*", Subtype: "*", Weight: 1}}
+	if len(header) > 0 {
+		accepts = ParseRanges(header)
+	}
+
+	best, bestWeight, bestSpecificity := -1, 0.0, -1
+	for i, offer := range offers {
+		mediaType := ParseRange(offer)
+		weight, specificity := 0.0, -1
+		for _, accept := range accepts {
+			s := accept.specificity(mediaType)
+			if s > specificity || s >= 0 && s == specificity && accept.Weight > weight {
+				weight, specificity = accept.Weight, s
+			}
+		}
+		if weight > bestWeight || weight > 0 && weight == bestWeight && specificity > bestSpecificity {
+			best, bestWeight, bestSpecificity = i, weight, specificity
+		}
+	}
+	return best
+}
@@ -0,0 +1,37 @@
+package accept
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNegotiate(t *testing.T) {
+	offers := []string{"application/json", "application/vnd.acme.v2+json", "text/html"}
+	tests := []struct {
+		tag    string
+		header string
+		result int
+	}{
+		{"t1", "", 0},
+		{"t2", "text/*", 2},
+		{"t3", "application/vnd.acme+json", 1},
+		{"t4", "application/*+json, application/json;q=0.5", 1},
+		{"t5", "*/*;q=0.5, text/html;q=0.6, application/*;q=0", 2},
+		{"t6", "image/png", -1},
+		{"t7", "application/json;q=0, */*;q=0", -1},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.result, Negotiate(test.header, offers), test.tag)
+	}
+}
+
+func TestParseRanges(t *testing.T) {
+	ranges := ParseRanges("text/html;level=1;q=0.5, utf-8")
+	if assert.Len(t, ranges, 2) {
+		assert.Equal(t, Range{Type: "text", Subtype: "html", Weight: 0.5,
+			Parameters: map[string]string{"level": "1", "q": "0.5"}, raw: "text/html;level=1;q=0.5"}, ranges[0])
+		assert.Equal(t, "utf-8", ranges[1].Type)
+		assert.Equal(t, 1.0, ranges[1].Weight)
+	}
+}
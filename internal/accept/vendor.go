@@ -0,0 +1,56 @@
+package accept
+
+import "strings"
+
+// VendorType is a media type of the vendor tree (RFC 6838, Section 3.2), such as "application/vnd.acme.v2+json".
+type VendorType struct {
+	// Name is the name of the media type in the vendor tree, such as "acme".
+	Name string
+	// Version is the version of the media type, such as "v2", or empty if the media type is not versioned.
+	Version string
+	// Suffix is the structured syntax suffix of the media type (RFC 6839), such as "json", or empty if there is none.
+	Suffix string
+}
+
+// ParseVendorType parses a media type of the vendor tree. The version is the last dot-separated part of the name
+// if it is made of a "v" followed by digits. False is returned if the media type is not in the vendor tree.
+func ParseVendorType(mediaType string) (VendorType, bool) {
+	subtype := strings.ToLower(ParseRange(mediaType).Subtype)
+	if !strings.HasPrefix(subtype, "vnd.") {
+		return VendorType{}, false
+	}
+	var vt VendorType
+	name := subtype[len("vnd."):]
+	if i := strings.LastIndexByte(name, '+'); i >= 0 {
+		name, vt.Suffix = name[:i], name[i+1:]
+	}
+	if i := strings.LastIndexByte(name, '.'); i >= 0 && isVersion(name[i+1:]) {
+		name, vt.Version = name[:i], name[i+1:]
+	}
+	vt.Name = name
+	return vt, name != ""
+}
+
+// isVersion checks if the given part of a vendor media type name is a version, such as "v2".
+func isVersion(s string) bool {
+	if len(s) < 2 || s[0] != 'v' {
+		return false
+	}
+	for i := 1; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// matchVersion checks if the unversioned vendor subtype of a media range, such as "vnd.acme+json",
+// matches a version of the same media type, such as "vnd.acme.v2+json".
+func matchVersion(rangeSubtype, subtype string) bool {
+	r, ok := ParseVendorType("/" + rangeSubtype)
+	if !ok || r.Version != "" {
+		return false
+	}
+	t, ok := ParseVendorType("/" + subtype)
+	return ok && t.Version != "" && t.Name == r.Name && t.Suffix == r.Suffix
+}
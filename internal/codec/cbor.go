@@ -0,0 +1,320 @@
+package codec
+
+import (
+	"encoding/binary"
+	"math"
+	"strconv"
+	"time"
+)
+
+// MarshalCBOR returns the CBOR encoding of v. Arrays, maps and strings are encoded with definite lengths.
+func MarshalCBOR(v interface{}) ([]byte, error) {
+	return marshal(cborAppender{}, v)
+}
+
+// UnmarshalCBOR decodes the CBOR-encoded data into the value pointed to by v.
+// Indefinite-length items are supported, as well as the date/time tags 0 and 1. Other tags are ignored.
+func UnmarshalCBOR(data []byte, v interface{}, opts DecodeOptions) error {
+	return unmarshal(&cborScanner{data: data}, len(data), v, opts)
+}
+
+// CBOR major types
+const (
+	cborUint byte = iota << 5
+	cborNegInt
+	cborBytes
+	cborString
+	cborArray
+	cborMap
+	cborTag
+	cborSimple
+)
+
+// cborBreak is the stop code ending indefinite-length items.
+const cborBreak = 0xff
+
+type cborAppender struct{}
+
+func (cborAppender) name() string { return "cbor" }
+
+// appendHead appends the initial byte of an item of the given major type followed by its argument.
+func (cborAppender) appendHead(b []byte, major byte, arg uint64) []byte {
+	switch {
+	case arg < 24:
+		return append(b, major|byte(arg))
+	case arg <= math.MaxUint8:
+		return append(b, major|24, byte(arg))
+	case arg <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, major|25), uint16(arg))
+	case arg <= math.MaxUint32:
+		return binary.BigEndian.AppendUint32(append(b, major|26), uint32(arg))
+	}
+	return binary.BigEndian.AppendUint64(append(b, major|27), arg)
+}
+
+func (cborAppender) appendNil(b []byte) []byte {
+	return append(b, 0xf6)
+}
+
+func (cborAppender) appendBool(b []byte, v bool) []byte {
+	if v {
+		return append(b, 0xf5)
+	}
+	return append(b, 0xf4)
+}
+
+func (a cborAppender) appendInt(b []byte, v int64) []byte {
+	if v >= 0 {
+		return a.appendHead(b, cborUint, uint64(v))
+	}
+	return a.appendHead(b, cborNegInt, uint64(-1-v))
+}
+
+func (a cborAppender) appendUint(b []byte, v uint64) []byte {
+	return a.appendHead(b, cborUint, v)
+}
+
+func (cborAppender) appendFloat32(b []byte, v float32) []byte {
+	return binary.BigEndian.AppendUint32(append(b, 0xfa), math.Float32bits(v))
+}
+
+func (cborAppender) appendFloat64(b []byte, v float64) []byte {
+	return binary.BigEndian.AppendUint64(append(b, 0xfb), math.Float64bits(v))
+}
+
+func (a cborAppender) appendString(b []byte, v string) []byte {
+	return append(a.appendHead(b, cborString, uint64(len(v))), v...)
+}
+
+func (a cborAppender) appendBytes(b []byte, v []byte) []byte {
+	return append(a.appendHead(b, cborBytes, uint64(len(v))), v...)
+}
+
+func (a cborAppender) appendArrayHeader(b []byte, n int) []byte {
+	return a.appendHead(b, cborArray, uint64(n))
+}
+
+func (a cborAppender) appendMapHeader(b []byte, n int) []byte {
+	return a.appendHead(b, cborMap, uint64(n))
+}
+
+type cborScanner struct {
+	data []byte
+	pos  int
+}
+
+func (s *cborScanner) name() string { return "cbor" }
+
+func (s *cborScanner) offset() int { return s.pos }
+
+func (s *cborScanner) error(msg string) error {
+	return &DecodeError{Offset: s.pos, msg: "cbor: " + msg}
+}
+
+// read consumes the next n bytes of the input.
+func (s *cborScanner) read(n uint64) ([]byte, error) {
+	if n > uint64(len(s.data)-s.pos) {
+		return nil, s.error("unexpected end of data")
+	}
+	b := s.data[s.pos : s.pos+int(n)]
+	s.pos += int(n)
+	return b, nil
+}
+
+func (s *cborScanner) end() (bool, error) {
+	if s.pos >= len(s.data) {
+		return false, s.error("unexpected end of data")
+	}
+	if s.data[s.pos] == cborBreak {
+		s.pos++
+		return true, nil
+	}
+	return false, nil
+}
+
+// readHead reads the initial byte of an item and its argument. The indefinite flag is set
+// if the additional information indicates an indefinite length (or, for major type 7, the break code).
+func (s *cborScanner) readHead() (major byte, info byte, arg uint64, indefinite bool, err error) {
+	b, err := s.read(1)
+	if err != nil {
+		return
+	}
+	major, info = b[0]&0xe0, b[0]&0x1f
+	switch {
+	case info < 24:
+		arg = uint64(info)
+	case info <= 27:
+		if b, err = s.read(1 << (info - 24)); err != nil {
+			return
+		}
+		for _, c := range b {
+			arg = arg<<8 | uint64(c)
+		}
+	case info == 31:
+		indefinite = true
+	default:
+		s.pos--
+		err = s.error("invalid additional information " + strconv.Itoa(int(info)))
+	}
+	return
+}
+
+func (s *cborScanner) next() (item, error) {
+	start := s.pos
+	major, info, arg, indefinite, err := s.readHead()
+	if err != nil {
+		return item{}, err
+	}
+	// tags are skipped, except for the date/time tags which apply to the tagged item
+	tag := int64(-1)
+	for major == cborTag {
+		if indefinite {
+			s.pos = start
+			return item{}, s.error("invalid indefinite-length tag")
+		}
+		tag, start = int64(arg), s.pos
+		if arg > math.MaxInt64 {
+			tag = -1
+		}
+		if major, info, arg, indefinite, err = s.readHead(); err != nil {
+			return item{}, err
+		}
+	}
+
+	var it item
+	switch major {
+	case cborUint:
+		if indefinite {
+			s.pos = start
+			return item{}, s.error("invalid indefinite-length integer")
+		}
+		it = item{kind: kindUint, u: arg}
+	case cborNegInt:
+		if indefinite {
+			s.pos = start
+			return item{}, s.error("invalid indefinite-length integer")
+		}
+		if arg > math.MaxInt64 {
+			s.pos = start
+			return item{}, s.error("integer overflows int64")
+		}
+		it = item{kind: kindInt, i: -1 - int64(arg)}
+	case cborBytes, cborString:
+		kind := kindBytes
+		if major == cborString {
+			kind = kindString
+		}
+		it = item{kind: kind}
+		if !indefinite {
+			if it.s, err = s.read(arg); err != nil {
+				return item{}, err
+			}
+			break
+		}
+		// an indefinite-length string is made of definite-length chunks of the same type
+		it.s = []byte{}
+		for {
+			if end, err := s.end(); err != nil {
+				return item{}, err
+			} else if end {
+				break
+			}
+			chunkStart := s.pos
+			m, _, n, ind, err := s.readHead()
+			if err != nil {
+				return item{}, err
+			}
+			if m != major || ind {
+				s.pos = chunkStart
+				return item{}, s.error("invalid chunk in indefinite-length string")
+			}
+			chunk, err := s.read(n)
+			if err != nil {
+				return item{}, err
+			}
+			it.s = append(it.s, chunk...)
+		}
+	case cborArray, cborMap:
+		kind := kindArray
+		if major == cborMap {
+			kind = kindMap
+		}
+		it = item{kind: kind, n: -1}
+		if !indefinite {
+			if arg > math.MaxInt32 {
+				s.pos = start
+				return item{}, s.error("length " + strconv.FormatUint(arg, 10) + " is too large")
+			}
+			it.n = int(arg)
+		}
+	case cborSimple:
+		switch info {
+		case 20, 21:
+			it = item{kind: kindBool, b: info == 21}
+		case 22, 23:
+			it = item{kind: kindNil}
+		case 25:
+			it = item{kind: kindFloat, f: halfToFloat64(uint16(arg))}
+		case 26:
+			it = item{kind: kindFloat, f: float64(math.Float32frombits(uint32(arg)))}
+		case 27:
+			it = item{kind: kindFloat, f: math.Float64frombits(arg)}
+		case 31:
+			s.pos = start
+			return item{}, s.error("unexpected break code")
+		default:
+			s.pos = start
+			return item{}, s.error("unsupported simple value " + strconv.FormatUint(arg, 10))
+		}
+	}
+
+	switch tag {
+	case 0:
+		if it.kind != kindString {
+			s.pos = start
+			return item{}, s.error("invalid date/time string")
+		}
+		t, err := time.Parse(time.RFC3339Nano, string(it.s))
+		if err != nil {
+			s.pos = start
+			return item{}, s.error("invalid date/time string")
+		}
+		it = item{kind: kindTime, t: t}
+	case 1:
+		switch it.kind {
+		case kindUint:
+			it = item{kind: kindTime, t: time.Unix(int64(it.u), 0).UTC()}
+		case kindInt:
+			it = item{kind: kindTime, t: time.Unix(it.i, 0).UTC()}
+		case kindFloat:
+			sec, frac := math.Modf(it.f)
+			it = item{kind: kindTime, t: time.Unix(int64(sec), int64(frac*1e9)).UTC()}
+		default:
+			s.pos = start
+			return item{}, s.error("invalid epoch-based date/time")
+		}
+	}
+	return it, nil
+}
+
+// halfToFloat64 converts an IEEE 754 half-precision floating-point number.
+func halfToFloat64(h uint16) float64 {
+	exp, mant := int(h>>10&0x1f), float64(h&0x3ff)
+	var f float64
+	switch exp {
+	case 0:
+		f = math.Ldexp(mant, -24)
+	case 0x1f:
+		if mant == 0 {
+			f = math.Inf(1)
+		} else {
+			f = math.NaN()
+		}
+	default:
+		f = math.Ldexp(mant+1024, exp-25)
+	}
+	if h&0x8000 != 0 {
+		f = -f
+	}
+	return f
+}
@@ -0,0 +1,118 @@
+package codec
+
+import (
+	"encoding/hex"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMarshalCBOR(t *testing.T) {
+	// examples from RFC 8949, Appendix A
+	tests := []struct {
+		value interface{}
+		hex   string
+	}{
+		{0, "00"},
+		{23, "17"},
+		{24, "1818"},
+		{1000, "1903e8"},
+		{1000000, "1a000f4240"},
+		{uint64(1000000000000), "1b000000e8d4a51000"},
+		{uint64(math.MaxUint64), "1bffffffffffffffff"},
+		{-1, "20"},
+		{-1000, "3903e7"},
+		{1.1, "fb3ff199999999999a"},
+		{float32(100000), "fa47c35000"},
+		{false, "f4"},
+		{true, "f5"},
+		{nil, "f6"},
+		{[]byte{1, 2, 3, 4}, "4401020304"},
+		{"IETF", "6449455446"},
+		{"ü", "62c3bc"},
+		{[]int{}, "80"},
+		{[]interface{}{1, []int{2, 3}, []int{4, 5}}, "8301820203820405"},
+		{map[int]int{1: 2, 3: 4}, "a201020304"},
+		{map[string]interface{}{"a": 1, "b": []int{2, 3}}, "a26161016162820203"},
+	}
+	for _, test := range tests {
+		data, err := MarshalCBOR(test.value)
+		assert.Nil(t, err)
+		assert.Equal(t, test.hex, hex.EncodeToString(data), "%#v", test.value)
+	}
+}
+
+func TestUnmarshalCBOR(t *testing.T) {
+	// examples from RFC 8949, Appendix A
+	tests := []struct {
+		hex   string
+		value interface{}
+	}{
+		{"1bffffffffffffffff", uint64(math.MaxUint64)},
+		{"3bffffffffffffffff", nil},
+		{"3903e7", int64(-1000)},
+		{"f90000", 0.0},
+		{"f93c00", 1.0},
+		{"f97bff", 65504.0},
+		{"f90001", 5.960464477539063e-8},
+		{"f9c400", -4.0},
+		{"f97c00", math.Inf(1)},
+		{"fa47c35000", 100000.0},
+		{"f7", nil},
+		{"c074323031332d30332d32315432303a30343a30305a", time.Date(2013, 3, 21, 20, 4, 0, 0, time.UTC)},
+		{"c11a514b67b0", time.Date(2013, 3, 21, 20, 4, 0, 0, time.UTC)},
+		{"c1fb41d452d9ec200000", time.Date(2013, 3, 21, 20, 4, 0, 500000000, time.UTC)},
+		{"d74401020304", []byte{1, 2, 3, 4}},
+		{"5f42010243030405ff", []byte{1, 2, 3, 4, 5}},
+		{"7f657374726561646d696e67ff", "streaming"},
+		{"9fff", []interface{}{}},
+		{"9f018202039f0405ffff", []interface{}{int64(1), []interface{}{int64(2), int64(3)}, []interface{}{int64(4), int64(5)}}},
+		{"bf61610161629f0203ffff", map[string]interface{}{"a": int64(1), "b": []interface{}{int64(2), int64(3)}}},
+		{"a201020304", map[interface{}]interface{}{int64(1): int64(2), int64(3): int64(4)}},
+	}
+	for _, test := range tests {
+		data, _ := hex.DecodeString(test.hex)
+		var v interface{}
+		err := UnmarshalCBOR(data, &v, DecodeOptions{})
+		if test.value == nil && test.hex != "f7" {
+			assert.EqualError(t, err, "cbor: integer overflows int64", test.hex)
+			continue
+		}
+		if assert.Nil(t, err, test.hex) {
+			assert.Equal(t, test.value, v, test.hex)
+		}
+	}
+
+	var s struct {
+		Name string  `json:"name"`
+		Tags [2]int  `json:"tags"`
+		Rate float32 `json:"rate"`
+	}
+	data, _ := hex.DecodeString("bf646e616d65636162636474616773830102036472617465f93e00ff")
+	assert.Nil(t, UnmarshalCBOR(data, &s, DecodeOptions{}))
+	assert.Equal(t, "abc", s.Name)
+	assert.Equal(t, [2]int{1, 2}, s.Tags)
+	assert.Equal(t, float32(1.5), s.Rate)
+
+	errors := []struct {
+		hex string
+		err string
+	}{
+		{"1c", "cbor: invalid additional information 28"},
+		{"ff", "cbor: unexpected break code"},
+		{"1f", "cbor: invalid indefinite-length integer"},
+		{"f0", "cbor: unsupported simple value 16"},
+		{"5f6161ff", "cbor: invalid chunk in indefinite-length string"},
+		{"9f01", "cbor: unexpected end of data"},
+		{"c001", "cbor: invalid date/time string"},
+		{"c16161", "cbor: invalid epoch-based date/time"},
+		{"9bffffffffffffffff", "cbor: length 18446744073709551615 is too large"},
+	}
+	for _, test := range errors {
+		data, _ := hex.DecodeString(test.hex)
+		var v interface{}
+		assert.EqualError(t, UnmarshalCBOR(data, &v, DecodeOptions{}), test.err, test.hex)
+	}
+}
@@ -0,0 +1,802 @@
+// Package codec implements the MessagePack and CBOR encodings used by the data readers and writers
+// of the ozzo routing package.
+//
+// Go values are mapped to the data model shared by both encodings as encoding/json maps them to JSON:
+// struct fields are named after their `json` tags (with support for "-", "omitempty" and embedded structs),
+// values implementing encoding.TextMarshaler or encoding.TextUnmarshaler are encoded as strings,
+// and []byte values are encoded as binary strings.
+package codec
+
+import (
+	"encoding"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxDepth is the nesting depth above which values are rejected, regardless of DecodeOptions.MaxDepth.
+// It protects the encoder against cyclic data and the decoder against deeply nested documents.
+const maxDepth = 10000
+
+var (
+	textMarshalerType   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	timeType            = reflect.TypeOf(time.Time{})
+)
+
+// DecodeOptions specifies how data is decoded.
+type DecodeOptions struct {
+	// DisallowUnknownFields causes an error to be returned when a map key does not match any field
+	// of the destination struct.
+	DisallowUnknownFields bool
+	// MaxDepth is the maximum nesting depth of arrays and maps. Zero means no limit.
+	MaxDepth int
+}
+
+// DecodeError describes malformed data or data that cannot be decoded into the destination value.
+type DecodeError struct {
+	// Offset is the position in the input at which the error was detected.
+	Offset int
+	// Mismatch is true if the data is well-formed but does not fit the destination value.
+	// The message of such an error names the Go type of the destination.
+	Mismatch bool
+	msg      string
+}
+
+// Error returns the error message.
+func (e *DecodeError) Error() string {
+	return e.msg
+}
+
+// appender appends encoded values to a buffer in a specific format.
+type appender interface {
+	name() string
+	appendNil(b []byte) []byte
+	appendBool(b []byte, v bool) []byte
+	appendInt(b []byte, v int64) []byte
+	appendUint(b []byte, v uint64) []byte
+	appendFloat32(b []byte, v float32) []byte
+	appendFloat64(b []byte, v float64) []byte
+	appendString(b []byte, v string) []byte
+	appendBytes(b []byte, v []byte) []byte
+	appendArrayHeader(b []byte, n int) []byte
+	appendMapHeader(b []byte, n int) []byte
+}
+
+// marshal encodes v with the given appender.
+func marshal(a appender, v interface{}) ([]byte, error) {
+	e := encoder{appender: a}
+	return e.encode(nil, reflect.ValueOf(v))
+}
+
+type encoder struct {
+	appender
+	depth int
+}
+
+func (e *encoder) encode(b []byte, v reflect.Value) ([]byte, error) {
+	if !v.IsValid() {
+		return e.appendNil(b), nil
+	}
+	if (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) && v.IsNil() {
+		return e.appendNil(b), nil
+	}
+	if v.Type().Implements(textMarshalerType) {
+		return e.encodeText(b, v.Interface().(encoding.TextMarshaler))
+	}
+	if v.CanAddr() && reflect.PtrTo(v.Type()).Implements(textMarshalerType) {
+		return e.encodeText(b, v.Addr().Interface().(encoding.TextMarshaler))
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		return e.appendBool(b, v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return e.appendInt(b, v.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return e.appendUint(b, v.Uint()), nil
+	case reflect.Float32:
+		return e.appendFloat32(b, float32(v.Float())), nil
+	case reflect.Float64:
+		return e.appendFloat64(b, v.Float()), nil
+	case reflect.String:
+		return e.appendString(b, v.String()), nil
+	case reflect.Ptr, reflect.Interface:
+		return e.nested(b, func(b []byte) ([]byte, error) {
+			return e.encode(b, v.Elem())
+		})
+	case reflect.Slice:
+		if v.IsNil() {
+			return e.appendNil(b), nil
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return e.appendBytes(b, v.Bytes()), nil
+		}
+		return e.encodeArray(b, v)
+	case reflect.Array:
+		return e.encodeArray(b, v)
+	case reflect.Map:
+		if v.IsNil() {
+			return e.appendNil(b), nil
+		}
+		return e.encodeMap(b, v)
+	case reflect.Struct:
+		return e.encodeStruct(b, v)
+	}
+	return nil, fmt.Errorf("%s: unsupported type: %v", e.name(), v.Type())
+}
+
+func (e *encoder) encodeText(b []byte, m encoding.TextMarshaler) ([]byte, error) {
+	text, err := m.MarshalText()
+	if err != nil {
+		return nil, err
+	}
+	return e.appendString(b, string(text)), nil
+}
+
+// nested calls fn to encode a value nested in another one, failing if the data is nested too deeply.
+func (e *encoder) nested(b []byte, fn func([]byte) ([]byte, error)) ([]byte, error) {
+	if e.depth++; e.depth > maxDepth {
+		return nil, fmt.Errorf("%s: maximum nesting depth of %d exceeded", e.name(), maxDepth)
+	}
+	b, err := fn(b)
+	e.depth--
+	return b, err
+}
+
+func (e *encoder) encodeArray(b []byte, v reflect.Value) ([]byte, error) {
+	return e.nested(b, func(b []byte) (_ []byte, err error) {
+		b = e.appendArrayHeader(b, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			if b, err = e.encode(b, v.Index(i)); err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	})
+}
+
+func (e *encoder) encodeMap(b []byte, v reflect.Value) ([]byte, error) {
+	keys := v.MapKeys()
+	sortKeys(keys)
+	return e.nested(b, func(b []byte) (_ []byte, err error) {
+		b = e.appendMapHeader(b, len(keys))
+		for _, k := range keys {
+			if b, err = e.encode(b, k); err != nil {
+				return nil, err
+			}
+			if b, err = e.encode(b, v.MapIndex(k)); err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	})
+}
+
+// sortKeys sorts the keys of a map so that maps are always encoded in the same way.
+// Keys that are neither strings nor numbers are left in their original order.
+func sortKeys(keys []reflect.Value) {
+	if len(keys) == 0 {
+		return
+	}
+	var less func(a, b reflect.Value) bool
+	switch keys[0].Kind() {
+	case reflect.String:
+		less = func(a, b reflect.Value) bool { return a.String() < b.String() }
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		less = func(a, b reflect.Value) bool { return a.Int() < b.Int() }
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		less = func(a, b reflect.Value) bool { return a.Uint() < b.Uint() }
+	case reflect.Float32, reflect.Float64:
+		less = func(a, b reflect.Value) bool { return a.Float() < b.Float() }
+	default:
+		return
+	}
+	sort.Slice(keys, func(i, j int) bool { return less(keys[i], keys[j]) })
+}
+
+func (e *encoder) encodeStruct(b []byte, v reflect.Value) ([]byte, error) {
+	fields := structFields(v.Type())
+	values := make([]reflect.Value, 0, len(fields))
+	names := make([]string, 0, len(fields))
+	for _, f := range fields {
+		fv, ok := fieldByIndex(v, f.index, false)
+		if !ok || f.omitEmpty && isEmptyValue(fv) {
+			continue
+		}
+		values = append(values, fv)
+		names = append(names, f.name)
+	}
+	return e.nested(b, func(b []byte) (_ []byte, err error) {
+		b = e.appendMapHeader(b, len(values))
+		for i, fv := range values {
+			b = e.appendString(b, names[i])
+			if b, err = e.encode(b, fv); err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	})
+}
+
+// isEmptyValue reports whether v is omitted by the "omitempty" option, following the rules of encoding/json.
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	}
+	return false
+}
+
+// itemKind is the kind of a decoded data item.
+type itemKind int
+
+const (
+	kindNil itemKind = iota
+	kindBool
+	kindInt
+	kindUint
+	kindFloat
+	kindString
+	kindBytes
+	kindArray
+	kindMap
+	kindTime
+)
+
+var kindNames = [...]string{"nil", "bool", "integer", "integer", "float", "string", "binary", "array", "map", "timestamp"}
+
+func (k itemKind) String() string {
+	return kindNames[k]
+}
+
+// item is a data item read from the input. Arrays and maps are followed by their elements.
+type item struct {
+	kind itemKind
+	b    bool
+	// i holds negative integers, and u non-negative ones
+	i int64
+	u uint64
+	f float64
+	// s holds the content of strings and binary strings
+	s []byte
+	t time.Time
+	// n is the number of elements of arrays and maps, or -1 if the length is indefinite
+	n int
+}
+
+// scanner reads data items in a specific format.
+type scanner interface {
+	name() string
+	// next reads the next data item.
+	next() (item, error)
+	// end reports whether an array or a map of indefinite length ends at the current position.
+	end() (bool, error)
+	// offset returns the current position in the input.
+	offset() int
+}
+
+// unmarshal decodes the data read by the given scanner into v, which must be a non-nil pointer.
+func unmarshal(s scanner, size int, v interface{}, opts DecodeOptions) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return &DecodeError{msg: fmt.Sprintf("%s: Unmarshal(non-pointer or nil %v)", s.name(), reflect.TypeOf(v))}
+	}
+	d := decoder{scanner: s, opts: opts}
+	if err := d.decode(rv.Elem()); err != nil {
+		return err
+	}
+	if s.offset() < size {
+		return d.error("invalid data after top-level value")
+	}
+	return nil
+}
+
+type decoder struct {
+	scanner
+	opts  DecodeOptions
+	depth int
+}
+
+// error returns a DecodeError reported at the current position.
+func (d *decoder) error(format string, args ...interface{}) error {
+	return &DecodeError{Offset: d.offset(), msg: d.name() + ": " + fmt.Sprintf(format, args...)}
+}
+
+// decode reads the next item into v. If v is not valid, the item is skipped.
+func (d *decoder) decode(v reflect.Value) error {
+	it, err := d.next()
+	if err != nil {
+		return err
+	}
+	if !v.IsValid() {
+		return d.skip(it)
+	}
+	return d.decodeItem(it, v)
+}
+
+func (d *decoder) decodeItem(it item, v reflect.Value) error {
+	if it.kind == kindNil {
+		switch v.Kind() {
+		case reflect.Ptr, reflect.Interface, reflect.Map, reflect.Slice:
+			v.Set(reflect.Zero(v.Type()))
+		}
+		return nil
+	}
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+
+	if it.kind == kindTime && v.Type() == timeType {
+		v.Set(reflect.ValueOf(it.t))
+		return nil
+	}
+	if (it.kind == kindString || it.kind == kindBytes) && reflect.PtrTo(v.Type()).Implements(textUnmarshalerType) {
+		if err := v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText(it.s); err != nil {
+			return d.error("%v", err)
+		}
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Interface:
+		if v.NumMethod() > 0 {
+			break
+		}
+		x, err := d.decodeInterface(it)
+		if err != nil {
+			return err
+		}
+		if x == nil {
+			v.Set(reflect.Zero(v.Type()))
+		} else {
+			v.Set(reflect.ValueOf(x))
+		}
+		return nil
+
+	case reflect.Bool:
+		if it.kind == kindBool {
+			v.SetBool(it.b)
+			return nil
+		}
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if it.kind == kindUint && it.u <= math.MaxInt64 && !v.OverflowInt(int64(it.u)) {
+			v.SetInt(int64(it.u))
+			return nil
+		}
+		if it.kind == kindInt && !v.OverflowInt(it.i) {
+			v.SetInt(it.i)
+			return nil
+		}
+		if it.kind == kindUint || it.kind == kindInt {
+			return d.error("integer overflows Go value of type %v", v.Type())
+		}
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if it.kind == kindUint && !v.OverflowUint(it.u) {
+			v.SetUint(it.u)
+			return nil
+		}
+		if it.kind == kindUint || it.kind == kindInt {
+			return d.error("integer overflows Go value of type %v", v.Type())
+		}
+
+	case reflect.Float32, reflect.Float64:
+		switch it.kind {
+		case kindFloat:
+			v.SetFloat(it.f)
+			return nil
+		case kindInt:
+			v.SetFloat(float64(it.i))
+			return nil
+		case kindUint:
+			v.SetFloat(float64(it.u))
+			return nil
+		}
+
+	case reflect.String:
+		if it.kind == kindString || it.kind == kindBytes {
+			v.SetString(string(it.s))
+			return nil
+		}
+
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 && (it.kind == kindBytes || it.kind == kindString) {
+			v.SetBytes(append([]byte{}, it.s...))
+			return nil
+		}
+		if it.kind == kindArray {
+			return d.decodeSlice(it, v)
+		}
+
+	case reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 && it.kind == kindBytes {
+			reflect.Copy(v, reflect.ValueOf(it.s))
+			for i := len(it.s); i < v.Len(); i++ {
+				v.Index(i).SetUint(0)
+			}
+			return nil
+		}
+		if it.kind == kindArray {
+			return d.decodeArray(it, v)
+		}
+
+	case reflect.Map:
+		if it.kind == kindMap {
+			return d.decodeMap(it, v)
+		}
+
+	case reflect.Struct:
+		if it.kind == kindMap {
+			return d.decodeStruct(it, v)
+		}
+	}
+	err := d.error("cannot decode %v into Go value of type %v", it.kind, v.Type())
+	err.(*DecodeError).Mismatch = true
+	return err
+}
+
+// elements calls fn for each element of the given array or map.
+func (d *decoder) elements(it item, fn func(i int) error) error {
+	if d.depth++; d.depth > maxDepth || d.opts.MaxDepth > 0 && d.depth > d.opts.MaxDepth {
+		limit := d.opts.MaxDepth
+		if limit <= 0 || limit > maxDepth {
+			limit = maxDepth
+		}
+		return d.error("maximum nesting depth of %d exceeded", limit)
+	}
+	for i := 0; it.n < 0 || i < it.n; i++ {
+		if it.n < 0 {
+			if end, err := d.end(); err != nil {
+				return err
+			} else if end {
+				break
+			}
+		}
+		if err := fn(i); err != nil {
+			return err
+		}
+	}
+	d.depth--
+	return nil
+}
+
+func (d *decoder) decodeSlice(it item, v reflect.Value) error {
+	slice := reflect.MakeSlice(v.Type(), 0, 0)
+	err := d.elements(it, func(i int) error {
+		slice = reflect.Append(slice, reflect.Zero(v.Type().Elem()))
+		return d.decode(slice.Index(i))
+	})
+	if err == nil {
+		v.Set(slice)
+	}
+	return err
+}
+
+func (d *decoder) decodeArray(it item, v reflect.Value) error {
+	n := 0
+	err := d.elements(it, func(i int) error {
+		if i >= v.Len() {
+			return d.decode(reflect.Value{})
+		}
+		n++
+		return d.decode(v.Index(i))
+	})
+	for i := n; i < v.Len(); i++ {
+		v.Index(i).Set(reflect.Zero(v.Type().Elem()))
+	}
+	return err
+}
+
+func (d *decoder) decodeMap(it item, v reflect.Value) error {
+	if v.IsNil() {
+		v.Set(reflect.MakeMap(v.Type()))
+	}
+	t := v.Type()
+	return d.elements(it, func(int) error {
+		key := reflect.New(t.Key()).Elem()
+		if err := d.decode(key); err != nil {
+			return err
+		}
+		elem := reflect.New(t.Elem()).Elem()
+		if err := d.decode(elem); err != nil {
+			return err
+		}
+		v.SetMapIndex(key, elem)
+		return nil
+	})
+}
+
+func (d *decoder) decodeStruct(it item, v reflect.Value) error {
+	fields := structFields(v.Type())
+	return d.elements(it, func(int) error {
+		key, err := d.next()
+		if err != nil {
+			return err
+		}
+		var fv reflect.Value
+		if key.kind == kindString || key.kind == kindBytes {
+			if f := lookupField(fields, string(key.s)); f != nil {
+				fv, _ = fieldByIndex(v, f.index, true)
+			} else if d.opts.DisallowUnknownFields {
+				return d.error("unknown field %q", key.s)
+			}
+		} else if err := d.skip(key); err != nil {
+			return err
+		}
+		return d.decode(fv)
+	})
+}
+
+// decodeInterface returns the value of the given item as an interface{}. Arrays are decoded as []interface{},
+// and maps as map[string]interface{} if all their keys are strings, or as map[interface{}]interface{} otherwise.
+func (d *decoder) decodeInterface(it item) (interface{}, error) {
+	switch it.kind {
+	case kindBool:
+		return it.b, nil
+	case kindInt:
+		return it.i, nil
+	case kindUint:
+		if it.u <= math.MaxInt64 {
+			return int64(it.u), nil
+		}
+		return it.u, nil
+	case kindFloat:
+		return it.f, nil
+	case kindString:
+		return string(it.s), nil
+	case kindBytes:
+		return append([]byte{}, it.s...), nil
+	case kindTime:
+		return it.t, nil
+	case kindArray:
+		a := []interface{}{}
+		err := d.elements(it, func(int) error {
+			var x interface{}
+			err := d.decode(reflect.ValueOf(&x).Elem())
+			a = append(a, x)
+			return err
+		})
+		return a, err
+	case kindMap:
+		var keys, values []interface{}
+		stringKeys := true
+		err := d.elements(it, func(int) error {
+			var key, value interface{}
+			if err := d.decode(reflect.ValueOf(&key).Elem()); err != nil {
+				return err
+			}
+			switch key.(type) {
+			case string:
+			case []interface{}, map[string]interface{}, map[interface{}]interface{}, []byte:
+				return d.error("unsupported map key of type %T", key)
+			default:
+				stringKeys = false
+			}
+			if err := d.decode(reflect.ValueOf(&value).Elem()); err != nil {
+				return err
+			}
+			keys, values = append(keys, key), append(values, value)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		if stringKeys {
+			m := make(map[string]interface{}, len(keys))
+			for i, k := range keys {
+				m[k.(string)] = values[i]
+			}
+			return m, nil
+		}
+		m := make(map[interface{}]interface{}, len(keys))
+		for i, k := range keys {
+			m[k] = values[i]
+		}
+		return m, nil
+	}
+	return nil, nil
+}
+
+// skip skips the elements of the given item.
+func (d *decoder) skip(it item) error {
+	switch it.kind {
+	case kindArray:
+		return d.elements(it, func(int) error {
+			return d.decode(reflect.Value{})
+		})
+	case kindMap:
+		return d.elements(it, func(int) error {
+			if err := d.decode(reflect.Value{}); err != nil {
+				return err
+			}
+			return d.decode(reflect.Value{})
+		})
+	}
+	return nil
+}
+
+// field is a struct field encoded as a map entry.
+type field struct {
+	name      string
+	index     []int
+	omitEmpty bool
+}
+
+var fieldCache sync.Map // map[reflect.Type][]field
+
+// structFields returns the fields of the given struct type that are encoded, following the rules of encoding/json:
+// fields are named after their `json` tags, and the fields of embedded structs are promoted unless they are hidden
+// by a field with the same name at a shallower depth.
+func structFields(t reflect.Type) []field {
+	if fields, ok := fieldCache.Load(t); ok {
+		return fields.([]field)
+	}
+	fields := typeFields(t)
+	fieldCache.Store(t, fields)
+	return fields
+}
+
+func typeFields(t reflect.Type) []field {
+	type candidate struct {
+		field
+		depth  int
+		tagged bool
+	}
+	type embedded struct {
+		typ   reflect.Type
+		index []int
+	}
+
+	var candidates []candidate
+	visited := map[reflect.Type]bool{}
+	current := []embedded{{typ: t}}
+	for depth := 0; len(current) > 0; depth++ {
+		var next []embedded
+		for _, e := range current {
+			if visited[e.typ] {
+				continue
+			}
+			visited[e.typ] = true
+			for i := 0; i < e.typ.NumField(); i++ {
+				sf := e.typ.Field(i)
+				ft := sf.Type
+				if ft.Name() == "" && ft.Kind() == reflect.Ptr {
+					ft = ft.Elem()
+				}
+				if sf.Anonymous {
+					if sf.PkgPath != "" && ft.Kind() != reflect.Struct {
+						continue
+					}
+				} else if sf.PkgPath != "" {
+					continue
+				}
+				tag := sf.Tag.Get("json")
+				if tag == "-" {
+					continue
+				}
+				name, opts := tag, ""
+				if j := strings.IndexByte(tag, ','); j >= 0 {
+					name, opts = tag[:j], tag[j+1:]
+				}
+				index := append(append([]int{}, e.index...), i)
+				if name == "" && sf.Anonymous && ft.Kind() == reflect.Struct {
+					next = append(next, embedded{ft, index})
+					continue
+				}
+				tagged := name != ""
+				if name == "" {
+					name = sf.Name
+				}
+				candidates = append(candidates, candidate{
+					field:  field{name: name, index: index, omitEmpty: strings.Contains(","+opts+",", ",omitempty,")},
+					depth:  depth,
+					tagged: tagged,
+				})
+			}
+		}
+		current = next
+	}
+
+	// keep the dominant field for each name: the shallowest one, or the only tagged one at that depth
+	byName := map[string][]candidate{}
+	for _, c := range candidates {
+		byName[c.name] = append(byName[c.name], c)
+	}
+	var fields []field
+	for _, c := range candidates {
+		group := byName[c.name]
+		var dominant []candidate
+		for _, g := range group {
+			if len(dominant) == 0 || g.depth < dominant[0].depth {
+				dominant = []candidate{g}
+			} else if g.depth == dominant[0].depth {
+				dominant = append(dominant, g)
+			}
+		}
+		if len(dominant) > 1 {
+			var tagged []candidate
+			for _, g := range dominant {
+				if g.tagged {
+					tagged = append(tagged, g)
+				}
+			}
+			dominant = tagged
+		}
+		if len(dominant) == 1 && equalIndex(dominant[0].index, c.index) {
+			fields = append(fields, c.field)
+		}
+	}
+	sort.SliceStable(fields, func(i, j int) bool {
+		a, b := fields[i].index, fields[j].index
+		for k := 0; k < len(a) && k < len(b); k++ {
+			if a[k] != b[k] {
+				return a[k] < b[k]
+			}
+		}
+		return len(a) < len(b)
+	})
+	return fields
+}
+
+func equalIndex(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// lookupField finds the field with the given name, preferring an exact match to a case-insensitive one.
+func lookupField(fields []field, name string) *field {
+	var match *field
+	for i := range fields {
+		if fields[i].name == name {
+			return &fields[i]
+		}
+		if match == nil && strings.EqualFold(fields[i].name, name) {
+			match = &fields[i]
+		}
+	}
+	return match
+}
+
+// fieldByIndex returns the nested field of v with the given index. Nil pointers to embedded structs are allocated
+// if alloc is true; otherwise false is returned when such a pointer is found.
+func fieldByIndex(v reflect.Value, index []int, alloc bool) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				if !alloc || !v.CanSet() {
+					return reflect.Value{}, false
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
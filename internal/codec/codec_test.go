@@ -0,0 +1,183 @@
+package codec
+
+import (
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type Base struct {
+	ID      int    `json:"id"`
+	Created string `json:"created,omitempty"`
+}
+
+type Profile struct {
+	Bio string `json:"bio"`
+}
+
+type User struct {
+	Base
+	*Profile
+	Name     string            `json:"name"`
+	Email    string            `json:"email,omitempty"`
+	Password string            `json:"-"`
+	Tags     []string          `json:"tags"`
+	Avatar   []byte            `json:"avatar"`
+	Scores   map[string]int    `json:"scores"`
+	Birthday time.Time         `json:"birthday"`
+	IP       net.IP            `json:"ip"`
+	Manager  *User             `json:"manager,omitempty"`
+	Extra    interface{}       `json:"extra"`
+	Limits   [2]uint8          `json:"limits"`
+	Labels   map[int]string    `json:"labels"`
+	Ratio    float32           `json:"ratio"`
+	Meta     map[string]string `json:"meta,omitempty"`
+	secret   string
+}
+
+var formats = []struct {
+	name      string
+	marshal   func(interface{}) ([]byte, error)
+	unmarshal func([]byte, interface{}, DecodeOptions) error
+}{
+	{"msgpack", MarshalMsgPack, UnmarshalMsgPack},
+	{"cbor", MarshalCBOR, UnmarshalCBOR},
+}
+
+func TestRoundTrip(t *testing.T) {
+	in := User{
+		Base:     Base{ID: 7},
+		Profile:  &Profile{Bio: "hi"},
+		Name:     "Alice",
+		Password: "secret",
+		Tags:     []string{"a", "b"},
+		Avatar:   []byte{0, 1, 2},
+		Scores:   map[string]int{"x": -1, "y": 300},
+		Birthday: time.Date(1990, 5, 17, 10, 30, 0, 0, time.UTC),
+		IP:       net.ParseIP("10.0.0.1"),
+		Manager:  &User{Name: "Bob"},
+		Extra:    []interface{}{"x", 1.5},
+		Limits:   [2]uint8{3, 4},
+		Labels:   map[int]string{2: "b", 1: "a"},
+		Ratio:    0.5,
+		secret:   "x",
+	}
+	for _, f := range formats {
+		data, err := f.marshal(in)
+		if assert.Nil(t, err, f.name) {
+			var out User
+			assert.Nil(t, f.unmarshal(data, &out, DecodeOptions{}), f.name)
+			in.Password, in.secret = "", ""
+			assert.Equal(t, in, out, f.name)
+			in.Password, in.secret = "secret", "x"
+		}
+
+		// decoding into interface{} uses the json tags as map keys
+		var v map[string]interface{}
+		assert.Nil(t, f.unmarshal(data, &v, DecodeOptions{}), f.name)
+		assert.Equal(t, int64(7), v["id"], f.name)
+		assert.Equal(t, "hi", v["bio"], f.name)
+		assert.Equal(t, []interface{}{"a", "b"}, v["tags"], f.name)
+		assert.Equal(t, []byte{0, 1, 2}, v["avatar"], f.name)
+		assert.Equal(t, map[interface{}]interface{}{int64(1): "a", int64(2): "b"}, v["labels"], f.name)
+		assert.Equal(t, "1990-05-17T10:30:00Z", v["birthday"], f.name)
+		assert.Equal(t, "Bob", v["manager"].(map[string]interface{})["name"], f.name)
+		assert.Nil(t, v["manager"].(map[string]interface{})["bio"], f.name)
+		for _, key := range []string{"Password", "secret", "created", "email", "meta"} {
+			assert.NotContains(t, v, key, f.name)
+		}
+	}
+}
+
+func TestStructFields(t *testing.T) {
+	type A struct {
+		Name string
+		X    int
+	}
+	type B struct {
+		Name string
+		X    int
+	}
+	type C struct {
+		A
+		B
+		X int `json:"y"`
+	}
+	type D struct {
+		A
+		Name string
+	}
+
+	names := func(fields []field) []string {
+		var s []string
+		for _, f := range fields {
+			s = append(s, f.name)
+		}
+		return s
+	}
+	// ambiguous fields at the same depth are dropped
+	assert.Equal(t, []string{"y"}, names(structFields(reflect.TypeOf(C{}))))
+	// outer fields hide embedded ones
+	assert.Equal(t, []string{"X", "Name"}, names(structFields(reflect.TypeOf(D{}))))
+
+	fields := structFields(reflect.TypeOf(User{}))
+	assert.Equal(t, "id", lookupField(fields, "id").name)
+	assert.Equal(t, "name", lookupField(fields, "NAME").name)
+	assert.Nil(t, lookupField(fields, "Password"))
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	var u User
+	for _, f := range formats {
+		data, _ := f.marshal(map[string]interface{}{"id": "x"})
+		err := f.unmarshal(data, &u, DecodeOptions{})
+		if assert.NotNil(t, err, f.name) {
+			assert.Equal(t, f.name+": cannot decode string into Go value of type int", err.Error())
+			assert.Equal(t, 6, err.(*DecodeError).Offset, f.name)
+		}
+
+		data, _ = f.marshal(map[string]interface{}{"id": 300, "ratio": 1})
+		var small struct {
+			ID int8 `json:"id"`
+		}
+		err = f.unmarshal(data, &small, DecodeOptions{})
+		assert.EqualError(t, err, f.name+": integer overflows Go value of type int8")
+
+		data, _ = f.marshal(map[string]interface{}{"name": "x", "age": 1})
+		assert.Nil(t, f.unmarshal(data, &u, DecodeOptions{}), f.name)
+		err = f.unmarshal(data, &u, DecodeOptions{DisallowUnknownFields: true})
+		assert.EqualError(t, err, f.name+`: unknown field "age"`)
+
+		data, _ = f.marshal([]interface{}{[]interface{}{[]interface{}{1}}})
+		var v interface{}
+		assert.Nil(t, f.unmarshal(data, &v, DecodeOptions{MaxDepth: 3}), f.name)
+		err = f.unmarshal(data, &v, DecodeOptions{MaxDepth: 2})
+		if assert.NotNil(t, err, f.name) {
+			assert.Equal(t, f.name+": maximum nesting depth of 2 exceeded", err.Error())
+			assert.Equal(t, 3, err.(*DecodeError).Offset, f.name)
+		}
+
+		data, _ = f.marshal("abc")
+		assert.EqualError(t, f.unmarshal(append(data, 1), &v, DecodeOptions{}), f.name+": invalid data after top-level value")
+		assert.EqualError(t, f.unmarshal(data[:2], &v, DecodeOptions{}), f.name+": unexpected end of data")
+		assert.EqualError(t, f.unmarshal(nil, &v, DecodeOptions{}), f.name+": unexpected end of data")
+		assert.EqualError(t, f.unmarshal(data, nil, DecodeOptions{}), f.name+": Unmarshal(non-pointer or nil <nil>)")
+	}
+}
+
+func TestMarshalErrors(t *testing.T) {
+	type node struct {
+		Next *node
+	}
+	cyclic := &node{}
+	cyclic.Next = cyclic
+	for _, f := range formats {
+		_, err := f.marshal(make(chan int))
+		assert.EqualError(t, err, f.name+": unsupported type: chan int")
+		_, err = f.marshal(cyclic)
+		assert.EqualError(t, err, f.name+": maximum nesting depth of 10000 exceeded")
+	}
+}
@@ -0,0 +1,275 @@
+package codec
+
+import (
+	"encoding/binary"
+	"math"
+	"strconv"
+	"time"
+)
+
+// MarshalMsgPack returns the MessagePack encoding of v.
+func MarshalMsgPack(v interface{}) ([]byte, error) {
+	return marshal(msgpackAppender{}, v)
+}
+
+// UnmarshalMsgPack decodes the MessagePack-encoded data into the value pointed to by v.
+// Besides the core types, the timestamp extension type is supported.
+func UnmarshalMsgPack(data []byte, v interface{}, opts DecodeOptions) error {
+	return unmarshal(&msgpackScanner{data: data}, len(data), v, opts)
+}
+
+type msgpackAppender struct{}
+
+func (msgpackAppender) name() string { return "msgpack" }
+
+func (msgpackAppender) appendNil(b []byte) []byte {
+	return append(b, 0xc0)
+}
+
+func (msgpackAppender) appendBool(b []byte, v bool) []byte {
+	if v {
+		return append(b, 0xc3)
+	}
+	return append(b, 0xc2)
+}
+
+func (a msgpackAppender) appendInt(b []byte, v int64) []byte {
+	switch {
+	case v >= 0:
+		return a.appendUint(b, uint64(v))
+	case v >= -32:
+		return append(b, byte(v))
+	case v >= math.MinInt8:
+		return append(b, 0xd0, byte(v))
+	case v >= math.MinInt16:
+		return binary.BigEndian.AppendUint16(append(b, 0xd1), uint16(v))
+	case v >= math.MinInt32:
+		return binary.BigEndian.AppendUint32(append(b, 0xd2), uint32(v))
+	}
+	return binary.BigEndian.AppendUint64(append(b, 0xd3), uint64(v))
+}
+
+func (msgpackAppender) appendUint(b []byte, v uint64) []byte {
+	switch {
+	case v < 0x80:
+		return append(b, byte(v))
+	case v <= math.MaxUint8:
+		return append(b, 0xcc, byte(v))
+	case v <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, 0xcd), uint16(v))
+	case v <= math.MaxUint32:
+		return binary.BigEndian.AppendUint32(append(b, 0xce), uint32(v))
+	}
+	return binary.BigEndian.AppendUint64(append(b, 0xcf), v)
+}
+
+func (msgpackAppender) appendFloat32(b []byte, v float32) []byte {
+	return binary.BigEndian.AppendUint32(append(b, 0xca), math.Float32bits(v))
+}
+
+func (msgpackAppender) appendFloat64(b []byte, v float64) []byte {
+	return binary.BigEndian.AppendUint64(append(b, 0xcb), math.Float64bits(v))
+}
+
+func (msgpackAppender) appendString(b []byte, v string) []byte {
+	n := len(v)
+	switch {
+	case n < 32:
+		b = append(b, 0xa0|byte(n))
+	case n <= math.MaxUint8:
+		b = append(b, 0xd9, byte(n))
+	case n <= math.MaxUint16:
+		b = binary.BigEndian.AppendUint16(append(b, 0xda), uint16(n))
+	default:
+		b = binary.BigEndian.AppendUint32(append(b, 0xdb), uint32(n))
+	}
+	return append(b, v...)
+}
+
+func (msgpackAppender) appendBytes(b []byte, v []byte) []byte {
+	n := len(v)
+	switch {
+	case n <= math.MaxUint8:
+		b = append(b, 0xc4, byte(n))
+	case n <= math.MaxUint16:
+		b = binary.BigEndian.AppendUint16(append(b, 0xc5), uint16(n))
+	default:
+		b = binary.BigEndian.AppendUint32(append(b, 0xc6), uint32(n))
+	}
+	return append(b, v...)
+}
+
+func (msgpackAppender) appendArrayHeader(b []byte, n int) []byte {
+	switch {
+	case n < 16:
+		return append(b, 0x90|byte(n))
+	case n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, 0xdc), uint16(n))
+	}
+	return binary.BigEndian.AppendUint32(append(b, 0xdd), uint32(n))
+}
+
+func (msgpackAppender) appendMapHeader(b []byte, n int) []byte {
+	switch {
+	case n < 16:
+		return append(b, 0x80|byte(n))
+	case n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, 0xde), uint16(n))
+	}
+	return binary.BigEndian.AppendUint32(append(b, 0xdf), uint32(n))
+}
+
+// msgpackTimestamp is the extension type of timestamps.
+const msgpackTimestamp = -1
+
+type msgpackScanner struct {
+	data []byte
+	pos  int
+}
+
+func (s *msgpackScanner) name() string { return "msgpack" }
+
+func (s *msgpackScanner) offset() int { return s.pos }
+
+// end always returns false, as the length of MessagePack arrays and maps is always known.
+func (s *msgpackScanner) end() (bool, error) {
+	return false, nil
+}
+
+func (s *msgpackScanner) error(msg string) error {
+	return &DecodeError{Offset: s.pos, msg: "msgpack: " + msg}
+}
+
+// read consumes the next n bytes of the input.
+func (s *msgpackScanner) read(n int) ([]byte, error) {
+	if n < 0 || n > len(s.data)-s.pos {
+		return nil, s.error("unexpected end of data")
+	}
+	b := s.data[s.pos : s.pos+n]
+	s.pos += n
+	return b, nil
+}
+
+// readUint consumes a big-endian unsigned integer of the given size.
+func (s *msgpackScanner) readUint(size int) (uint64, error) {
+	b, err := s.read(size)
+	if err != nil {
+		return 0, err
+	}
+	var v uint64
+	for _, c := range b {
+		v = v<<8 | uint64(c)
+	}
+	return v, nil
+}
+
+func (s *msgpackScanner) next() (item, error) {
+	b, err := s.read(1)
+	if err != nil {
+		return item{}, err
+	}
+	c := b[0]
+	switch {
+	case c < 0x80:
+		return item{kind: kindUint, u: uint64(c)}, nil
+	case c >= 0xe0:
+		return item{kind: kindInt, i: int64(int8(c))}, nil
+	case c&0xf0 == 0x80:
+		return item{kind: kindMap, n: int(c & 0x0f)}, nil
+	case c&0xf0 == 0x90:
+		return item{kind: kindArray, n: int(c & 0x0f)}, nil
+	case c&0xe0 == 0xa0:
+		return s.readString(kindString, int(c&0x1f))
+	}
+
+	switch c {
+	case 0xc0:
+		return item{kind: kindNil}, nil
+	case 0xc2, 0xc3:
+		return item{kind: kindBool, b: c == 0xc3}, nil
+	case 0xc4, 0xc5, 0xc6:
+		n, err := s.readUint(1 << (c - 0xc4))
+		if err != nil {
+			return item{}, err
+		}
+		return s.readString(kindBytes, int(n))
+	case 0xc7, 0xc8, 0xc9:
+		n, err := s.readUint(1 << (c - 0xc7))
+		if err != nil {
+			return item{}, err
+		}
+		return s.readExt(int(n))
+	case 0xca:
+		v, err := s.readUint(4)
+		return item{kind: kindFloat, f: float64(math.Float32frombits(uint32(v)))}, err
+	case 0xcb:
+		v, err := s.readUint(8)
+		return item{kind: kindFloat, f: math.Float64frombits(v)}, err
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		v, err := s.readUint(1 << (c - 0xcc))
+		return item{kind: kindUint, u: v}, err
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		size := 1 << (c - 0xd0)
+		v, err := s.readUint(size)
+		// sign-extend the integer
+		i := int64(v<<(64-8*size)) >> (64 - 8*size)
+		if i >= 0 {
+			return item{kind: kindUint, u: uint64(i)}, err
+		}
+		return item{kind: kindInt, i: i}, err
+	case 0xd4, 0xd5, 0xd6, 0xd7, 0xd8:
+		return s.readExt(1 << (c - 0xd4))
+	case 0xd9, 0xda, 0xdb:
+		n, err := s.readUint(1 << (c - 0xd9))
+		if err != nil {
+			return item{}, err
+		}
+		return s.readString(kindString, int(n))
+	case 0xdc, 0xdd:
+		n, err := s.readUint(2 << (c - 0xdc))
+		return item{kind: kindArray, n: int(n)}, err
+	case 0xde, 0xdf:
+		n, err := s.readUint(2 << (c - 0xde))
+		return item{kind: kindMap, n: int(n)}, err
+	}
+	s.pos--
+	return item{}, s.error("invalid type code 0xc1")
+}
+
+func (s *msgpackScanner) readString(kind itemKind, n int) (item, error) {
+	b, err := s.read(n)
+	return item{kind: kind, s: b}, err
+}
+
+// readExt reads the type and the data of an extension whose data has n bytes.
+func (s *msgpackScanner) readExt(n int) (item, error) {
+	typ, err := s.read(1)
+	if err != nil {
+		return item{}, err
+	}
+	start := s.pos
+	data, err := s.read(n)
+	if err != nil {
+		return item{}, err
+	}
+	if int8(typ[0]) != msgpackTimestamp {
+		s.pos = start
+		return item{}, s.error("unsupported extension type " + strconv.Itoa(int(int8(typ[0]))))
+	}
+
+	var sec int64
+	var nsec uint32
+	switch n {
+	case 4:
+		sec = int64(binary.BigEndian.Uint32(data))
+	case 8:
+		v := binary.BigEndian.Uint64(data)
+		sec, nsec = int64(v&(1<<34-1)), uint32(v>>34)
+	case 12:
+		nsec, sec = binary.BigEndian.Uint32(data), int64(binary.BigEndian.Uint64(data[4:]))
+	default:
+		s.pos = start
+		return item{}, s.error("invalid timestamp length " + strconv.Itoa(n))
+	}
+	return item{kind: kindTime, t: time.Unix(sec, int64(nsec)).UTC()}, nil
+}
@@ -0,0 +1,109 @@
+package codec
+
+import (
+	"encoding/hex"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMarshalMsgPack(t *testing.T) {
+	tests := []struct {
+		value interface{}
+		hex   string
+	}{
+		{nil, "c0"},
+		{false, "c2"},
+		{true, "c3"},
+		{0, "00"},
+		{127, "7f"},
+		{128, "cc80"},
+		{256, "cd0100"},
+		{uint32(70000), "ce00011170"},
+		{uint64(math.MaxUint64), "cfffffffffffffffff"},
+		{-1, "ff"},
+		{-32, "e0"},
+		{-33, "d0df"},
+		{-129, "d1ff7f"},
+		{-40000, "d2ffff63c0"},
+		{int64(math.MinInt64), "d38000000000000000"},
+		{float32(1.5), "ca3fc00000"},
+		{1.5, "cb3ff8000000000000"},
+		{"", "a0"},
+		{"abc", "a3616263"},
+		{strings.Repeat("x", 32), "d920" + strings.Repeat("78", 32)},
+		{[]byte{1, 2}, "c4020102"},
+		{[]int{1, 2}, "920102"},
+		{make([]int, 16), "dc0010" + strings.Repeat("00", 16)},
+		{map[string]int{"b": 2, "a": 1}, "82a16101a16202"},
+		{[]string(nil), "c0"},
+		{struct {
+			A int `json:"a"`
+			B int `json:"b,omitempty"`
+		}{A: 1}, "81a16101"},
+	}
+	for _, test := range tests {
+		data, err := MarshalMsgPack(test.value)
+		assert.Nil(t, err)
+		assert.Equal(t, test.hex, hex.EncodeToString(data), "%#v", test.value)
+	}
+}
+
+func TestUnmarshalMsgPack(t *testing.T) {
+	tests := []struct {
+		hex   string
+		value interface{}
+	}{
+		{"c0", nil},
+		{"c3", true},
+		{"7f", int64(127)},
+		{"cd0100", int64(256)},
+		{"cfffffffffffffffff", uint64(math.MaxUint64)},
+		{"e0", int64(-32)},
+		{"d17fff", int64(32767)},
+		{"d2ffff63c0", int64(-40000)},
+		{"ca3fc00000", 1.5},
+		{"d903616263", "abc"},
+		{"c500020102", []byte{1, 2}},
+		{"dc0002c0c3", []interface{}{nil, true}},
+		{"de0001a16101", map[string]interface{}{"a": int64(1)}},
+		{"8101a161", map[interface{}]interface{}{int64(1): "a"}},
+		// timestamp extension in its 32, 64 and 96-bit formats
+		{"d6ff5e0be100", time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"d7ff0000000c5e0be100", time.Date(2020, 1, 1, 0, 0, 0, 3, time.UTC)},
+		{"c70cff00000001ffffffffffffffff", time.Date(1969, 12, 31, 23, 59, 59, 1, time.UTC)},
+	}
+	for _, test := range tests {
+		data, _ := hex.DecodeString(test.hex)
+		var v interface{}
+		if assert.Nil(t, UnmarshalMsgPack(data, &v, DecodeOptions{}), test.hex) {
+			assert.Equal(t, test.value, v, test.hex)
+		}
+	}
+
+	var ts struct {
+		Time time.Time `json:"time"`
+	}
+	data, _ := hex.DecodeString("81a474696d65d6ff5e0be100")
+	assert.Nil(t, UnmarshalMsgPack(data, &ts, DecodeOptions{}))
+	assert.Equal(t, time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC), ts.Time)
+
+	errors := []struct {
+		hex string
+		err string
+	}{
+		{"c1", "msgpack: invalid type code 0xc1"},
+		{"d40101", "msgpack: unsupported extension type 1"},
+		{"c703ff000000", "msgpack: invalid timestamp length 3"},
+		{"dd0000ffff", "msgpack: unexpected end of data"},
+		{"c6ffffffff", "msgpack: unexpected end of data"},
+	}
+	for _, test := range errors {
+		data, _ := hex.DecodeString(test.hex)
+		var v interface{}
+		assert.EqualError(t, UnmarshalMsgPack(data, &v, DecodeOptions{}), test.err, test.hex)
+	}
+}
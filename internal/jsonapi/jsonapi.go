@@ -0,0 +1,646 @@
+// Package jsonapi maps Go structs to the resource objects of JSON:API documents (https://jsonapi.org)
+// for the data readers and writers of the ozzo routing package.
+//
+// A struct is a resource if one of its fields has a `jsonapi` tag of the form "primary,<type>", which holds the
+// resource identifier. The other fields are mapped with tags of the form "attr,<name>" and "relation,<name>",
+// optionally followed by ",omitempty". Attributes are encoded with encoding/json. Relationships are pointers to
+// resources, resources, or slices of them.
+//
+//     type Article struct {
+//         ID       int        `jsonapi:"primary,articles"`
+//         Title    string     `jsonapi:"attr,title"`
+//         Author   *Person    `jsonapi:"relation,author"`
+//         Comments []*Comment `jsonapi:"relation,comments,omitempty"`
+//     }
+package jsonapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Document is a JSON:API top-level document.
+type Document struct {
+	// Data is the primary data: a resource object, an array of resource objects, or null.
+	Data     json.RawMessage        `json:"data,omitempty"`
+	Errors   []*ErrorObject         `json:"errors,omitempty"`
+	Included []*Resource            `json:"included,omitempty"`
+	Links    map[string]string      `json:"links,omitempty"`
+	Meta     map[string]interface{} `json:"meta,omitempty"`
+}
+
+// Resource is a JSON:API resource object.
+type Resource struct {
+	Type          string                     `json:"type"`
+	ID            string                     `json:"id,omitempty"`
+	Attributes    map[string]json.RawMessage `json:"attributes,omitempty"`
+	Relationships map[string]*Relationship   `json:"relationships,omitempty"`
+}
+
+// Relationship is a JSON:API relationship object. Its data is a resource identifier, an array of them, or null.
+type Relationship struct {
+	Data json.RawMessage `json:"data"`
+}
+
+// Identifier is a JSON:API resource identifier object.
+type Identifier struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+}
+
+// ErrorObject is a JSON:API error object.
+type ErrorObject struct {
+	Status string `json:"status,omitempty"`
+	Title  string `json:"title,omitempty"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// TypeError reports a resource object whose type does not match the type of the Go value it is decoded into.
+type TypeError struct {
+	Expected string
+	Actual   string
+}
+
+// Error returns the error message.
+func (e *TypeError) Error() string {
+	return fmt.Sprintf("jsonapi: resource type %q does not match %q", e.Actual, e.Expected)
+}
+
+// field kinds
+const (
+	primary  = "primary"
+	attr     = "attr"
+	relation = "relation"
+)
+
+// field is a struct field mapped to a part of a resource object.
+type field struct {
+	name      string
+	index     []int
+	omitEmpty bool
+}
+
+// resourceType describes how a struct type is mapped to resource objects.
+type resourceType struct {
+	name      string
+	id        []int
+	attrs     []field
+	relations []field
+}
+
+var resourceTypes sync.Map // map[reflect.Type]*resourceType
+
+// resourceTypeOf returns the mapping of the given struct type, or an error if the type is not a resource.
+func resourceTypeOf(t reflect.Type) (*resourceType, error) {
+	if rt, ok := resourceTypes.Load(t); ok {
+		return rt.(*resourceType), nil
+	}
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("jsonapi: %v is not a resource struct", t)
+	}
+	rt := &resourceType{}
+	if err := rt.addFields(t, nil); err != nil {
+		return nil, err
+	}
+	if rt.id == nil {
+		return nil, fmt.Errorf("jsonapi: %v has no primary field", t)
+	}
+	resourceTypes.Store(t, rt)
+	return rt, nil
+}
+
+func (rt *resourceType) addFields(t reflect.Type, index []int) error {
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag := sf.Tag.Get("jsonapi")
+		fi := append(append([]int{}, index...), i)
+		if tag == "" {
+			if sf.Anonymous && sf.Type.Kind() == reflect.Struct {
+				if err := rt.addFields(sf.Type, fi); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if sf.PkgPath != "" || tag == "-" {
+			continue
+		}
+		parts := strings.Split(tag, ",")
+		f := field{index: fi}
+		if len(parts) > 1 {
+			f.name = parts[1]
+		}
+		f.omitEmpty = len(parts) > 2 && parts[2] == "omitempty"
+		switch parts[0] {
+		case primary:
+			if f.name == "" {
+				return fmt.Errorf("jsonapi: the primary field of %v has no resource type", t)
+			}
+			rt.name, rt.id = f.name, fi
+		case attr:
+			if f.name == "" {
+				f.name = sf.Name
+			}
+			rt.attrs = append(rt.attrs, f)
+		case relation:
+			if f.name == "" {
+				f.name = sf.Name
+			}
+			rt.relations = append(rt.relations, f)
+		default:
+			return fmt.Errorf("jsonapi: invalid tag %q on %v.%v", tag, t, sf.Name)
+		}
+	}
+	return nil
+}
+
+// ParseInclude parses the comma-separated relationship paths of an "include" query parameter.
+func ParseInclude(include string) [][]string {
+	var paths [][]string
+	for _, path := range strings.Split(include, ",") {
+		if path = strings.TrimSpace(path); path != "" {
+			paths = append(paths, strings.Split(path, "."))
+		}
+	}
+	return paths
+}
+
+// includeTree holds the relationship paths to be included in a compound document.
+type includeTree map[string]includeTree
+
+func newIncludeTree(paths [][]string) includeTree {
+	tree := includeTree{}
+	for _, path := range paths {
+		node := tree
+		for _, name := range path {
+			if node[name] == nil {
+				node[name] = includeTree{}
+			}
+			node = node[name]
+		}
+	}
+	return tree
+}
+
+// Marshal creates a document whose primary data is the given resource, slice of resources, or nil.
+// The resources reached through the given relationship paths are added to the included resources.
+func Marshal(data interface{}, include [][]string) (*Document, error) {
+	m := &marshaler{seen: map[Identifier]bool{}}
+	tree := newIncludeTree(include)
+
+	v := reflect.ValueOf(data)
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		v = v.Elem()
+	}
+	doc := &Document{}
+	var primaryData interface{}
+	switch v.Kind() {
+	case reflect.Invalid:
+	case reflect.Slice, reflect.Array:
+		resources := make([]*Resource, 0, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			r, err := m.resource(v.Index(i), true)
+			if err != nil {
+				return nil, err
+			}
+			if r != nil {
+				resources = append(resources, r)
+			}
+		}
+		for i := 0; i < v.Len(); i++ {
+			if err := m.include(v.Index(i), tree); err != nil {
+				return nil, err
+			}
+		}
+		primaryData = resources
+	default:
+		r, err := m.resource(v, true)
+		if err != nil {
+			return nil, err
+		}
+		if err := m.include(v, tree); err != nil {
+			return nil, err
+		}
+		primaryData = r
+	}
+	var err error
+	if doc.Data, err = encode(primaryData); err != nil {
+		return nil, err
+	}
+	doc.Included = m.included
+	return doc, nil
+}
+
+type marshaler struct {
+	seen     map[Identifier]bool
+	included []*Resource
+}
+
+// resource creates the resource object of the given value. Nil is returned for nil pointers.
+// If primary is true, the resource is marked as seen so that it is not included again.
+func (m *marshaler) resource(v reflect.Value, primary bool) (*Resource, error) {
+	v, ok := indirect(v)
+	if !ok {
+		return nil, nil
+	}
+	rt, err := resourceTypeOf(v.Type())
+	if err != nil {
+		return nil, err
+	}
+	id, err := formatID(v.FieldByIndex(rt.id))
+	if err != nil {
+		return nil, err
+	}
+	r := &Resource{Type: rt.name, ID: id}
+	if primary {
+		m.seen[Identifier{r.Type, r.ID}] = true
+	}
+
+	for _, f := range rt.attrs {
+		fv := v.FieldByIndex(f.index)
+		if f.omitEmpty && isEmptyValue(fv) {
+			continue
+		}
+		raw, err := encode(fv.Interface())
+		if err != nil {
+			return nil, err
+		}
+		if r.Attributes == nil {
+			r.Attributes = map[string]json.RawMessage{}
+		}
+		r.Attributes[f.name] = raw
+	}
+
+	for _, f := range rt.relations {
+		fv := v.FieldByIndex(f.index)
+		if f.omitEmpty && isEmptyValue(fv) {
+			continue
+		}
+		linkage, err := identifiers(fv)
+		if err != nil {
+			return nil, err
+		}
+		raw, err := encode(linkage)
+		if err != nil {
+			return nil, err
+		}
+		if r.Relationships == nil {
+			r.Relationships = map[string]*Relationship{}
+		}
+		r.Relationships[f.name] = &Relationship{Data: raw}
+	}
+	return r, nil
+}
+
+// include adds the resources related to the given resource through the paths of the tree to the included resources.
+func (m *marshaler) include(v reflect.Value, tree includeTree) error {
+	if len(tree) == 0 {
+		return nil
+	}
+	v, ok := indirect(v)
+	if !ok {
+		return nil
+	}
+	rt, err := resourceTypeOf(v.Type())
+	if err != nil {
+		return err
+	}
+	names := make([]string, 0, len(tree))
+	for name := range tree {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		var rel *field
+		for i := range rt.relations {
+			if rt.relations[i].name == name {
+				rel = &rt.relations[i]
+			}
+		}
+		if rel == nil {
+			return &IncludeError{Type: rt.name, Relationship: name}
+		}
+		fv := v.FieldByIndex(rel.index)
+		related := []reflect.Value{fv}
+		if fv.Kind() == reflect.Slice || fv.Kind() == reflect.Array {
+			related = related[:0]
+			for i := 0; i < fv.Len(); i++ {
+				related = append(related, fv.Index(i))
+			}
+		}
+		for _, rv := range related {
+			r, err := m.resource(rv, false)
+			if err != nil {
+				return err
+			}
+			if r == nil {
+				continue
+			}
+			if key := (Identifier{r.Type, r.ID}); !m.seen[key] {
+				m.seen[key] = true
+				m.included = append(m.included, r)
+			}
+			if err := m.include(rv, tree[name]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// IncludeError reports a relationship path in an "include" query parameter that does not exist.
+type IncludeError struct {
+	Type         string
+	Relationship string
+}
+
+// Error returns the error message.
+func (e *IncludeError) Error() string {
+	return fmt.Sprintf("jsonapi: resource type %q has no relationship %q", e.Type, e.Relationship)
+}
+
+// identifiers returns the resource linkage of a relationship field:
+// an Identifier, a slice of Identifier values, or nil.
+func identifiers(v reflect.Value) (interface{}, error) {
+	if v.Kind() == reflect.Slice || v.Kind() == reflect.Array {
+		ids := make([]Identifier, 0, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			id, err := identifier(v.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			if id != nil {
+				ids = append(ids, *id)
+			}
+		}
+		return ids, nil
+	}
+	id, err := identifier(v)
+	if id == nil || err != nil {
+		return nil, err
+	}
+	return id, nil
+}
+
+func identifier(v reflect.Value) (*Identifier, error) {
+	v, ok := indirect(v)
+	if !ok {
+		return nil, nil
+	}
+	rt, err := resourceTypeOf(v.Type())
+	if err != nil {
+		return nil, err
+	}
+	id, err := formatID(v.FieldByIndex(rt.id))
+	if err != nil {
+		return nil, err
+	}
+	return &Identifier{rt.name, id}, nil
+}
+
+// indirect dereferences pointers and interfaces. It returns false if a nil value is found.
+func indirect(v reflect.Value) (reflect.Value, bool) {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return v, false
+		}
+		v = v.Elem()
+	}
+	return v, v.IsValid()
+}
+
+func formatID(v reflect.Value) (string, error) {
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	}
+	return "", fmt.Errorf("jsonapi: unsupported primary field type %v", v.Type())
+}
+
+func parseID(id string, v reflect.Value) error {
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(id)
+		return nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(id, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("jsonapi: invalid resource id %q", id)
+		}
+		v.SetInt(n)
+		return nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(id, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("jsonapi: invalid resource id %q", id)
+		}
+		v.SetUint(n)
+		return nil
+	}
+	return fmt.Errorf("jsonapi: unsupported primary field type %v", v.Type())
+}
+
+// isEmptyValue reports whether v is omitted by the "omitempty" option, following the rules of encoding/json.
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	}
+	return false
+}
+
+// encode returns the JSON encoding of v without escaping HTML characters.
+func encode(v interface{}) (json.RawMessage, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte{'\n'}), nil
+}
+
+// Unmarshal decodes a document whose primary data is a resource object or an array of resource objects into
+// the value pointed to by data, which must be a pointer to a resource struct or to a slice of resources.
+// The related resources of relationships are populated with their identifiers only.
+// A *TypeError is returned if the type of a resource object does not match the type of its Go value.
+func Unmarshal(body []byte, data interface{}, disallowUnknownFields bool) error {
+	v := reflect.ValueOf(data)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return errors.New("jsonapi: data must be a non-nil pointer")
+	}
+	var doc struct {
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return err
+	}
+	raw := bytes.TrimSpace(doc.Data)
+	if len(raw) == 0 || bytes.Equal(raw, []byte("null")) {
+		return errors.New("jsonapi: the document has no primary data")
+	}
+	u := unmarshaler{disallowUnknownFields: disallowUnknownFields}
+	v = v.Elem()
+	if v.Kind() == reflect.Slice {
+		if raw[0] != '[' {
+			return errors.New("jsonapi: the primary data must be an array of resource objects")
+		}
+		var resources []json.RawMessage
+		if err := json.Unmarshal(raw, &resources); err != nil {
+			return err
+		}
+		slice := reflect.MakeSlice(v.Type(), len(resources), len(resources))
+		for i, r := range resources {
+			if err := u.resource(r, slice.Index(i)); err != nil {
+				return err
+			}
+		}
+		v.Set(slice)
+		return nil
+	}
+	if raw[0] != '{' {
+		return errors.New("jsonapi: the primary data must be a resource object")
+	}
+	return u.resource(raw, v)
+}
+
+type unmarshaler struct {
+	disallowUnknownFields bool
+}
+
+// resource decodes a resource object into v, allocating pointers as needed.
+func (u *unmarshaler) resource(raw json.RawMessage, v reflect.Value) error {
+	v = allocate(v)
+	rt, err := resourceTypeOf(v.Type())
+	if err != nil {
+		return err
+	}
+	var r Resource
+	if err := json.Unmarshal(raw, &r); err != nil {
+		return err
+	}
+	if r.Type != rt.name {
+		return &TypeError{Expected: rt.name, Actual: r.Type}
+	}
+	if r.ID != "" {
+		if err := parseID(r.ID, v.FieldByIndex(rt.id)); err != nil {
+			return err
+		}
+	}
+
+	for name, value := range r.Attributes {
+		f := lookup(rt.attrs, name)
+		if f == nil {
+			if u.disallowUnknownFields {
+				return fmt.Errorf("jsonapi: unknown attribute %q", name)
+			}
+			continue
+		}
+		dec := json.NewDecoder(bytes.NewReader(value))
+		if u.disallowUnknownFields {
+			dec.DisallowUnknownFields()
+		}
+		if err := dec.Decode(v.FieldByIndex(f.index).Addr().Interface()); err != nil {
+			return fmt.Errorf("jsonapi: attribute %q: %v", name, err)
+		}
+	}
+
+	for name, rel := range r.Relationships {
+		f := lookup(rt.relations, name)
+		if f == nil {
+			if u.disallowUnknownFields {
+				return fmt.Errorf("jsonapi: unknown relationship %q", name)
+			}
+			continue
+		}
+		if rel == nil {
+			continue
+		}
+		if err := u.linkage(rel.Data, v.FieldByIndex(f.index)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// linkage decodes the resource linkage of a relationship into the relationship field v.
+func (u *unmarshaler) linkage(raw json.RawMessage, v reflect.Value) error {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || bytes.Equal(raw, []byte("null")) {
+		v.Set(reflect.Zero(v.Type()))
+		return nil
+	}
+	if v.Kind() == reflect.Slice {
+		var ids []Identifier
+		if err := json.Unmarshal(raw, &ids); err != nil {
+			return fmt.Errorf("jsonapi: invalid resource linkage: %v", err)
+		}
+		slice := reflect.MakeSlice(v.Type(), len(ids), len(ids))
+		for i, id := range ids {
+			if err := setIdentifier(id, slice.Index(i)); err != nil {
+				return err
+			}
+		}
+		v.Set(slice)
+		return nil
+	}
+	var id Identifier
+	if err := json.Unmarshal(raw, &id); err != nil {
+		return fmt.Errorf("jsonapi: invalid resource linkage: %v", err)
+	}
+	return setIdentifier(id, v)
+}
+
+// setIdentifier sets the identifier of the related resource v.
+func setIdentifier(id Identifier, v reflect.Value) error {
+	v = allocate(v)
+	rt, err := resourceTypeOf(v.Type())
+	if err != nil {
+		return err
+	}
+	if id.Type != rt.name {
+		return &TypeError{Expected: rt.name, Actual: id.Type}
+	}
+	return parseID(id.ID, v.FieldByIndex(rt.id))
+}
+
+// allocate dereferences v, allocating nil pointers.
+func allocate(v reflect.Value) reflect.Value {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+func lookup(fields []field, name string) *field {
+	for i := range fields {
+		if fields[i].name == name {
+			return &fields[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package jsonapi
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type person struct {
+	ID   string `jsonapi:"primary,people"`
+	Name string `jsonapi:"attr,name"`
+}
+
+type comment struct {
+	ID     int     `jsonapi:"primary,comments"`
+	Body   string  `jsonapi:"attr,body"`
+	Author *person `jsonapi:"relation,author"`
+}
+
+type timestamps struct {
+	Created string `jsonapi:"attr,created,omitempty"`
+}
+
+type article struct {
+	timestamps
+	ID       int        `jsonapi:"primary,articles"`
+	Title    string     `jsonapi:"attr,title"`
+	Author   *person    `jsonapi:"relation,author"`
+	Comments []*comment `jsonapi:"relation,comments,omitempty"`
+	internal string
+}
+
+func marshal(t *testing.T, data interface{}, include string) string {
+	doc, err := Marshal(data, ParseInclude(include))
+	if !assert.Nil(t, err) {
+		return ""
+	}
+	s, _ := encode(doc)
+	return string(s)
+}
+
+func TestMarshal(t *testing.T) {
+	alice := &person{"a", "Alice"}
+	bob := &person{"b", "Bob"}
+	a1 := article{
+		ID:       1,
+		Title:    "<Hello>",
+		Author:   alice,
+		Comments: []*comment{{5, "Hi", bob}, {6, "Hey", alice}},
+	}
+	a2 := &article{ID: 2, timestamps: timestamps{"today"}}
+
+	assert.Equal(t, `{"data":{"type":"articles","id":"2","attributes":{"created":"today","title":""},"relationships":{"author":{"data":null}}}}`,
+		marshal(t, a2, ""))
+	assert.Equal(t, `{"data":{"type":"articles","id":"1","attributes":{"title":"<Hello>"},`+
+		`"relationships":{"author":{"data":{"type":"people","id":"a"}},"comments":{"data":[{"type":"comments","id":"5"},{"type":"comments","id":"6"}]}}},`+
+		`"included":[{"type":"people","id":"a","attributes":{"name":"Alice"}}]}`,
+		marshal(t, a1, "author"))
+	assert.Equal(t, `{"data":[{"type":"comments","id":"5","attributes":{"body":"Hi"},"relationships":{"author":{"data":{"type":"people","id":"b"}}}},`+
+		`{"type":"comments","id":"6","attributes":{"body":"Hey"},"relationships":{"author":{"data":{"type":"people","id":"a"}}}}],`+
+		`"included":[{"type":"people","id":"b","attributes":{"name":"Bob"}},{"type":"people","id":"a","attributes":{"name":"Alice"}}]}`,
+		marshal(t, a1.Comments, "author"))
+
+	// related resources are included once, and primary resources are not included again
+	doc, err := Marshal([]*article{&a1, a2}, ParseInclude("comments.author, author"))
+	if assert.Nil(t, err) {
+		var ids []Identifier
+		for _, r := range doc.Included {
+			ids = append(ids, Identifier{r.Type, r.ID})
+		}
+		assert.Equal(t, []Identifier{{"people", "a"}, {"comments", "5"}, {"people", "b"}, {"comments", "6"}}, ids)
+	}
+
+	assert.Equal(t, `{"data":null}`, marshal(t, nil, ""))
+	assert.Equal(t, `{"data":[]}`, marshal(t, []article{}, ""))
+
+	_, err = Marshal(a1, ParseInclude("author.articles"))
+	assert.EqualError(t, err, `jsonapi: resource type "people" has no relationship "articles"`)
+	assert.IsType(t, &IncludeError{}, err)
+	_, err = Marshal(struct{ ID int }{1}, nil)
+	assert.EqualError(t, err, "jsonapi: struct { ID int } has no primary field")
+	_, err = Marshal(1, nil)
+	assert.EqualError(t, err, "jsonapi: int is not a resource struct")
+}
+
+func TestParseInclude(t *testing.T) {
+	assert.Nil(t, ParseInclude(""))
+	assert.Equal(t, [][]string{{"a"}, {"b", "c"}}, ParseInclude("a, ,b.c"))
+}
+
+func TestUnmarshal(t *testing.T) {
+	var a article
+	body := `{"data":{"type":"articles","id":"1","attributes":{"title":"Hello","created":"today","views":3},
+		"relationships":{"author":{"data":{"type":"people","id":"a"}},"comments":{"data":[{"type":"comments","id":"5"}]}}}}`
+	if assert.Nil(t, Unmarshal([]byte(body), &a, false)) {
+		assert.Equal(t, article{
+			ID:         1,
+			Title:      "Hello",
+			timestamps: timestamps{"today"},
+			Author:     &person{ID: "a"},
+			Comments:   []*comment{{ID: 5}},
+		}, a)
+	}
+	assert.EqualError(t, Unmarshal([]byte(body), &a, true), `jsonapi: unknown attribute "views"`)
+
+	var people []person
+	if assert.Nil(t, Unmarshal([]byte(`{"data":[{"type":"people","attributes":{"name":"Alice"}}]}`), &people, true)) {
+		assert.Equal(t, []person{{Name: "Alice"}}, people)
+	}
+
+	tests := []struct {
+		body string
+		err  string
+	}{
+		{`{"data":null}`, "jsonapi: the document has no primary data"},
+		{`{"errors":[]}`, "jsonapi: the document has no primary data"},
+		{`{"data":[]}`, "jsonapi: the primary data must be a resource object"},
+		{`{"data":{"type":"people"}}`, `jsonapi: resource type "people" does not match "articles"`},
+		{`{"data":{"type":"articles","id":"x"}}`, `jsonapi: invalid resource id "x"`},
+		{`{"data":{"type":"articles","attributes":{"title":1}}}`,
+			`jsonapi: attribute "title": json: cannot unmarshal number into Go value of type string`},
+		{`{"data":{"type":"articles","relationships":{"author":{"data":{"type":"articles","id":"1"}}}}}`,
+			`jsonapi: resource type "articles" does not match "people"`},
+		{`{"data":{"type":"articles","relationships":{"comments":{"data":{}}}}}`,
+			"jsonapi: invalid resource linkage: json: cannot unmarshal object into Go value of type []jsonapi.Identifier"},
+	}
+	for _, test := range tests {
+		var a article
+		assert.EqualError(t, Unmarshal([]byte(test.body), &a, false), test.err, test.body)
+	}
+	_, ok := Unmarshal([]byte(`{"data":{"type":"people"}}`), &a, false).(*TypeError)
+	assert.True(t, ok)
+	assert.EqualError(t, Unmarshal([]byte(`{"data":{"type":"people"}}`), &people, false),
+		"jsonapi: the primary data must be an array of resource objects")
+	assert.EqualError(t, Unmarshal([]byte(`{}`), a, false), "jsonapi: data must be a non-nil pointer")
+}
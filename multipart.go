@@ -0,0 +1,193 @@
+package routing
+
+import (
+	"errors"
+	"hash"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+)
+
+const fileTag = "file"
+
+// sniffLen is the number of leading bytes of an uploaded file examined by DetectFileType.
+const sniffLen = 512
+
+var (
+	fileHeaderType      = reflect.TypeOf((*multipart.FileHeader)(nil))
+	fileHeaderSliceType = reflect.TypeOf([]*multipart.FileHeader(nil))
+)
+
+// fileLimits represents the constraints declared for a file field via the "file" struct tag.
+type fileLimits struct {
+	maxSize int64
+	types   []string
+}
+
+// ReadMultipartFormData populates the data variable with the values and the uploaded files of the given multipart form.
+//
+// Struct fields of type *multipart.FileHeader receive the first file uploaded under the field name,
+// while fields of type []*multipart.FileHeader receive all of them. The name is determined in the same
+// way as for the other form fields. A struct tag named "file" may be used to restrict the accepted uploads:
+//
+//     var data struct {
+//         Title  string
+//         Avatar *multipart.FileHeader   `form:"avatar" file:"maxsize=1048576,types=image/png|image/jpeg"`
+//         Scans  []*multipart.FileHeader `file:"types=application/pdf|image/*"`
+//     }
+//
+// "maxsize" is the maximum number of bytes allowed for each file. "types" lists the acceptable MIME types
+// separated by "|"; a type may end with "/*" to accept any subtype. The MIME type of a file is determined by
+// sniffing its content (see DetectFileType) rather than by trusting the Content-Type header of the part.
+// An upload violating these limits results in an HTTPError with status 413 or 415, respectively.
+func ReadMultipartFormData(form *multipart.Form, data interface{}) error {
+	rv, err := formTarget(data)
+	if err != nil {
+		return err
+	}
+	return readForm(form.Value, form.File, "", rv)
+}
+
+// DetectFileType returns the MIME type of the given uploaded file, as determined by
+// http.DetectContentType from the leading bytes of the file content.
+// The returned type has no parameters, e.g. "text/plain" rather than "text/plain; charset=utf-8".
+func DetectFileType(fh *multipart.FileHeader) (string, error) {
+	f, err := fh.Open()
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	buf := make([]byte, sniffLen)
+	n, err := io.ReadFull(f, buf)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", err
+	}
+	t, _, err := mime.ParseMediaType(http.DetectContentType(buf[:n]))
+	return t, err
+}
+
+// CopyFormFile streams the content of the given uploaded file into dst.
+// If h is not nil, the content is also fed into h and the resulting checksum is returned.
+// The method returns the number of bytes copied.
+func CopyFormFile(dst io.Writer, fh *multipart.FileHeader, h hash.Hash) (n int64, sum []byte, err error) {
+	f, err := fh.Open()
+	if err != nil {
+		return 0, nil, err
+	}
+	defer f.Close()
+
+	if h != nil {
+		dst = io.MultiWriter(dst, h)
+	}
+	if n, err = io.Copy(dst, f); err != nil {
+		return n, nil, err
+	}
+	if h != nil {
+		sum = h.Sum(nil)
+	}
+	return n, sum, nil
+}
+
+// SaveFormFile streams the content of the given uploaded file into a newly created file with the specified path.
+// If h is not nil, the checksum of the content is calculated while the file is being written and returned.
+// The partially written file is removed if the copying fails.
+func SaveFormFile(fh *multipart.FileHeader, path string, h hash.Hash) (sum []byte, err error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	if _, sum, err = CopyFormFile(f, fh, h); err != nil {
+		f.Close()
+		os.Remove(path)
+		return nil, err
+	}
+	if err = f.Close(); err != nil {
+		os.Remove(path)
+		return nil, err
+	}
+	return sum, nil
+}
+
+// isFileField checks if the given type should be populated with uploaded files.
+func isFileField(t reflect.Type) bool {
+	return t == fileHeaderType || t == fileHeaderSliceType
+}
+
+func readFormFiles(files map[string][]*multipart.FileHeader, name string, field reflect.StructField, rv reflect.Value) error {
+	fhs := files[name]
+	if len(fhs) == 0 {
+		return nil
+	}
+	limits, err := parseFileLimits(field.Tag.Get(fileTag))
+	if err != nil {
+		return err
+	}
+	if rv.Type() == fileHeaderType {
+		fhs = fhs[:1]
+	}
+	for _, fh := range fhs {
+		if err := limits.check(name, fh); err != nil {
+			return err
+		}
+	}
+	if rv.Type() == fileHeaderType {
+		rv.Set(reflect.ValueOf(fhs[0]))
+	} else {
+		rv.Set(reflect.ValueOf(fhs))
+	}
+	return nil
+}
+
+// parseFileLimits parses a "file" struct tag such as "maxsize=1024,types=image/png|image/gif".
+func parseFileLimits(tag string) (limits fileLimits, err error) {
+	for _, opt := range strings.Split(tag, ",") {
+		k, v := opt, ""
+		if i := strings.IndexByte(opt, '='); i >= 0 {
+			k, v = opt[:i], opt[i+1:]
+		}
+		switch strings.TrimSpace(k) {
+		case "":
+		case "maxsize":
+			if limits.maxSize, err = strconv.ParseInt(strings.TrimSpace(v), 10, 64); err != nil {
+				return limits, err
+			}
+		case "types":
+			for _, t := range strings.Split(v, "|") {
+				if t = strings.TrimSpace(t); t != "" {
+					limits.types = append(limits.types, strings.ToLower(t))
+				}
+			}
+		default:
+			return limits, errors.New("Unknown file option: " + k)
+		}
+	}
+	return limits, nil
+}
+
+// check verifies that the given uploaded file satisfies the limits.
+func (l fileLimits) check(name string, fh *multipart.FileHeader) error {
+	if l.maxSize > 0 && fh.Size > l.maxSize {
+		return NewHTTPError(fasthttp.StatusRequestEntityTooLarge, "file "+name+" exceeds "+strconv.FormatInt(l.maxSize, 10)+" bytes")
+	}
+	if len(l.types) == 0 {
+		return nil
+	}
+	t, err := DetectFileType(fh)
+	if err != nil {
+		return err
+	}
+	for _, allowed := range l.types {
+		if allowed == t || strings.HasSuffix(allowed, "/*") && strings.HasPrefix(t, allowed[:len(allowed)-1]) {
+			return nil
+		}
+	}
+	return NewHTTPError(fasthttp.StatusUnsupportedMediaType, "file "+name+" has unsupported type "+t)
+}
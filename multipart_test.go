@@ -0,0 +1,120 @@
+package routing
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"io/ioutil"
+	"mime/multipart"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+var pngHeader = []byte("\x89PNG\x0D\x0A\x1A\x0A")
+
+func newMultipartContext(values map[string]string, files map[string][][]byte) *Context {
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	for k, v := range values {
+		w.WriteField(k, v)
+	}
+	for k, contents := range files {
+		for i, content := range contents {
+			fw, _ := w.CreateFormFile(k, k+string(rune('0'+i)))
+			fw.Write(content)
+		}
+	}
+	w.Close()
+
+	var ctx fasthttp.RequestCtx
+	ctx.Request.Header.SetMethod("POST")
+	ctx.Request.SetRequestURI("/upload?Title=query")
+	ctx.Request.Header.SetContentType(w.FormDataContentType())
+	ctx.Request.SetBody(body.Bytes())
+	return NewContext(&ctx)
+}
+
+func TestReadMultipartForm(t *testing.T) {
+	var data struct {
+		Title  string
+		Avatar *multipart.FileHeader   `form:"avatar" file:"maxsize=100,types=image/png"`
+		Scans  []*multipart.FileHeader `file:"types=text/*"`
+		Other  *multipart.FileHeader
+	}
+	c := newMultipartContext(map[string]string{"Title": "doc"}, map[string][][]byte{
+		"avatar": {append(pngHeader, "data"...)},
+		"Scans":  {[]byte("page 1"), []byte("page 2")},
+	})
+	assert.Nil(t, c.Read(&data))
+	assert.Equal(t, "doc", data.Title)
+	if assert.NotNil(t, data.Avatar) {
+		assert.Equal(t, "avatar0", data.Avatar.Filename)
+	}
+	if assert.Len(t, data.Scans, 2) {
+		assert.Equal(t, "Scans1", data.Scans[1].Filename)
+	}
+	assert.Nil(t, data.Other)
+
+	c = newMultipartContext(nil, map[string][][]byte{
+		"avatar": {append(pngHeader, bytes.Repeat([]byte("x"), 100)...)},
+	})
+	err := c.Read(&data)
+	if assert.NotNil(t, err) {
+		assert.Equal(t, fasthttp.StatusRequestEntityTooLarge, err.(HTTPError).StatusCode())
+	}
+
+	c = newMultipartContext(nil, map[string][][]byte{
+		"avatar": {[]byte("<html><body>not an image</body></html>")},
+	})
+	err = c.Read(&data)
+	if assert.NotNil(t, err) {
+		assert.Equal(t, fasthttp.StatusUnsupportedMediaType, err.(HTTPError).StatusCode())
+		assert.Equal(t, "file avatar has unsupported type text/html", err.Error())
+	}
+
+	var bad struct {
+		File *multipart.FileHeader `file:"limit=1"`
+	}
+	c = newMultipartContext(nil, map[string][][]byte{"File": {[]byte("abc")}})
+	assert.NotNil(t, c.Read(&bad))
+
+	c = newMultipartContext(map[string]string{"Title": "doc"}, nil)
+	c.Request.SetBodyString("--truncated")
+	assertHTTPError(t, c.Read(&data), fasthttp.StatusBadRequest, "malformed multipart form data")
+}
+
+func TestSaveFormFile(t *testing.T) {
+	c := newMultipartContext(nil, map[string][][]byte{"doc": {[]byte("hello world")}})
+	fh, err := c.FormFile("doc")
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	var buf bytes.Buffer
+	n, sum, err := CopyFormFile(&buf, fh, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(11), n)
+	assert.Nil(t, sum)
+	assert.Equal(t, "hello world", buf.String())
+
+	dir, err := ioutil.TempDir("", "routing")
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "doc.txt")
+	sum, err = SaveFormFile(fh, path, sha256.New())
+	assert.Nil(t, err)
+	expected := sha256.Sum256([]byte("hello world"))
+	assert.Equal(t, expected[:], sum)
+	content, _ := ioutil.ReadFile(path)
+	assert.Equal(t, "hello world", string(content))
+
+	typ, err := DetectFileType(fh)
+	assert.Nil(t, err)
+	assert.Equal(t, "text/plain", typ)
+}
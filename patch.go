@@ -0,0 +1,491 @@
+package routing
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+)
+
+// JSONPatchDataReader applies the request body as a JSON Patch document (RFC 6902) to the given data.
+// The data should already hold the current state of the resource being patched.
+//
+// A malformed patch document results in a 400 HTTP error, an operation that cannot be applied
+// because the resource does not have the expected state (a missing path or a failed "test" operation)
+// results in a 409 HTTP error, and an invalid operation or a result that does not fit the data
+// results in a 422 HTTP error. The data is left unchanged if any operation fails.
+type JSONPatchDataReader struct{}
+
+func (r *JSONPatchDataReader) Read(ctx *fasthttp.RequestCtx, data interface{}) error {
+	var ops []patchOperation
+	if err := json.Unmarshal(ctx.PostBody(), &ops); err != nil {
+		return NewHTTPError(fasthttp.StatusBadRequest, err.Error())
+	}
+	return patchData(data, func(doc interface{}) (interface{}, error) {
+		var err error
+		for i, op := range ops {
+			if doc, err = op.apply(doc); err != nil {
+				return nil, wrapPatchError(err, "operation "+strconv.Itoa(i)+" ("+op.Op+" "+op.Path+")")
+			}
+		}
+		return doc, nil
+	})
+}
+
+// MergePatchDataReader applies the request body as a JSON Merge Patch document (RFC 7386) to the given data.
+// The data should already hold the current state of the resource being patched.
+//
+// A malformed patch document results in a 400 HTTP error, and a result that does not fit the data
+// results in a 422 HTTP error.
+type MergePatchDataReader struct{}
+
+func (r *MergePatchDataReader) Read(ctx *fasthttp.RequestCtx, data interface{}) error {
+	patch, err := decodeJSONValue(ctx.PostBody())
+	if err != nil {
+		return NewHTTPError(fasthttp.StatusBadRequest, err.Error())
+	}
+	return patchData(data, func(doc interface{}) (interface{}, error) {
+		return mergePatch(doc, patch), nil
+	})
+}
+
+// patchOperation represents a single operation in a JSON Patch document.
+type patchOperation struct {
+	Op    string           `json:"op"`
+	Path  string           `json:"path"`
+	From  string           `json:"from"`
+	Value *json.RawMessage `json:"value"`
+}
+
+// patchError is an error caused by applying a patch, carrying the HTTP status that should be reported.
+type patchError struct {
+	status  int
+	message string
+}
+
+func (e *patchError) Error() string {
+	return e.message
+}
+
+func conflict(message string) error {
+	return &patchError{fasthttp.StatusConflict, message}
+}
+
+func unprocessable(message string) error {
+	return &patchError{fasthttp.StatusUnprocessableEntity, message}
+}
+
+// wrapPatchError converts err into an HTTPError whose message is prefixed with the given context.
+func wrapPatchError(err error, context string) HTTPError {
+	status := fasthttp.StatusUnprocessableEntity
+	if e, ok := err.(*patchError); ok {
+		status = e.status
+	}
+	return NewHTTPError(status, context+": "+err.Error())
+}
+
+// patchData converts data into its generic JSON representation, transforms it using fn, and stores the result back into data.
+func patchData(data interface{}, fn func(interface{}) (interface{}, error)) error {
+	rv := reflect.ValueOf(data)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return errors.New("data must be a pointer")
+	}
+	current, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	original, err := decodeJSONValue(current)
+	if err != nil {
+		return err
+	}
+	// the patch modifies the document in place, so keep the original to find the removed members
+	doc, err := fn(copyValue(original))
+	if err != nil {
+		return err
+	}
+	result, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+
+	// check that the result fits the data before touching it, as the current value may share pointers and maps with data
+	if err := json.Unmarshal(result, reflect.New(rv.Type().Elem()).Interface()); err != nil {
+		return NewHTTPError(fasthttp.StatusUnprocessableEntity, err.Error())
+	}
+
+	// decode into a copy of the current value so that fields not represented in JSON (such as unexported
+	// fields and those tagged with "-") are preserved, clearing the members removed by the patch first
+	v := reflect.New(rv.Type().Elem())
+	v.Elem().Set(rv.Elem())
+	clearRemoved(v.Elem(), original, doc)
+	if err := json.Unmarshal(result, v.Interface()); err != nil {
+		return NewHTTPError(fasthttp.StatusUnprocessableEntity, err.Error())
+	}
+	rv.Elem().Set(v.Elem())
+	return nil
+}
+
+// clearRemoved zeroes the struct fields and deletes the map entries of v whose members are present in the
+// JSON document before but missing from the document after, as decoding the latter would leave them untouched.
+func clearRemoved(v reflect.Value, before, after interface{}) {
+	b, ok := before.(map[string]interface{})
+	if !ok {
+		return
+	}
+	a, ok := after.(map[string]interface{})
+	if !ok {
+		return
+	}
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+	if !v.CanSet() {
+		return
+	}
+	switch v.Kind() {
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return
+		}
+		for k := range b {
+			if _, ok := a[k]; !ok {
+				v.SetMapIndex(reflect.ValueOf(k).Convert(v.Type().Key()), reflect.Value{})
+			}
+		}
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			name, tagged := jsonFieldName(field)
+			if name == "-" || field.PkgPath != "" && !field.Anonymous {
+				continue
+			}
+			if field.Anonymous && !tagged {
+				// the members of an embedded struct are promoted into the enclosing object
+				clearRemoved(v.Field(i), before, after)
+				continue
+			}
+			if _, ok := b[name]; !ok {
+				continue
+			}
+			if value, ok := a[name]; !ok {
+				v.Field(i).Set(reflect.Zero(field.Type))
+			} else {
+				clearRemoved(v.Field(i), b[name], value)
+			}
+		}
+	}
+}
+
+// jsonFieldName returns the name of the JSON member that the struct field is encoded as,
+// and whether the name comes from a "json" tag.
+func jsonFieldName(field reflect.StructField) (string, bool) {
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return tag, true
+	}
+	if name := strings.Split(tag, ",")[0]; name != "" {
+		return name, true
+	}
+	return field.Name, false
+}
+
+// decodeJSONValue decodes JSON data into generic values, keeping numbers as json.Number.
+func decodeJSONValue(data []byte) (v interface{}, err error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	err = dec.Decode(&v)
+	return
+}
+
+func (op *patchOperation) value() (interface{}, error) {
+	if op.Value == nil {
+		return nil, unprocessable(`missing "value"`)
+	}
+	return decodeJSONValue(*op.Value)
+}
+
+func (op *patchOperation) apply(doc interface{}) (interface{}, error) {
+	path, err := parsePointer(op.Path)
+	if err != nil {
+		return nil, err
+	}
+	switch op.Op {
+	case "add":
+		v, err := op.value()
+		if err != nil {
+			return nil, err
+		}
+		return addValue(doc, path, v)
+	case "remove":
+		doc, _, err = removeValue(doc, path)
+		return doc, err
+	case "replace":
+		v, err := op.value()
+		if err != nil {
+			return nil, err
+		}
+		if doc, _, err = removeValue(doc, path); err != nil {
+			return nil, err
+		}
+		return addValue(doc, path, v)
+	case "move", "copy":
+		from, err := parsePointer(op.From)
+		if err != nil {
+			return nil, err
+		}
+		var v interface{}
+		if op.Op == "move" {
+			if isPrefix(from, path) && len(from) < len(path) {
+				return nil, unprocessable(`"from" is a proper prefix of "path"`)
+			}
+			doc, v, err = removeValue(doc, from)
+		} else {
+			v, err = getValue(doc, from)
+			v = copyValue(v)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return addValue(doc, path, v)
+	case "test":
+		v, err := op.value()
+		if err != nil {
+			return nil, err
+		}
+		current, err := getValue(doc, path)
+		if err != nil {
+			return nil, err
+		}
+		if !jsonEqual(current, v) {
+			return nil, conflict("test failed")
+		}
+		return doc, nil
+	}
+	return nil, unprocessable("unknown operation " + strconv.Quote(op.Op))
+}
+
+// parsePointer splits a JSON Pointer (RFC 6901) into its unescaped reference tokens.
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if pointer[0] != '/' {
+		return nil, unprocessable("invalid JSON pointer " + strconv.Quote(pointer))
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, t := range tokens {
+		tokens[i] = strings.Replace(strings.Replace(t, "~1", "/", -1), "~0", "~", -1)
+	}
+	return tokens, nil
+}
+
+func isPrefix(prefix, path []string) bool {
+	if len(prefix) > len(path) {
+		return false
+	}
+	for i, t := range prefix {
+		if path[i] != t {
+			return false
+		}
+	}
+	return true
+}
+
+// arrayIndex parses a reference token used to index an array of size n.
+// If allowEnd is true, the token "-" and the index n, both referring to the end of the array, are accepted.
+func arrayIndex(token string, n int, allowEnd bool) (int, error) {
+	if token == "-" && allowEnd {
+		return n, nil
+	}
+	i, err := strconv.Atoi(token)
+	if err != nil || i < 0 || token != strconv.Itoa(i) {
+		return 0, unprocessable("invalid array index " + strconv.Quote(token))
+	}
+	if i > n || i == n && !allowEnd {
+		return 0, conflict("array index " + token + " out of range")
+	}
+	return i, nil
+}
+
+func getValue(doc interface{}, path []string) (interface{}, error) {
+	for _, token := range path {
+		switch v := doc.(type) {
+		case map[string]interface{}:
+			var ok bool
+			if doc, ok = v[token]; !ok {
+				return nil, conflict("member " + strconv.Quote(token) + " not found")
+			}
+		case []interface{}:
+			i, err := arrayIndex(token, len(v), false)
+			if err != nil {
+				return nil, err
+			}
+			doc = v[i]
+		default:
+			return nil, conflict("cannot traverse into a scalar value at " + strconv.Quote(token))
+		}
+	}
+	return doc, nil
+}
+
+// addValue adds the value at the given path and returns the modified document.
+func addValue(doc interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	parent, err := getValue(doc, path[:len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+	token := path[len(path)-1]
+	switch v := parent.(type) {
+	case map[string]interface{}:
+		v[token] = value
+		return doc, nil
+	case []interface{}:
+		i, err := arrayIndex(token, len(v), true)
+		if err != nil {
+			return nil, err
+		}
+		v = append(v, nil)
+		copy(v[i+1:], v[i:])
+		v[i] = value
+		return replaceParent(doc, path[:len(path)-1], v)
+	}
+	return nil, conflict("cannot add a member to a scalar value")
+}
+
+// removeValue removes the value at the given path and returns the modified document together with the removed value.
+func removeValue(doc interface{}, path []string) (interface{}, interface{}, error) {
+	if len(path) == 0 {
+		return nil, doc, nil
+	}
+	parent, err := getValue(doc, path[:len(path)-1])
+	if err != nil {
+		return nil, nil, err
+	}
+	token := path[len(path)-1]
+	switch v := parent.(type) {
+	case map[string]interface{}:
+		old, ok := v[token]
+		if !ok {
+			return nil, nil, conflict("member " + strconv.Quote(token) + " not found")
+		}
+		delete(v, token)
+		return doc, old, nil
+	case []interface{}:
+		i, err := arrayIndex(token, len(v), false)
+		if err != nil {
+			return nil, nil, err
+		}
+		old := v[i]
+		v = append(v[:i:i], v[i+1:]...)
+		doc, err = replaceParent(doc, path[:len(path)-1], v)
+		return doc, old, err
+	}
+	return nil, nil, conflict("cannot remove a member from a scalar value")
+}
+
+// replaceParent stores a resized array back at the given path, as slices cannot be modified in place.
+func replaceParent(doc interface{}, path []string, array []interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return array, nil
+	}
+	parent, err := getValue(doc, path[:len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+	token := path[len(path)-1]
+	switch v := parent.(type) {
+	case map[string]interface{}:
+		v[token] = array
+	case []interface{}:
+		i, _ := arrayIndex(token, len(v), false)
+		v[i] = array
+	}
+	return doc, nil
+}
+
+// copyValue returns a deep copy of a generic JSON value.
+func copyValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			m[k] = copyValue(e)
+		}
+		return m
+	case []interface{}:
+		a := make([]interface{}, len(v))
+		for i, e := range v {
+			a[i] = copyValue(e)
+		}
+		return a
+	}
+	return value
+}
+
+// jsonEqual checks if two generic JSON values are equal, comparing numbers by their numeric value.
+func jsonEqual(a, b interface{}) bool {
+	switch va := a.(type) {
+	case map[string]interface{}:
+		vb, ok := b.(map[string]interface{})
+		if !ok || len(va) != len(vb) {
+			return false
+		}
+		for k, e := range va {
+			if f, ok := vb[k]; !ok || !jsonEqual(e, f) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		vb, ok := b.([]interface{})
+		if !ok || len(va) != len(vb) {
+			return false
+		}
+		for i := range va {
+			if !jsonEqual(va[i], vb[i]) {
+				return false
+			}
+		}
+		return true
+	case json.Number:
+		vb, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		fa, errA := va.Float64()
+		fb, errB := vb.Float64()
+		return errA == nil && errB == nil && fa == fb
+	}
+	return a == b
+}
+
+// mergePatch applies a JSON Merge Patch to the target as described in RFC 7386.
+func mergePatch(target, patch interface{}) interface{} {
+	p, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	t, ok := target.(map[string]interface{})
+	if !ok {
+		t = map[string]interface{}{}
+	}
+	for k, v := range p {
+		if v == nil {
+			delete(t, k)
+		} else {
+			t[k] = mergePatch(t[k], v)
+		}
+	}
+	return t
+}
@@ -0,0 +1,113 @@
+package routing
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+type patchAddress struct {
+	City string `json:"city"`
+	Zip  string `json:"zip,omitempty"`
+}
+
+type patchUser struct {
+	Name    string        `json:"name"`
+	Age     int           `json:"age"`
+	Tags    []string      `json:"tags"`
+	Address *patchAddress `json:"address,omitempty"`
+}
+
+func newPatchUser() patchUser {
+	return patchUser{
+		Name:    "john",
+		Age:     30,
+		Tags:    []string{"a", "b"},
+		Address: &patchAddress{City: "Paris", Zip: "75001"},
+	}
+}
+
+func TestJSONPatch(t *testing.T) {
+	tests := []struct {
+		tag      string
+		patch    string
+		status   int
+		expected patchUser
+	}{
+		{"t1", `[{"op":"replace","path":"/name","value":"jane"},{"op":"add","path":"/tags/1","value":"x"}]`, 0,
+			patchUser{"jane", 30, []string{"a", "x", "b"}, &patchAddress{"Paris", "75001"}}},
+		{"t2", `[{"op":"remove","path":"/address/zip"},{"op":"add","path":"/tags/-","value":"c"}]`, 0,
+			patchUser{"john", 30, []string{"a", "b", "c"}, &patchAddress{"Paris", ""}}},
+		{"t3", `[{"op":"test","path":"/age","value":30.0},{"op":"move","from":"/tags/0","path":"/tags/1"}]`, 0,
+			patchUser{"john", 30, []string{"b", "a"}, &patchAddress{"Paris", "75001"}}},
+		{"t4", `[{"op":"copy","from":"/address/city","path":"/name"},{"op":"remove","path":"/address"}]`, 0,
+			patchUser{"Paris", 30, []string{"a", "b"}, nil}},
+		{"t5", `[{"op":"replace","path":"/name","value":"jane"},{"op":"test","path":"/age","value":31}]`,
+			fasthttp.StatusConflict, newPatchUser()},
+		{"t6", `[{"op":"remove","path":"/unknown"}]`, fasthttp.StatusConflict, newPatchUser()},
+		{"t7", `[{"op":"add","path":"/tags/5","value":"x"}]`, fasthttp.StatusConflict, newPatchUser()},
+		{"t8", `[{"op":"append","path":"/tags"}]`, fasthttp.StatusUnprocessableEntity, newPatchUser()},
+		{"t9", `[{"op":"replace","path":"/age","value":"old"}]`, fasthttp.StatusUnprocessableEntity, newPatchUser()},
+		{"t10", `[{"op":"add","path":"name","value":"x"}]`, fasthttp.StatusUnprocessableEntity, newPatchUser()},
+		{"t11", `{"op":"add"}`, fasthttp.StatusBadRequest, newPatchUser()},
+	}
+	for _, test := range tests {
+		user := newPatchUser()
+		c := newReadContext(MIME_JSON_PATCH, test.patch)
+		c.Request.Header.SetMethod("PATCH")
+		err := c.ApplyPatch(&user)
+		if test.status == 0 {
+			assert.Nil(t, err, test.tag)
+		} else if assert.NotNil(t, err, test.tag) {
+			assert.Equal(t, test.status, err.(HTTPError).StatusCode(), test.tag)
+		}
+		assert.Equal(t, test.expected, user, test.tag)
+	}
+}
+
+func TestMergePatch(t *testing.T) {
+	user := newPatchUser()
+	c := newReadContext(MIME_MERGE_PATCH, `{"name":"jane","tags":["z"],"address":{"zip":null}}`)
+	c.Request.Header.SetMethod("PATCH")
+	assert.Nil(t, c.Read(&user))
+	assert.Equal(t, patchUser{"jane", 30, []string{"z"}, &patchAddress{"Paris", ""}}, user)
+
+	c = newReadContext(MIME_MERGE_PATCH, `{"address":null}`)
+	assert.Nil(t, c.ApplyPatch(&user))
+	assert.Nil(t, user.Address)
+
+	c = newReadContext(MIME_MERGE_PATCH, `{"age":"x"}`)
+	assertHTTPError(t, c.ApplyPatch(&user), fasthttp.StatusUnprocessableEntity, "")
+
+	c = newReadContext(MIME_MERGE_PATCH, `{"age":`)
+	assertHTTPError(t, c.ApplyPatch(&user), fasthttp.StatusBadRequest, "")
+
+	c = newReadContext(MIME_JSON, `{"age":1}`)
+	assertHTTPError(t, c.ApplyPatch(&user), fasthttp.StatusUnsupportedMediaType, "")
+}
+
+type patchAccount struct {
+	Name     string            `json:"name"`
+	Password string            `json:"-"`
+	Labels   map[string]string `json:"labels,omitempty"`
+	Owner    *patchAccount     `json:"owner,omitempty"`
+	secret   string
+}
+
+func TestPatchPreservesHiddenFields(t *testing.T) {
+	account := patchAccount{Name: "al", Password: "hash", secret: "s",
+		Labels: map[string]string{"a": "1", "b": "2"}, Owner: &patchAccount{Name: "root", Password: "root-hash"}}
+	c := newReadContext(MIME_MERGE_PATCH, `{"name":"bob","labels":{"a":null},"owner":{"name":"admin"}}`)
+	assert.Nil(t, c.ApplyPatch(&account))
+	assert.Equal(t, patchAccount{Name: "bob", Password: "hash", secret: "s",
+		Labels: map[string]string{"b": "2"}, Owner: &patchAccount{Name: "admin", Password: "root-hash"}}, account)
+
+	c = newReadContext(MIME_JSON_PATCH, `[{"op":"remove","path":"/owner"},{"op":"remove","path":"/labels"}]`)
+	assert.Nil(t, c.ApplyPatch(&account))
+	assert.Equal(t, patchAccount{Name: "bob", Password: "hash", secret: "s"}, account)
+
+	c = newReadContext(MIME_MERGE_PATCH, `{"name":1}`)
+	assertHTTPError(t, c.ApplyPatch(&account), fasthttp.StatusUnprocessableEntity, "")
+	assert.Equal(t, patchAccount{Name: "bob", Password: "hash", secret: "s"}, account)
+}
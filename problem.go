@@ -0,0 +1,172 @@
+package routing
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"reflect"
+	"sort"
+
+	"github.com/jackwhelpton/fasthttp-routing/v2/internal/accept"
+	"github.com/valyala/fasthttp"
+)
+
+// problemNamespace is the XML namespace of problem details objects as defined in RFC 7807, Appendix A.
+const problemNamespace = "urn:ietf:rfc:7807"
+
+// Problem represents a problem details object as defined in RFC 7807.
+// Problem implements HTTPError, so it can be returned by handlers like any other HTTP error.
+// It is rendered as "application/problem+json" or "application/problem+xml" according to the "Accept"
+// header of the request (see WriteProblem).
+type Problem struct {
+	// Type is a URI reference identifying the problem type. When empty, "about:blank" is assumed.
+	Type string
+	// Title is a short, human-readable summary of the problem type.
+	Title string
+	// Status is the HTTP status code generated for this occurrence of the problem.
+	Status int
+	// Detail is a human-readable explanation specific to this occurrence of the problem.
+	Detail string
+	// Instance is a URI reference identifying the specific occurrence of the problem.
+	Instance string
+	// Extensions holds additional members of the problem details object.
+	Extensions map[string]interface{}
+}
+
+// NewProblem creates a new Problem with the given HTTP status code.
+// The title is set to the status message returned by fasthttp.StatusMessage(),
+// and the detail is set to the optional detail parameter.
+func NewProblem(status int, detail ...string) *Problem {
+	p := &Problem{
+		Title:  fasthttp.StatusMessage(status),
+		Status: status,
+	}
+	if len(detail) > 0 {
+		p.Detail = detail[0]
+	}
+	return p
+}
+
+// With sets an extension member of the problem and returns the problem itself.
+func (p *Problem) With(name string, value interface{}) *Problem {
+	if p.Extensions == nil {
+		p.Extensions = make(map[string]interface{})
+	}
+	p.Extensions[name] = value
+	return p
+}
+
+// Error returns the detail of the problem, or its title if there is no detail.
+func (p *Problem) Error() string {
+	if p.Detail != "" {
+		return p.Detail
+	}
+	return p.Title
+}
+
+// StatusCode returns the HTTP status code.
+func (p *Problem) StatusCode() int {
+	return p.Status
+}
+
+// members returns the standard members of the problem that are set.
+func (p *Problem) members() map[string]interface{} {
+	m := make(map[string]interface{}, 5+len(p.Extensions))
+	for k, v := range p.Extensions {
+		m[k] = v
+	}
+	for k, v := range map[string]string{"type": p.Type, "title": p.Title, "detail": p.Detail, "instance": p.Instance} {
+		if v != "" {
+			m[k] = v
+		} else {
+			delete(m, k)
+		}
+	}
+	if p.Status != 0 {
+		m["status"] = p.Status
+	} else {
+		delete(m, "status")
+	}
+	return m
+}
+
+// MarshalJSON encodes the problem as a JSON object, with the extension members at the same level as the standard ones.
+func (p *Problem) MarshalJSON() ([]byte, error) {
+	return json.Marshal(p.members())
+}
+
+// MarshalXML encodes the problem as described in RFC 7807, Appendix A.
+// Array and slice extension values are encoded as sequences of "i" elements.
+func (p *Problem) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	start = xml.StartElement{Name: xml.Name{Space: problemNamespace, Local: "problem"}}
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	m := p.members()
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := encodeProblemMember(e, name, m[name]); err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(start.End())
+}
+
+func encodeProblemMember(e *xml.Encoder, name string, value interface{}) error {
+	elem := xml.StartElement{Name: xml.Name{Local: name}}
+	rv := reflect.ValueOf(value)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array || rv.Type().Elem().Kind() == reflect.Uint8 {
+		return e.EncodeElement(value, elem)
+	}
+	if err := e.EncodeToken(elem); err != nil {
+		return err
+	}
+	for i := 0; i < rv.Len(); i++ {
+		if err := e.EncodeElement(rv.Index(i).Interface(), xml.StartElement{Name: xml.Name{Local: "i"}}); err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(elem.End())
+}
+
+// WriteProblem writes the problem to the response and sets the HTTP status code accordingly.
+// The problem is encoded as "application/problem+xml" if the "Accept" header of the request prefers it or
+// "application/xml" over their JSON counterparts (see content.NegotiateContentType), and as "application/problem+json" otherwise.
+func WriteProblem(ctx *fasthttp.RequestCtx, p *Problem) error {
+	var (
+		body []byte
+		err  error
+	)
+	if acceptsXML(string(ctx.Request.Header.Peek("Accept"))) {
+		if body, err = xml.Marshal(p); err != nil {
+			return err
+		}
+		ctx.SetContentType(MIME_PROBLEM_XML + "; charset=UTF-8")
+	} else {
+		buf := new(bytes.Buffer)
+		enc := json.NewEncoder(buf)
+		enc.SetEscapeHTML(false)
+		if err = enc.Encode(p.members()); err != nil {
+			return err
+		}
+		body = buf.Bytes()
+		ctx.SetContentType(MIME_PROBLEM_JSON)
+	}
+	if p.Status != 0 {
+		ctx.SetStatusCode(p.Status)
+	}
+	ctx.SetBody(body)
+	return nil
+}
+
+// problemOffers lists the media types a problem is rendered as, each followed by the generic type of its encoding.
+var problemOffers = []string{MIME_PROBLEM_JSON, MIME_JSON, MIME_PROBLEM_XML, MIME_XML}
+
+// acceptsXML checks if the given "Accept" header value prefers an XML representation of a problem to a JSON one.
+func acceptsXML(header string) bool {
+	return accept.Negotiate(header, problemOffers) >= 2
+}
@@ -0,0 +1,103 @@
+package routing
+
+import (
+	"encoding/xml"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func TestNewProblem(t *testing.T) {
+	p := NewProblem(fasthttp.StatusNotFound)
+	assert.Equal(t, fasthttp.StatusNotFound, p.StatusCode())
+	assert.Equal(t, "Not Found", p.Title)
+	assert.Equal(t, "Not Found", p.Error())
+
+	p = NewProblem(fasthttp.StatusForbidden, "not enough credit").With("balance", 30)
+	assert.Equal(t, "not enough credit", p.Error())
+	assert.Equal(t, 30, p.Extensions["balance"])
+
+	var err error = p
+	_, ok := err.(HTTPError)
+	assert.True(t, ok)
+}
+
+func TestProblemMarshal(t *testing.T) {
+	p := &Problem{
+		Type:     "https://example.com/probs/out-of-credit",
+		Title:    "You do not have enough credit.",
+		Status:   fasthttp.StatusForbidden,
+		Detail:   "Your current balance is 30, but that costs 50.",
+		Instance: "/account/12345/msgs/abc",
+	}
+	p.With("balance", 30).With("accounts", []string{"/account/12345", "/account/67890"}).With("title", "ignored")
+
+	data, err := p.MarshalJSON()
+	assert.Nil(t, err)
+	assert.Equal(t, `{"accounts":["/account/12345","/account/67890"],"balance":30,"detail":"Your current balance is 30, but that costs 50.","instance":"/account/12345/msgs/abc","status":403,"title":"You do not have enough credit.","type":"https://example.com/probs/out-of-credit"}`, string(data))
+
+	data, err = xml.Marshal(p)
+	assert.Nil(t, err)
+	assert.Equal(t, `<problem xmlns="urn:ietf:rfc:7807"><accounts><i>/account/12345</i><i>/account/67890</i></accounts><balance>30</balance><detail>Your current balance is 30, but that costs 50.</detail><instance>/account/12345/msgs/abc</instance><status>403</status><title>You do not have enough credit.</title><type>https://example.com/probs/out-of-credit</type></problem>`, string(data))
+
+	data, err = (&Problem{}).MarshalJSON()
+	assert.Nil(t, err)
+	assert.Equal(t, `{}`, string(data))
+}
+
+func TestWriteProblem(t *testing.T) {
+	tests := []struct {
+		tag         string
+		accept      string
+		contentType string
+		body        string
+	}{
+		{"t1", "", MIME_PROBLEM_JSON, `{"detail":"<id> is missing","status":400,"title":"Bad Request"}` + "\n"},
+		{"t2", "application/json", MIME_PROBLEM_JSON, `{"detail":"<id> is missing","status":400,"title":"Bad Request"}` + "\n"},
+		{"t3", "application/problem+xml", MIME_PROBLEM_XML + "; charset=UTF-8", `<problem xmlns="urn:ietf:rfc:7807"><detail>&lt;id&gt; is missing</detail><status>400</status><title>Bad Request</title></problem>`},
+		{"t4", "text/html, application/xml;q=0.9, */*;q=0.8", MIME_PROBLEM_XML + "; charset=UTF-8", ""},
+		{"t5", "application/xml;q=0.5, application/json", MIME_PROBLEM_JSON, ""},
+		{"t6", "text/*;q=0.1, application/json;q=0.2", MIME_PROBLEM_JSON, ""},
+		{"t7", "application/xml;q=0, */*", MIME_PROBLEM_JSON, ""},
+		{"t8", "text/*", MIME_PROBLEM_JSON, ""},
+		{"t9", "application/*+xml, application/json;q=0.5", MIME_PROBLEM_XML + "; charset=UTF-8", ""},
+		{"t10", "application/problem+json;q=0.5, application/*;q=0.8", MIME_PROBLEM_JSON, ""},
+	}
+	for _, test := range tests {
+		var ctx fasthttp.RequestCtx
+		ctx.Request.Header.Set("Accept", test.accept)
+		err := WriteProblem(&ctx, NewProblem(fasthttp.StatusBadRequest, "<id> is missing"))
+		assert.Nil(t, err, test.tag)
+		assert.Equal(t, fasthttp.StatusBadRequest, ctx.Response.StatusCode(), test.tag)
+		assert.Equal(t, test.contentType, string(ctx.Response.Header.ContentType()), test.tag)
+		if test.body != "" {
+			assert.Equal(t, test.body, string(ctx.Response.Body()), test.tag)
+		}
+	}
+}
+
+func TestRouterProblem(t *testing.T) {
+	router := New()
+	router.Get("/users", func(c *Context) error {
+		return NewProblem(fasthttp.StatusUnauthorized, "token expired")
+	})
+	router.Get("/posts", func(c *Context) error {
+		return errors.New("abc")
+	})
+
+	var ctx fasthttp.RequestCtx
+	ctx.Request.Header.SetMethod("GET")
+	ctx.Request.SetRequestURI("/users")
+	router.HandleRequest(&ctx)
+	assert.Equal(t, fasthttp.StatusUnauthorized, ctx.Response.StatusCode())
+	assert.Equal(t, MIME_PROBLEM_JSON, string(ctx.Response.Header.ContentType()))
+	assert.Equal(t, `{"detail":"token expired","status":401,"title":"Unauthorized"}`+"\n", string(ctx.Response.Body()))
+
+	ctx.Response.Reset()
+	ctx.Request.SetRequestURI("/posts")
+	router.HandleRequest(&ctx)
+	assert.Equal(t, fasthttp.StatusInternalServerError, ctx.Response.StatusCode())
+	assert.Equal(t, "Internal Server Error", string(ctx.Response.Body()))
+}
@@ -3,12 +3,12 @@ package routing
 import (
 	"bytes"
 	"encoding"
-	"encoding/json"
-	"encoding/xml"
 	"errors"
+	"mime/multipart"
 	"reflect"
 	"strconv"
 
+	"github.com/jackwhelpton/fasthttp-routing/v2/internal/codec"
 	"github.com/valyala/fasthttp"
 )
 
@@ -20,6 +20,14 @@ const (
 	MIME_HTML           = "text/html"
 	MIME_FORM           = "application/x-www-form-urlencoded"
 	MIME_MULTIPART_FORM = "multipart/form-data"
+	MIME_JSON_PATCH     = "application/json-patch+json"
+	MIME_MERGE_PATCH    = "application/merge-patch+json"
+	MIME_PROBLEM_JSON   = "application/problem+json"
+	MIME_PROBLEM_XML    = "application/problem+xml"
+	MIME_MSGPACK        = "application/msgpack"
+	MIME_MSGPACK2       = "application/x-msgpack"
+	MIME_CBOR           = "application/cbor"
+	MIME_JSONAPI        = "application/vnd.api+json"
 )
 
 var (
@@ -43,6 +51,12 @@ var (
 		MIME_JSON:           &JSONDataReader{},
 		MIME_XML:            &XMLDataReader{},
 		MIME_XML2:           &XMLDataReader{},
+		MIME_JSON_PATCH:     &JSONPatchDataReader{},
+		MIME_MERGE_PATCH:    &MergePatchDataReader{},
+		MIME_MSGPACK:        &MsgPackDataReader{},
+		MIME_MSGPACK2:       &MsgPackDataReader{},
+		MIME_CBOR:           &CBORDataReader{},
+		MIME_JSONAPI:        &JSONAPIDataReader{},
 	}
 	// DefaultFormDataReader is the reader used when there is no matching reader in DataReaders
 	// or if the current request is a GET request.
@@ -50,54 +64,120 @@ var (
 )
 
 // JSONDataReader reads the request body as JSON-formatted data.
-type JSONDataReader struct{}
+// Malformed data results in a 400 HTTP error reporting the line and column where the problem was found.
+type JSONDataReader struct {
+	DecodeOptions
+}
 
 func (r *JSONDataReader) Read(ctx *fasthttp.RequestCtx, data interface{}) error {
-	return json.Unmarshal(ctx.PostBody(), data)
+	return r.decode(ctx.PostBody(), data)
 }
 
 // XMLDataReader reads the request body as XML-formatted data.
-type XMLDataReader struct{}
+// Malformed data results in a 400 HTTP error reporting the line and column where the problem was found.
+type XMLDataReader struct {
+	DecodeOptions
+}
 
 func (r *XMLDataReader) Read(ctx *fasthttp.RequestCtx, data interface{}) error {
-	return xml.Unmarshal(ctx.PostBody(), data)
+	return r.decode(ctx.PostBody(), data, xmlCharsetReader(ctx))
+}
+
+// MsgPackDataReader reads the request body as MessagePack-encoded data.
+// Struct fields are matched against the map keys as JSONDataReader matches them against JSON object keys,
+// using the `json` struct tags. Malformed data results in a 400 HTTP error reporting the offset where the problem was found.
+type MsgPackDataReader struct {
+	DecodeOptions
+}
+
+func (r *MsgPackDataReader) Read(ctx *fasthttp.RequestCtx, data interface{}) error {
+	return r.decodeBinary(ctx.PostBody(), data, codec.UnmarshalMsgPack)
+}
+
+// CBORDataReader reads the request body as CBOR-encoded data (RFC 8949).
+// Struct fields are matched against the map keys as JSONDataReader matches them against JSON object keys,
+// using the `json` struct tags. Malformed data results in a 400 HTTP error reporting the offset where the problem was found.
+type CBORDataReader struct {
+	DecodeOptions
+}
+
+func (r *CBORDataReader) Read(ctx *fasthttp.RequestCtx, data interface{}) error {
+	return r.decodeBinary(ctx.PostBody(), data, codec.UnmarshalCBOR)
+}
+
+// JSONAPIDataReader reads the request body as a JSON:API document (https://jsonapi.org) whose primary data is
+// a resource object or an array of resource objects. The data must be a pointer to a struct, or to a slice of structs,
+// mapped to resource objects with `jsonapi` struct tags (see content.JSONAPIDataWriter). Related resources are populated
+// with their identifiers only.
+// A resource object whose type does not match the struct results in a 409 HTTP error, and a malformed document
+// results in a 400 HTTP error.
+type JSONAPIDataReader struct {
+	DecodeOptions
+}
+
+func (r *JSONAPIDataReader) Read(ctx *fasthttp.RequestCtx, data interface{}) error {
+	return r.decode(ctx.PostBody(), data)
 }
 
 // FormDataReader reads the query parameters and request body as form data.
+// For multipart requests, the uploaded files are populated as well (see ReadMultipartFormData).
 type FormDataReader struct{}
 
 func (r *FormDataReader) Read(ctx *fasthttp.RequestCtx, data interface{}) error {
 	f := make(map[string][]string)
+	var files map[string][]*multipart.FileHeader
 	if ctx.IsPost() || ctx.IsPut() || bytes.Equal(ctx.Method(), strPatch) {
 		ctx.PostArgs().VisitAll(func(key, value []byte) {
 			k := string(key)
 			f[k] = append(f[k], string(value))
 		})
+		if len(ctx.Request.Header.MultipartFormBoundary()) > 0 {
+			mf, err := ctx.MultipartForm()
+			if err != nil {
+				return WrapHTTPError(fasthttp.StatusBadRequest, err, "malformed multipart form data")
+			}
+			for k, v := range mf.Value {
+				f[k] = append(f[k], v...)
+			}
+			files = mf.File
+		}
 	}
 	ctx.QueryArgs().VisitAll(func(key, value []byte) {
 		k := string(key)
 		f[k] = append(f[k], string(value))
 	})
-	return ReadFormData(f, data)
+	rv, err := formTarget(data)
+	if err != nil {
+		return err
+	}
+	return readForm(f, files, "", rv)
 }
 
 const formTag = "form"
 
 // ReadFormData populates the data variable with the data from the given form values.
 func ReadFormData(form map[string][]string, data interface{}) error {
+	rv, err := formTarget(data)
+	if err != nil {
+		return err
+	}
+	return readForm(form, nil, "", rv)
+}
+
+// formTarget checks that data is a pointer to a struct and returns the struct value.
+func formTarget(data interface{}) (reflect.Value, error) {
 	rv := reflect.ValueOf(data)
 	if rv.Kind() != reflect.Ptr || rv.IsNil() {
-		return errors.New("data must be a pointer")
+		return rv, errors.New("data must be a pointer")
 	}
 	rv = indirect(rv)
 	if rv.Kind() != reflect.Struct {
-		return errors.New("data must be a pointer to a struct")
+		return rv, errors.New("data must be a pointer to a struct")
 	}
-
-	return readForm(form, "", rv)
+	return rv, nil
 }
 
-func readForm(form map[string][]string, prefix string, rv reflect.Value) error {
+func readForm(form map[string][]string, files map[string][]*multipart.FileHeader, prefix string, rv reflect.Value) error {
 	rv = indirect(rv)
 	rt := rv.Type()
 	n := rt.NumField()
@@ -123,6 +203,13 @@ func readForm(form map[string][]string, prefix string, rv reflect.Value) error {
 			name = prefix + "." + name
 		}
 
+		if isFileField(field.Type) {
+			if err := readFormFiles(files, name, field, rv.Field(i)); err != nil {
+				return err
+			}
+			continue
+		}
+
 		// check if type implements a known type, like encoding.TextUnmarshaler
 		if ok, err := readFormFieldKnownType(form, name, rv.Field(i)); err != nil {
 			return err
@@ -140,7 +227,7 @@ func readForm(form map[string][]string, prefix string, rv reflect.Value) error {
 		if name == "" {
 			name = prefix
 		}
-		if err := readForm(form, name, rv.Field(i)); err != nil {
+		if err := readForm(form, files, name, rv.Field(i)); err != nil {
 			return err
 		}
 	}
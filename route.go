@@ -28,13 +28,11 @@ func (r *Route) Name(name string) *Route {
 }
 
 // Tag associates some custom data with the route.
+// The data of a route with multiple methods (see RouteGroup.To) is also associated with the route of each method.
 func (r *Route) Tag(value interface{}) *Route {
-	if len(r.routes) > 0 {
-		// this route is a composite one (a path with multiple methods)
-		for _, route := range r.routes {
-			route.Tag(value)
-		}
-		return r
+	// a composite route (a path with multiple methods) tags the route of each method as well
+	for _, route := range r.routes {
+		route.Tag(value)
 	}
 	if r.tags == nil {
 		r.tags = []interface{}{}
@@ -113,16 +111,28 @@ func (r *Route) To(methods string, handlers ...Handler) *Route {
 // The parameters should be given in the sequence of name1, value1, name2, value2, and so on.
 // If a parameter in the route is not provided a value, the parameter token will remain in the resulting URL.
 // The method will perform URL encoding for all given parameter values.
+//
+// If the route has no "format" parameter, a "format" value listed in the router's Extensions is appended to the URL
+// as an extension, as in URL("id", 42, "format", "csv") giving "/reports/42.csv". Other "format" values are ignored.
 func (r *Route) URL(pairs ...interface{}) (s string) {
 	s = r.template
+	format := ""
 	for i := 0; i < len(pairs); i++ {
 		name := fmt.Sprintf("<%v>", pairs[i])
 		value := ""
 		if i < len(pairs)-1 {
 			value = url.QueryEscape(fmt.Sprint(pairs[i+1]))
 		}
+		if name == "<format>" && !strings.Contains(s, name) {
+			if _, ok := r.group.router.Extensions[value]; ok {
+				format = value
+			}
+		}
 		s = strings.Replace(s, name, value, -1)
 	}
+	if format != "" {
+		s += "." + format
+	}
 	return
 }
 
@@ -22,7 +22,7 @@ func newMockStore() *mockStore {
 }
 
 func (s *mockStore) Add(key string, data interface{}) int {
-	for _, handler := range data.([]Handler) {
+	for _, handler := range data.(*routeEntry).handlers {
 		handler(nil)
 	}
 	return s.store.Add(key, data)
@@ -66,11 +66,22 @@ func TestRouteURL(t *testing.T) {
 	assert.Equal(t, "/admin/users/123/profile/", r.URL("id", 123, "action", "profile", ""))
 	assert.Equal(t, "/admin/users/123/profile/", r.URL("id", 123, "action", "profile", "", "xyz/abc"))
 	assert.Equal(t, "/admin/users/123/a%2C%3C%3E%3F%23/", r.URL("id", 123, "action", "a,<>?#"))
+
+	// formats are appended as extensions when they are listed in the router's extensions
+	r = group.newRoute("GET", "/reports/<id>")
+	assert.Equal(t, "/admin/reports/42", r.URL("id", 42, "format", "csv"))
+	router.Extensions = map[string]string{"csv": "text/csv"}
+	assert.Equal(t, "/admin/reports/42.csv", r.URL("id", 42, "format", "csv"))
+	assert.Equal(t, "/admin/reports/42", r.URL("id", 42, "format", ""))
+	assert.Equal(t, "/admin/reports/42", r.URL("id", 42, "format", "exe"))
+	assert.Equal(t, "/admin/reports/42", r.URL("id", 42, "format", "csv/../x"))
+	r = group.newRoute("GET", "/reports/<id>/<format>")
+	assert.Equal(t, "/admin/reports/42/csv", r.URL("id", 42, "format", "csv"))
 }
 
 func newHandler(tag string, buf *bytes.Buffer) Handler {
 	return func(*Context) error {
-		fmt.Fprintf(buf, tag)
+		fmt.Fprint(buf, tag)
 		return nil
 	}
 }
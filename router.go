@@ -29,13 +29,25 @@ type (
 		RouteGroup
 		IgnoreTrailingSlash bool // whether to ignore trailing slashes in the end of the request URL
 		UseEscapedPath      bool // whether to use encoded URL instead of decoded URL to match routes
-		pool                sync.Pool
-		routes              []*Route
-		namedRoutes         map[string]*Route
-		stores              map[string]routeStore
-		maxParams           int
-		notFound            []Handler
-		notFoundHandlers    []Handler
+		// Extensions maps the URL extensions selecting the format of a response, such as "csv", to MIME types.
+		// When set, a request path ending with one of the extensions, as in "/reports/42.csv", is matched without it
+		// if a route matches the shorter path, and the extension is available via Context.Format().
+		// A route whose path ends with the extension itself, as in "/reports/<id>.csv", takes precedence.
+		Extensions       map[string]string
+		pool             sync.Pool
+		routes           []*Route
+		namedRoutes      map[string]*Route
+		stores           map[string]routeStore
+		extStores        map[string]routeStore // the routes ending with a literal extension, by method and extension
+		maxParams        int
+		notFound         []Handler
+		notFoundHandlers []Handler
+	}
+
+	// routeEntry is the data stored in a routeStore for each route.
+	routeEntry struct {
+		route    *Route
+		handlers []Handler
 	}
 
 	// routeStore stores route paths and the corresponding handlers.
@@ -64,6 +76,7 @@ func New() *Router {
 	r := &Router{
 		namedRoutes: make(map[string]*Route),
 		stores:      make(map[string]routeStore),
+		extStores:   make(map[string]routeStore),
 	}
 	r.RouteGroup = *newRouteGroup("", r, make([]Handler, 0))
 	r.NotFound(MethodNotAllowedHandler, NotFoundHandler)
@@ -80,14 +93,34 @@ func New() *Router {
 func (r *Router) HandleRequest(ctx *fasthttp.RequestCtx) {
 	c := r.pool.Get().(*Context)
 	c.init(ctx)
+	path := string(ctx.Path())
+	if r.UseEscapedPath {
+		u, _ := url.Parse(path)
+		path = u.EscapedPath()
+	}
+	method := string(ctx.Method())
+	if p, ext := r.splitExtension(path); ext != "" {
+		// a route ending with the literal extension, such as "/files/<id>.json", takes precedence over
+		// the route matching the path without the extension
+		if c.route, c.handlers, c.pnames = r.findExtension(method, r.normalizeRequestPath(p), ext, c.pvalues); c.route == nil {
+			c.route, c.handlers, c.pnames = r.find(method, r.normalizeRequestPath(p), c.pvalues)
+		}
+		if c.route != nil {
+			c.format = ext
+		}
+	}
+	if c.route == nil {
+		c.route, c.handlers, c.pnames = r.find(method, r.normalizeRequestPath(path), c.pvalues)
+	}
 	if r.UseEscapedPath {
-		u, _ := url.Parse(string(ctx.Path()))
-		c.handlers, c.pnames = r.find(string(ctx.Method()), r.normalizeRequestPath(u.EscapedPath()), c.pvalues)
 		for i, v := range c.pvalues {
 			c.pvalues[i], _ = url.QueryUnescape(v)
 		}
+	}
+	if c.route != nil {
+		c.codecs = c.route.group.Codecs()
 	} else {
-		c.handlers, c.pnames = r.find(string(ctx.Method()), r.normalizeRequestPath(string(ctx.Path())), c.pvalues)
+		c.codecs = r.codecs
 	}
 	if err := c.Next(); err != nil {
 		r.handleError(c, err)
@@ -122,7 +155,7 @@ func (r *Router) NotFound(handlers ...Handler) {
 // Find determines the handlers and parameters to use for a specified method and path.
 func (r *Router) Find(method, path string) (handlers []Handler, params map[string]string) {
 	pvalues := make([]string, r.maxParams)
-	handlers, pnames := r.find(method, path, pvalues)
+	_, handlers, pnames := r.find(method, path, pvalues)
 	params = make(map[string]string, len(pnames))
 	for i, n := range pnames {
 		params[n] = pvalues[i]
@@ -131,11 +164,16 @@ func (r *Router) Find(method, path string) (handlers []Handler, params map[strin
 }
 
 // handleError is the error handler for handling any unhandled errors.
+// The error is written using the data writer negotiated for the request (see Context.WriteError()).
+// If that fails, the public error message is written as plain text.
 func (r *Router) handleError(c *Context, err error) {
-	if httpError, ok := err.(HTTPError); ok {
+	if c.WriteError(err) == nil {
+		return
+	}
+	if httpError, ok := AsHTTPError(err); ok {
 		c.Error(httpError.Error(), httpError.StatusCode())
 	} else {
-		c.Error(err.Error(), fasthttp.StatusInternalServerError)
+		c.Error(fasthttp.StatusMessage(fasthttp.StatusInternalServerError), fasthttp.StatusInternalServerError)
 	}
 }
 
@@ -155,20 +193,48 @@ func (r *Router) addRoute(route *Route, handlers []Handler) {
 		path = path[:len(path)-1] + "<:.*>"
 	}
 
-	if n := store.Add(path, handlers); n > r.maxParams {
+	if n := store.Add(path, &routeEntry{route, handlers}); n > r.maxParams {
 		r.maxParams = n
 	}
+
+	// the routes ending with a literal extension are also stored without it, by method and extension, so that
+	// they can be matched before a route with a parameter in place of the last path segment, such as "/files/<id>"
+	if base, ext := splitLiteralExtension(path); ext != "" {
+		key := route.method + " " + ext
+		extStore := r.extStores[key]
+		if extStore == nil {
+			extStore = newStore()
+			r.extStores[key] = extStore
+		}
+		extStore.Add(base, &routeEntry{route, handlers})
+	}
 }
 
-func (r *Router) find(method, path string, pvalues []string) (handlers []Handler, pnames []string) {
-	var hh interface{}
+// find returns the route matching the given method and path, together with its handlers and parameter names.
+// If no route matches, a nil route and the handlers registered via NotFound are returned.
+func (r *Router) find(method, path string, pvalues []string) (route *Route, handlers []Handler, pnames []string) {
+	var entry interface{}
 	if store := r.stores[method]; store != nil {
-		hh, pnames = store.Get(path, pvalues)
+		entry, pnames = store.Get(path, pvalues)
+	}
+	if entry != nil {
+		e := entry.(*routeEntry)
+		return e.route, e.handlers, pnames
 	}
-	if hh != nil {
-		return hh.([]Handler), pnames
+	return nil, r.notFoundHandlers, pnames
+}
+
+// findExtension returns the route ending with the given literal extension that matches the given method and path
+// without the extension, together with its handlers and parameter names.
+// If no such route matches, a nil route and nil handlers are returned.
+func (r *Router) findExtension(method, path, ext string, pvalues []string) (route *Route, handlers []Handler, pnames []string) {
+	if store := r.extStores[method+" "+ext]; store != nil {
+		if entry, pnames := store.Get(path, pvalues); entry != nil {
+			e := entry.(*routeEntry)
+			return e.route, e.handlers, pnames
+		}
 	}
-	return r.notFoundHandlers, pnames
+	return nil, nil, nil
 }
 
 func (r *Router) findAllowedMethods(path string) map[string]bool {
@@ -182,6 +248,34 @@ func (r *Router) findAllowedMethods(path string) map[string]bool {
 	return methods
 }
 
+// splitExtension splits the given path into the path without its extension and the extension,
+// if the extension is listed in Extensions. Otherwise, the extension is empty.
+func (r *Router) splitExtension(path string) (string, string) {
+	if r.Extensions == nil {
+		return path, ""
+	}
+	i := strings.LastIndexByte(path, '.')
+	if i <= strings.LastIndexByte(path, '/')+1 || i == len(path)-1 {
+		return path, ""
+	}
+	ext := strings.ToLower(path[i+1:])
+	if _, ok := r.Extensions[ext]; !ok {
+		return path, ""
+	}
+	return path[:i], ext
+}
+
+// splitLiteralExtension splits the given route path into the path without its extension and the lower-cased
+// extension, if the path ends with an extension that is not part of a parameter, as in "/files/<id>.json".
+// Otherwise, the extension is empty.
+func splitLiteralExtension(path string) (string, string) {
+	i := strings.LastIndexByte(path, '.')
+	if i <= strings.LastIndexByte(path, '/') || i <= strings.LastIndexByte(path, '>') || i == len(path)-1 {
+		return path, ""
+	}
+	return path[:i], strings.ToLower(path[i+1:])
+}
+
 func (r *Router) normalizeRequestPath(path string) string {
 	if r.IgnoreTrailingSlash && len(path) > 1 && path[len(path)-1] == '/' {
 		for i := len(path) - 2; i > 0; i-- {
@@ -200,10 +294,14 @@ func NotFoundHandler(*Context) error {
 }
 
 // MethodNotAllowedHandler handles the situation when a request has matching route without matching HTTP method.
-// In this case, the handler will respond with an Allow HTTP header listing the allowed HTTP methods.
+// In this case, the handler will respond with an Allow HTTP header listing the allowed HTTP methods,
+// and return a 405 HTTP error unless the request is an OPTIONS request.
 // Otherwise, the handler will do nothing and let the next handler (usually a NotFoundHandler) to handle the problem.
 func MethodNotAllowedHandler(c *Context) error {
 	methods := c.Router().findAllowedMethods(string(c.Path()))
+	if p, ext := c.Router().splitExtension(string(c.Path())); len(methods) == 0 && ext != "" {
+		methods = c.Router().findAllowedMethods(p)
+	}
 	if len(methods) == 0 {
 		return nil
 	}
@@ -216,10 +314,10 @@ func MethodNotAllowedHandler(c *Context) error {
 	}
 	sort.Strings(ms)
 	c.Response.Header.Set("Allow", strings.Join(ms, ", "))
+	c.Abort()
 	if !bytes.Equal(c.Method(), strOptions) {
-		c.SetStatusCode(fasthttp.StatusMethodNotAllowed)
+		return NewHTTPError(fasthttp.StatusMethodNotAllowed)
 	}
-	c.Abort()
 	return nil
 }
 
@@ -106,6 +106,47 @@ func TestRouterNormalizeRequestPath(t *testing.T) {
 	}
 }
 
+func TestRouterExtensions(t *testing.T) {
+	r := New()
+	r.Extensions = map[string]string{"csv": "text/csv", "json": "application/json"}
+	handler := func(c *Context) error {
+		return c.Write(c.Param("id") + " " + c.Format())
+	}
+	r.Get("/reports/<id>", handler)
+	r.Get("/files/<id>", handler)
+	r.Get("/files/<id>.json", func(c *Context) error {
+		return c.Write("explicit " + c.Param("id") + " " + c.Format())
+	})
+	r.Post("/users", handler)
+
+	tests := []struct {
+		tag    string
+		method string
+		path   string
+		status int
+		body   string
+	}{
+		{"t1", "GET", "/reports/42.csv", fasthttp.StatusOK, "42 csv"},
+		{"t2", "GET", "/reports/42.CSV", fasthttp.StatusOK, "42 csv"},
+		{"t3", "GET", "/reports/42", fasthttp.StatusOK, "42 "},
+		{"t4", "GET", "/reports/42.pdf", fasthttp.StatusOK, "42.pdf "},
+		{"t5", "GET", "/reports/42.csv.json", fasthttp.StatusOK, "42.csv json"},
+		{"t6", "GET", "/reports/.csv", fasthttp.StatusOK, ".csv "},
+		{"t7", "GET", "/files/a.json", fasthttp.StatusOK, "explicit a json"},
+		{"t7b", "GET", "/files/a.csv", fasthttp.StatusOK, "a csv"},
+		{"t8", "POST", "/users.json", fasthttp.StatusOK, " json"},
+		{"t9", "GET", "/users.json", fasthttp.StatusMethodNotAllowed, "Method Not Allowed"},
+	}
+	for _, test := range tests {
+		var ctx fasthttp.RequestCtx
+		ctx.Request.Header.SetMethod(test.method)
+		ctx.Request.SetRequestURI(test.path)
+		r.HandleRequest(&ctx)
+		assert.Equal(t, test.status, ctx.Response.StatusCode(), test.tag)
+		assert.Equal(t, test.body, string(ctx.Response.Body()), test.tag)
+	}
+}
+
 func TestRouterHandleError(t *testing.T) {
 	r := New()
 	c := NewContext(&fasthttp.RequestCtx{})
@@ -0,0 +1,153 @@
+package sse
+
+import (
+	"strconv"
+	"sync"
+
+	"github.com/jackwhelpton/fasthttp-routing/v2"
+)
+
+// DefaultHistorySize is the default number of events kept by a Hub for the clients resuming their streams.
+const DefaultHistorySize = 100
+
+// DefaultBufferSize is the default number of events that can be queued for a subscriber of a Hub.
+const DefaultBufferSize = 64
+
+// HubOptions configures a Hub.
+type HubOptions struct {
+	// HistorySize is the number of recent events kept so that the clients reconnecting with a "Last-Event-ID"
+	// header receive the events they have missed. Defaults to DefaultHistorySize. A negative value disables history.
+	HistorySize int
+	// BufferSize is the number of events that can be queued for a subscriber. A subscriber whose queue is full
+	// is disconnected, so that a slow client does not hold up the others; the client then reconnects and resumes
+	// from the last event it has received. Defaults to DefaultBufferSize.
+	BufferSize int
+}
+
+// Hub broadcasts events to many subscribers.
+//
+//     hub := sse.NewHub()
+//     router.Get("/updates", hub.Handler())
+//
+//     // elsewhere in the application
+//     hub.Publish(sse.Event{Event: "price", Data: price})
+type Hub struct {
+	options HubOptions
+
+	mu          sync.Mutex
+	seq         uint64
+	history     []Event
+	subscribers map[chan Event]struct{}
+}
+
+// NewHub creates a new Hub.
+func NewHub(opts ...HubOptions) *Hub {
+	h := &Hub{subscribers: make(map[chan Event]struct{})}
+	if len(opts) > 0 {
+		h.options = opts[0]
+	}
+	if h.options.HistorySize == 0 {
+		h.options.HistorySize = DefaultHistorySize
+	}
+	if h.options.BufferSize <= 0 {
+		h.options.BufferSize = DefaultBufferSize
+	}
+	return h
+}
+
+// Publish sends an event to all subscribers.
+// If the event has no ID, it is assigned a sequence number so that the clients can resume their streams.
+func (h *Hub) Publish(e Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.seq++
+	if e.ID == "" {
+		e.ID = strconv.FormatUint(h.seq, 10)
+	}
+	if h.options.HistorySize > 0 {
+		if len(h.history) == h.options.HistorySize {
+			h.history = append(h.history[:0], h.history[1:]...)
+		}
+		h.history = append(h.history, e)
+	}
+	for ch := range h.subscribers {
+		select {
+		case ch <- e:
+		default:
+			delete(h.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// Subscribe registers a new subscriber and returns the channel receiving the published events.
+// If lastEventID is the ID of an event in the history of the hub, the events published after it are queued first.
+// The channel is closed when the subscriber is removed, either by calling the returned cancel function
+// or because the subscriber does not keep up with the published events.
+func (h *Hub) Subscribe(lastEventID string) (events <-chan Event, cancel func()) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var missed []Event
+	if lastEventID != "" {
+		for i, e := range h.history {
+			if e.ID == lastEventID {
+				missed = h.history[i+1:]
+				break
+			}
+		}
+	}
+	size := h.options.BufferSize
+	if len(missed) > size {
+		size = len(missed)
+	}
+	ch := make(chan Event, size)
+	for _, e := range missed {
+		ch <- e
+	}
+	h.subscribers[ch] = struct{}{}
+	return ch, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		if _, ok := h.subscribers[ch]; ok {
+			delete(h.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// Close removes all subscribers, which ends the streams served by Handler.
+// The hub can still be used afterwards.
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.subscribers {
+		delete(h.subscribers, ch)
+		close(ch)
+	}
+}
+
+// Subscribers returns the number of subscribers.
+func (h *Hub) Subscribers() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.subscribers)
+}
+
+// Handler returns a handler that subscribes each client to the hub and streams the published events to it.
+// Clients reconnecting with a "Last-Event-ID" header first receive the events they have missed.
+func (h *Hub) Handler(opts ...Options) routing.Handler {
+	return Handler(func(s *Sender) {
+		events, cancel := h.Subscribe(s.LastEventID())
+		defer cancel()
+		for {
+			select {
+			case e, ok := <-events:
+				if !ok || s.Send(e) != nil {
+					return
+				}
+			case <-s.Done():
+				return
+			}
+		}
+	}, opts...)
+}
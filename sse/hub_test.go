@@ -0,0 +1,75 @@
+package sse
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jackwhelpton/fasthttp-routing/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func receive(events <-chan Event) []string {
+	var ids []string
+	for {
+		select {
+		case e, ok := <-events:
+			if !ok {
+				return append(ids, "closed")
+			}
+			ids = append(ids, e.ID)
+		default:
+			return ids
+		}
+	}
+}
+
+func TestHub(t *testing.T) {
+	h := NewHub(HubOptions{HistorySize: 3, BufferSize: 2})
+	a, cancelA := h.Subscribe("")
+	b, _ := h.Subscribe("")
+	assert.Equal(t, 2, h.Subscribers())
+
+	h.Publish(Event{Data: "1"})
+	h.Publish(Event{ID: "x", Data: "2"})
+	assert.Equal(t, []string{"1", "x"}, receive(a))
+
+	// b does not keep up and is disconnected
+	h.Publish(Event{Data: "3"})
+	assert.Equal(t, []string{"3"}, receive(a))
+	assert.Equal(t, []string{"1", "x", "closed"}, receive(b))
+	assert.Equal(t, 1, h.Subscribers())
+
+	h.Publish(Event{Data: "4"})
+	c, _ := h.Subscribe("x")
+	assert.Equal(t, []string{"3", "4"}, receive(c))
+	d, _ := h.Subscribe("1")
+	assert.Empty(t, receive(d))
+
+	cancelA()
+	cancelA()
+	assert.Equal(t, []string{"4", "closed"}, receive(a))
+	assert.Equal(t, 2, h.Subscribers())
+
+	h.Close()
+	assert.Equal(t, 0, h.Subscribers())
+	assert.Equal(t, []string{"closed"}, receive(c))
+}
+
+func TestHubHandler(t *testing.T) {
+	h := NewHub()
+	h.Publish(Event{Data: "a"})
+	h.Publish(Event{Data: "b"})
+	go func() {
+		for h.Subscribers() == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		h.Publish(Event{Event: "c", Data: "c"})
+		h.Close()
+	}()
+
+	var ctx fasthttp.RequestCtx
+	ctx.Request.Header.Set("Last-Event-ID", "1")
+	assert.Nil(t, h.Handler(Options{Heartbeat: -1})(routing.NewContext(&ctx)))
+	assert.Equal(t, ": ok\n\nid: 2\ndata: b\n\nid: 3\nevent: c\ndata: c\n\n", string(ctx.Response.Body()))
+}
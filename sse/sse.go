@@ -0,0 +1,247 @@
+// Package sse provides Server-Sent Events support for the ozzo routing package.
+package sse
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackwhelpton/fasthttp-routing/v2"
+)
+
+// MIME is the MIME type of event streams.
+const MIME = "text/event-stream"
+
+// DefaultHeartbeat is the default interval between two heartbeats sent to keep idle connections open.
+const DefaultHeartbeat = 15 * time.Second
+
+// Event represents an event sent to the client.
+type Event struct {
+	// ID is the event ID, which the client sends back in the "Last-Event-ID" header when it reconnects.
+	ID string
+	// Event is the event type. If empty, the client dispatches the event as a "message" event.
+	Event string
+	// Data is the event data. Strings and byte slices are sent as is, while other values are encoded as JSON.
+	// Data spanning several lines is sent as several "data" fields.
+	Data interface{}
+	// Retry is the reconnection time the client should use. Zero means the reconnection time is not changed.
+	Retry time.Duration
+}
+
+// ErrClosed is returned when an event is sent on a stream that has ended.
+var ErrClosed = errors.New("sse: stream closed")
+
+// Options configures the event streams created by Handler and Hub.Handler.
+type Options struct {
+	// Heartbeat is the interval between the comments sent to keep the connection open while no event is sent.
+	// Defaults to DefaultHeartbeat. A negative value disables heartbeats.
+	Heartbeat time.Duration
+	// Retry is the reconnection time sent to the client when the stream starts. Zero means no reconnection time is sent.
+	Retry time.Duration
+}
+
+// Sender sends events to a client.
+// Its methods are safe for concurrent use.
+type Sender struct {
+	lastEventID string
+	params      map[string]string
+	data        map[string]interface{}
+
+	mu     sync.Mutex
+	w      *bufio.Writer
+	err    error
+	done   chan struct{}
+	closed bool
+}
+
+// LastEventID returns the value of the "Last-Event-ID" header sent by the client when it reconnects,
+// that is, the ID of the last event it has received.
+func (s *Sender) LastEventID() string {
+	return s.lastEventID
+}
+
+// Param returns the named route parameter of the request.
+func (s *Sender) Param(name string) string {
+	return s.params[name]
+}
+
+// Get returns the named data item registered with the routing.Context of the request via routing.Context.Set().
+func (s *Sender) Get(name string) interface{} {
+	return s.data[name]
+}
+
+// Done returns a channel that is closed when the client has disconnected and no more events can be sent.
+func (s *Sender) Done() <-chan struct{} {
+	return s.done
+}
+
+// Send sends an event to the client.
+// An error is returned if the data cannot be encoded or the client has disconnected.
+func (s *Sender) Send(e Event) error {
+	data, err := encodeEvent(e)
+	if err != nil {
+		return err
+	}
+	return s.write(data)
+}
+
+// comment sends a comment, which is ignored by the client.
+func (s *Sender) comment(text string) error {
+	return s.write([]byte(": " + text + "\n\n"))
+}
+
+func (s *Sender) write(data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return s.err
+	}
+	if _, s.err = s.w.Write(data); s.err == nil {
+		s.err = s.w.Flush()
+	}
+	if s.err != nil && !s.closed {
+		s.closed = true
+		close(s.done)
+	}
+	return s.err
+}
+
+// close prevents further events from being sent once the stream has ended.
+func (s *Sender) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err == nil {
+		s.err = ErrClosed
+	}
+	if !s.closed {
+		s.closed = true
+		close(s.done)
+	}
+}
+
+// Handler returns a handler that streams the events sent by fn to the client.
+//
+// The handler sets the "Content-Type" response header as "text/event-stream" and calls fn with a Sender
+// once the response headers have been sent. The stream ends when fn returns. As fn runs after the handler
+// has returned, it must not use the routing.Context of the request; the route parameters, the data
+// registered with the context and the "Last-Event-ID" header are available through the Sender instead.
+// While the stream is open, heartbeats are sent as comments to keep the connection alive.
+//
+//     router.Get("/clock", sse.Handler(func(s *sse.Sender) {
+//         ticker := time.NewTicker(time.Second)
+//         defer ticker.Stop()
+//         for {
+//             select {
+//             case t := <-ticker.C:
+//                 s.Send(sse.Event{Data: t.Format(time.RFC3339)})
+//             case <-s.Done():
+//                 return
+//             }
+//         }
+//     }))
+func Handler(fn func(s *Sender), opts ...Options) routing.Handler {
+	var options Options
+	if len(opts) > 0 {
+		options = opts[0]
+	}
+	if options.Heartbeat == 0 {
+		options.Heartbeat = DefaultHeartbeat
+	}
+
+	return func(c *routing.Context) error {
+		s := &Sender{
+			lastEventID: string(c.Request.Header.Peek("Last-Event-ID")),
+			params:      c.Params(),
+			data:        c.Data(),
+			done:        make(chan struct{}),
+		}
+		c.Response.Header.SetContentType(MIME)
+		c.Response.Header.Set("Cache-Control", "no-cache")
+		c.Response.Header.Set("X-Accel-Buffering", "no")
+		c.SetBodyStreamWriter(func(w *bufio.Writer) {
+			s.w = w
+			if options.Retry > 0 {
+				s.Send(Event{Retry: options.Retry})
+			} else {
+				// sends the response headers right away
+				s.comment("ok")
+			}
+			stop := make(chan struct{})
+			var wg sync.WaitGroup
+			if options.Heartbeat > 0 {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					heartbeat(s, options.Heartbeat, stop)
+				}()
+			}
+			fn(s)
+			close(stop)
+			wg.Wait()
+			s.close()
+		})
+		return nil
+	}
+}
+
+// heartbeat sends a comment to the client periodically until the stop channel is closed or the client disconnects.
+func heartbeat(s *Sender, interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if s.comment("heartbeat") != nil {
+				return
+			}
+		case <-stop:
+			return
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// encodeEvent encodes the given event in the event stream format.
+func encodeEvent(e Event) ([]byte, error) {
+	var b strings.Builder
+	if e.ID != "" {
+		b.WriteString("id: " + singleLine(e.ID) + "\n")
+	}
+	if e.Event != "" {
+		b.WriteString("event: " + singleLine(e.Event) + "\n")
+	}
+	if e.Retry > 0 {
+		b.WriteString("retry: " + strconv.FormatInt(int64(e.Retry/time.Millisecond), 10) + "\n")
+	}
+	if e.Data != nil {
+		var data string
+		switch d := e.Data.(type) {
+		case string:
+			data = d
+		case []byte:
+			data = string(d)
+		default:
+			bytes, err := json.Marshal(d)
+			if err != nil {
+				return nil, err
+			}
+			data = string(bytes)
+		}
+		data = strings.NewReplacer("\r\n", "\n", "\r", "\n").Replace(data)
+		for _, line := range strings.Split(data, "\n") {
+			b.WriteString("data: " + line + "\n")
+		}
+	}
+	b.WriteString("\n")
+	return []byte(b.String()), nil
+}
+
+// singleLine removes the line breaks from a field value, which would otherwise end the field.
+func singleLine(s string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(s)
+}
@@ -0,0 +1,73 @@
+package sse
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackwhelpton/fasthttp-routing/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func TestHandler(t *testing.T) {
+	router := routing.New()
+	router.Get("/rooms/<id>", func(c *routing.Context) error {
+		c.Set("user", "john")
+		return c.Next()
+	}, Handler(func(s *Sender) {
+		assert.Equal(t, "5", s.LastEventID())
+		s.Send(Event{ID: "6", Event: "join", Data: s.Param("id") + ":" + s.Get("user").(string)})
+		s.Send(Event{Data: map[string]int{"count": 2}})
+	}, Options{Retry: 3 * time.Second}))
+
+	var ctx fasthttp.RequestCtx
+	ctx.Request.Header.SetMethod("GET")
+	ctx.Request.SetRequestURI("/rooms/42")
+	ctx.Request.Header.Set("Last-Event-ID", "5")
+	router.HandleRequest(&ctx)
+	assert.Equal(t, MIME, string(ctx.Response.Header.ContentType()))
+	assert.Equal(t, "no-cache", string(ctx.Response.Header.Peek("Cache-Control")))
+	assert.Equal(t, "retry: 3000\n\nid: 6\nevent: join\ndata: 42:john\n\ndata: {\"count\":2}\n\n", string(ctx.Response.Body()))
+}
+
+func TestHandlerHeartbeat(t *testing.T) {
+	var sender *Sender
+	h := Handler(func(s *Sender) {
+		sender = s
+		time.Sleep(50 * time.Millisecond)
+	}, Options{Heartbeat: 10 * time.Millisecond})
+
+	var ctx fasthttp.RequestCtx
+	assert.Nil(t, h(routing.NewContext(&ctx)))
+	body := string(ctx.Response.Body())
+	assert.True(t, strings.HasPrefix(body, ": ok\n\n: heartbeat\n\n"), body)
+
+	assert.Equal(t, ErrClosed, sender.Send(Event{Data: "late"}))
+	select {
+	case <-sender.Done():
+	default:
+		t.Error("Done() should be closed when the stream ends")
+	}
+}
+
+func Test_encodeEvent(t *testing.T) {
+	tests := []struct {
+		tag   string
+		event Event
+		data  string
+	}{
+		{"t1", Event{Data: "a\nb\r\nc\rd"}, "data: a\ndata: b\ndata: c\ndata: d\n\n"},
+		{"t2", Event{ID: "1\n2", Event: "a\r\nb", Data: []byte("x")}, "id: 12\nevent: ab\ndata: x\n\n"},
+		{"t3", Event{Data: ""}, "data: \n\n"},
+		{"t4", Event{Data: []int{1, 2}, Retry: 1500 * time.Millisecond}, "retry: 1500\ndata: [1,2]\n\n"},
+		{"t5", Event{Event: "ping"}, "event: ping\n\n"},
+	}
+	for _, test := range tests {
+		data, err := encodeEvent(test.event)
+		assert.Nil(t, err, test.tag)
+		assert.Equal(t, test.data, string(data), test.tag)
+	}
+	_, err := encodeEvent(Event{Data: func() {}})
+	assert.NotNil(t, err)
+}
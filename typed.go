@@ -0,0 +1,108 @@
+package routing
+
+import (
+	"reflect"
+
+	"github.com/valyala/fasthttp"
+)
+
+// TypeInfo describes the request and response types of a handler created by Typed.
+// Attach it as a tag to the routes the handler is registered with, using TypeInfoOf(), so that tools such as
+// documentation generators can introspect the routes via Route.Tags().
+type TypeInfo struct {
+	// Request is the type of the request data that the handler reads.
+	Request reflect.Type
+	// Response is the type of the response data that the handler writes.
+	Response reflect.Type
+	// Status is the HTTP status code sent when the handler succeeds.
+	Status int
+}
+
+// TypeInfoOf returns the TypeInfo of a handler created by Typed with the given request and response types
+// and the optional success status (200 by default).
+func TypeInfoOf[Req, Resp any](status ...int) TypeInfo {
+	info := TypeInfo{
+		Request:  reflect.TypeOf((*Req)(nil)).Elem(),
+		Response: reflect.TypeOf((*Resp)(nil)).Elem(),
+		Status:   fasthttp.StatusOK,
+	}
+	if len(status) > 0 {
+		info.Status = status[0]
+	}
+	return info
+}
+
+// Typed adapts a function that takes a request value and returns a response value into a Handler.
+//
+// The request value is populated by calling Context.Read(), followed by the route parameters, which are
+// assigned to the struct fields in the same way as form data (see ReadFormData). Errors reading the request
+// are reported as a 400 HTTP error unless they can be converted by AsHTTPError().
+//
+// If the function succeeds, the response value is written via Context.Write(), that is, using the data writer
+// negotiated for the request, and the HTTP status is set to the optional status parameter (200 by default).
+// No response body is written for the status 204. Errors returned by the function are passed on unchanged
+// if they can be converted by AsHTTPError(); otherwise they are wrapped in a 500 HTTP error.
+// The errors wrapped in a 400 or 500 HTTP error are kept as its cause, and their messages are not sent to the client.
+//
+// The request and response types can be recorded as a route tag with TypeInfoOf():
+//
+//     type CreateUser struct {
+//         Name string `json:"name"`
+//     }
+//
+//     router.Post("/users", routing.Typed(func(c *routing.Context, req CreateUser) (*User, error) {
+//         return users.Create(req.Name)
+//     }, fasthttp.StatusCreated)).Tag(routing.TypeInfoOf[CreateUser, *User](fasthttp.StatusCreated))
+func Typed[Req, Resp any](fn func(*Context, Req) (Resp, error), status ...int) Handler {
+	info := TypeInfoOf[Req, Resp](status...)
+
+	return func(c *Context) error {
+		var req Req
+		if err := readTyped(c, &req); err != nil {
+			if _, ok := AsHTTPError(err); !ok {
+				err = WrapHTTPError(fasthttp.StatusBadRequest, err, "")
+			}
+			return err
+		}
+		resp, err := fn(c, req)
+		if err != nil {
+			if _, ok := AsHTTPError(err); !ok {
+				err = WrapHTTPError(fasthttp.StatusInternalServerError, err, "")
+			}
+			return err
+		}
+		c.SetStatusCode(info.Status)
+		if info.Status == fasthttp.StatusNoContent {
+			return nil
+		}
+		return c.Write(resp)
+	}
+}
+
+// readTyped populates the request value of a typed handler.
+func readTyped(c *Context, data interface{}) error {
+	rv := reflect.ValueOf(data)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			rv.Set(reflect.New(rv.Type().Elem()))
+		}
+		data, rv = rv.Interface(), rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		if c.IsGet() || len(c.PostBody()) == 0 {
+			return nil
+		}
+		return c.Read(data)
+	}
+	if err := c.Read(data); err != nil {
+		return err
+	}
+	if len(c.pnames) == 0 {
+		return nil
+	}
+	params := make(map[string][]string, len(c.pnames))
+	for i, name := range c.pnames {
+		params[name] = []string{c.pvalues[i]}
+	}
+	return ReadFormData(params, data)
+}
@@ -0,0 +1,108 @@
+package routing
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+type typedRequest struct {
+	ID   int    `form:"id"`
+	Name string `json:"name"`
+}
+
+type typedResponse struct {
+	ID      int
+	Message string
+}
+
+type typedDataWriter struct{}
+
+func (w *typedDataWriter) SetHeader(h *fasthttp.ResponseHeader) {}
+
+func (w *typedDataWriter) Write(res io.Writer, data interface{}) error {
+	r, ok := data.(*typedResponse)
+	if !ok {
+		return DefaultDataWriter.Write(res, data)
+	}
+	return DefaultDataWriter.Write(res, strings.Repeat("#", r.ID)+r.Message)
+}
+
+func TestTyped(t *testing.T) {
+	router := New()
+	h := Typed(func(c *Context, req typedRequest) (*typedResponse, error) {
+		switch req.Name {
+		case "":
+			return nil, NewHTTPError(fasthttp.StatusNotFound, "no name")
+		case "fail":
+			return nil, errors.New("failed")
+		}
+		return &typedResponse{req.ID, "hello " + req.Name}, nil
+	}, fasthttp.StatusCreated)
+	writer := func(c *Context) error {
+		c.SetDataWriter(&typedDataWriter{})
+		return nil
+	}
+	route := router.Post("/users/<id>", writer, h).Tag(TypeInfoOf[typedRequest, *typedResponse](fasthttp.StatusCreated))
+
+	if assert.Len(t, route.Tags(), 1) {
+		info := route.Tags()[0].(TypeInfo)
+		assert.Equal(t, reflect.TypeOf(typedRequest{}), info.Request)
+		assert.Equal(t, reflect.TypeOf(&typedResponse{}), info.Response)
+		assert.Equal(t, fasthttp.StatusCreated, info.Status)
+	}
+
+	tests := []struct {
+		tag    string
+		body   string
+		status int
+		output string
+	}{
+		{"t1", `{"name":"john"}`, fasthttp.StatusCreated, "###hello john"},
+		{"t2", `{"name":"john","id":5}`, fasthttp.StatusCreated, "###hello john"},
+		{"t3", `{}`, fasthttp.StatusNotFound, "no name"},
+		{"t4", `{"name":"fail"}`, fasthttp.StatusInternalServerError, "Internal Server Error"},
+		{"t5", `{"name":1}`, fasthttp.StatusBadRequest, ""},
+	}
+	for _, test := range tests {
+		var ctx fasthttp.RequestCtx
+		ctx.Request.Header.SetMethod("POST")
+		ctx.Request.SetRequestURI("/users/3")
+		ctx.Request.Header.SetContentType(MIME_JSON)
+		ctx.Request.SetBodyString(test.body)
+		router.HandleRequest(&ctx)
+		assert.Equal(t, test.status, ctx.Response.StatusCode(), test.tag)
+		if test.output != "" {
+			assert.Equal(t, test.output, string(ctx.Response.Body()), test.tag)
+		}
+	}
+}
+
+func TestTypedNoContent(t *testing.T) {
+	var deleted string
+	router := New()
+	h := Typed(func(c *Context, id string) (struct{}, error) {
+		deleted = c.Param("id")
+		return struct{}{}, nil
+	}, fasthttp.StatusNoContent)
+	info := TypeInfoOf[string, struct{}](fasthttp.StatusNoContent)
+	route := router.To("DELETE,PUT", "/users/<id>", h).Tag(info)
+	router.Get("/users", func(c *Context) error { return nil })
+	assert.Equal(t, []interface{}{info}, route.Tags())
+	assert.Equal(t, []interface{}{info}, router.Routes()[0].Tags())
+	assert.Equal(t, []interface{}{info}, router.Routes()[1].Tags())
+	assert.Len(t, router.Routes()[2].Tags(), 0)
+
+	var ctx fasthttp.RequestCtx
+	ctx.Request.Header.SetMethod("DELETE")
+	ctx.Request.SetRequestURI("/users/7")
+	router.HandleRequest(&ctx)
+	assert.Equal(t, fasthttp.StatusNoContent, ctx.Response.StatusCode())
+	assert.Equal(t, "", string(ctx.Response.Body()))
+	assert.Equal(t, "7", deleted)
+}
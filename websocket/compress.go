@@ -0,0 +1,81 @@
+package websocket
+
+import (
+	"bytes"
+	"compress/flate"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+)
+
+// deflateTail is the empty stored block ending each compressed message, which is removed from the messages
+// sent and appended to the messages received as described in RFC 7692, Section 7.2.
+var deflateTail = []byte{0x00, 0x00, 0xff, 0xff}
+
+var errMessageTooBig = errors.New("websocket: message too big")
+
+var flateWriters = sync.Pool{
+	New: func() interface{} {
+		w, _ := flate.NewWriter(nil, flate.DefaultCompression)
+		return w
+	},
+}
+
+// deflate compresses a message. As no context takeover is negotiated, each message is compressed independently.
+func deflate(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w := flateWriters.Get().(*flate.Writer)
+	defer flateWriters.Put(w)
+	w.Reset(&buf)
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Flush(); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), deflateTail), nil
+}
+
+// inflate decompresses a message. An error is returned if the decompressed message exceeds the given limit,
+// unless the limit is negative.
+func inflate(data []byte, limit int64) ([]byte, error) {
+	r := flate.NewReader(io.MultiReader(bytes.NewReader(data), bytes.NewReader(deflateTail)))
+	defer r.Close()
+	var src io.Reader = r
+	if limit >= 0 {
+		src = io.LimitReader(r, limit+1)
+	}
+	out, err := io.ReadAll(src)
+	// the reader reports an unexpected EOF because the message does not end with a final block
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return nil, err
+	}
+	if limit >= 0 && int64(len(out)) > limit {
+		return nil, errMessageTooBig
+	}
+	return out, nil
+}
+
+// offersDeflate checks if the given "Sec-WebSocket-Extensions" header value offers the permessage-deflate extension
+// with parameters the server can accept. Offers limiting the window size of the server are declined,
+// as the compressor always uses the largest window.
+func offersDeflate(extensions string) bool {
+	for _, ext := range strings.Split(extensions, ",") {
+		params := strings.Split(ext, ";")
+		if !strings.EqualFold(strings.TrimSpace(params[0]), "permessage-deflate") {
+			continue
+		}
+		accepted := true
+		for _, param := range params[1:] {
+			kv := strings.SplitN(strings.TrimSpace(param), "=", 2)
+			if strings.EqualFold(kv[0], "server_max_window_bits") && (len(kv) < 2 || strings.Trim(kv[1], `"`) != "15") {
+				accepted = false
+			}
+		}
+		if accepted {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,42 @@
+package websocket
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_deflate(t *testing.T) {
+	for _, message := range []string{"", "a", "hello hello hello hello"} {
+		compressed, err := deflate([]byte(message))
+		assert.Nil(t, err, message)
+		data, err := inflate(compressed, -1)
+		assert.Nil(t, err, message)
+		assert.Equal(t, message, string(data), message)
+	}
+
+	compressed, _ := deflate([]byte("hello hello hello hello"))
+	_, err := inflate(compressed, 10)
+	assert.Equal(t, errMessageTooBig, err)
+	_, err = inflate([]byte{0xff, 0xff, 0xff}, -1)
+	assert.NotNil(t, err)
+}
+
+func Test_offersDeflate(t *testing.T) {
+	tests := []struct {
+		extensions string
+		expected   bool
+	}{
+		{"", false},
+		{"x-webkit-deflate-frame", false},
+		{"permessage-deflate", true},
+		{"permessage-deflate; client_max_window_bits", true},
+		{"Permessage-Deflate; server_no_context_takeover", true},
+		{"permessage-deflate; server_max_window_bits=10", false},
+		{`permessage-deflate; server_max_window_bits="15"`, true},
+		{"permessage-deflate; server_max_window_bits=10, permessage-deflate", true},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.expected, offersDeflate(test.extensions), test.extensions)
+	}
+}
@@ -0,0 +1,479 @@
+package websocket
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// Message types, which are the opcodes of the frames carrying the messages as defined in RFC 6455, Section 5.2.
+const (
+	TextMessage   = 1
+	BinaryMessage = 2
+	CloseMessage  = 8
+	PingMessage   = 9
+	PongMessage   = 10
+
+	continuationFrame = 0
+)
+
+// Close codes defined in RFC 6455, Section 7.4.1.
+const (
+	CloseNormalClosure           = 1000
+	CloseGoingAway               = 1001
+	CloseProtocolError           = 1002
+	CloseUnsupportedData         = 1003
+	CloseNoStatusReceived        = 1005
+	CloseAbnormalClosure         = 1006
+	CloseInvalidFramePayloadData = 1007
+	ClosePolicyViolation         = 1008
+	CloseMessageTooBig           = 1009
+	CloseMandatoryExtension      = 1010
+	CloseInternalServerErr       = 1011
+)
+
+const (
+	finBit  = 0x80
+	rsv1Bit = 0x40
+	rsv2Bit = 0x20
+	rsv3Bit = 0x10
+	maskBit = 0x80
+
+	maxControlPayload = 125
+	// readChunkSize is the maximum number of bytes of a frame payload allocated before they are received.
+	readChunkSize = 1 << 16
+)
+
+var (
+	// ErrCloseSent is returned when a message is written after a close message has been sent.
+	ErrCloseSent = errors.New("websocket: close sent")
+	// ErrInvalidControl is returned when a control message is too long.
+	ErrInvalidControl = errors.New("websocket: control messages must not exceed 125 bytes")
+	// ErrInvalidMessageType is returned when a message of an unknown type is written.
+	ErrInvalidMessageType = errors.New("websocket: invalid message type")
+)
+
+// CloseError is returned by Conn.ReadMessage when the connection is closed, either by the client,
+// in which case Code and Text are those sent by the client, or by the server because the client violated
+// the protocol, in which case Code and Text are those sent to the client.
+type CloseError struct {
+	// Code is the close code, such as CloseNormalClosure.
+	Code int
+	// Text is the reason for closing the connection.
+	Text string
+}
+
+// Error returns the close code and reason.
+func (e *CloseError) Error() string {
+	s := "websocket: close " + strconv.Itoa(e.Code)
+	if e.Text != "" {
+		s += " " + e.Text
+	}
+	return s
+}
+
+// Conn represents a WebSocket connection established by Handler.
+//
+// A connection supports one concurrent reader and multiple concurrent writers: ReadMessage must be called
+// from a single goroutine, while WriteMessage and WriteClose may be called from several goroutines.
+type Conn struct {
+	conn        net.Conn
+	br          *bufio.Reader
+	subprotocol string
+	compress    bool
+	params      map[string]string
+	data        map[string]interface{}
+
+	readLimit    int64
+	fragmentSize int
+	pingHandler  func(data []byte) error
+	pongHandler  func(data []byte) error
+
+	writeMu   sync.Mutex
+	closeSent bool
+	readErr   error
+}
+
+func newConn(conn net.Conn, options *Options) *Conn {
+	c := &Conn{
+		conn:         conn,
+		br:           bufio.NewReader(conn),
+		readLimit:    options.ReadLimit,
+		fragmentSize: options.FragmentSize,
+	}
+	if c.readLimit == 0 {
+		c.readLimit = DefaultReadLimit
+	}
+	return c
+}
+
+// Subprotocol returns the subprotocol negotiated during the handshake, or an empty string if there is none.
+func (c *Conn) Subprotocol() string {
+	return c.subprotocol
+}
+
+// Compressed returns whether the permessage-deflate extension was negotiated during the handshake.
+func (c *Conn) Compressed() bool {
+	return c.compress
+}
+
+// Param returns the named parameter found in the URL path matching the route serving the connection.
+func (c *Conn) Param(name string) string {
+	return c.params[name]
+}
+
+// Get returns the named data item registered with the routing.Context of the handshake request via routing.Context.Set().
+func (c *Conn) Get(name string) interface{} {
+	return c.data[name]
+}
+
+// LocalAddr returns the local network address.
+func (c *Conn) LocalAddr() net.Addr {
+	return c.conn.LocalAddr()
+}
+
+// RemoteAddr returns the remote network address.
+func (c *Conn) RemoteAddr() net.Addr {
+	return c.conn.RemoteAddr()
+}
+
+// SetReadDeadline sets the deadline for future calls to ReadMessage. A zero value means ReadMessage will not time out.
+func (c *Conn) SetReadDeadline(t time.Time) error {
+	return c.conn.SetReadDeadline(t)
+}
+
+// SetWriteDeadline sets the deadline for future writes. A zero value means writes will not time out.
+func (c *Conn) SetWriteDeadline(t time.Time) error {
+	return c.conn.SetWriteDeadline(t)
+}
+
+// SetReadLimit sets the maximum size of the messages read from the client. A negative value means no limit.
+// When a message exceeds the limit, the connection is closed with CloseMessageTooBig.
+func (c *Conn) SetReadLimit(limit int64) {
+	c.readLimit = limit
+}
+
+// SetPingHandler sets the handler called when a ping message is received.
+// The default handler replies with a pong message carrying the same data.
+func (c *Conn) SetPingHandler(h func(data []byte) error) {
+	c.pingHandler = h
+}
+
+// SetPongHandler sets the handler called when a pong message is received. By default, pong messages are ignored.
+func (c *Conn) SetPongHandler(h func(data []byte) error) {
+	c.pongHandler = h
+}
+
+// ReadMessage reads the next data message from the client and returns its type (TextMessage or BinaryMessage)
+// and its payload. Fragmented messages are reassembled, compressed messages are decompressed, and control
+// messages are handled while waiting for a data message: ping messages are answered by the ping handler,
+// pong messages are passed to the pong handler, and close messages are answered before a *CloseError is returned.
+//
+// If the client violates the protocol, the connection is closed with the appropriate close code
+// and a *CloseError is returned. Once an error has been returned, subsequent calls return the same error.
+func (c *Conn) ReadMessage() (messageType int, data []byte, err error) {
+	if c.readErr != nil {
+		return 0, nil, c.readErr
+	}
+	messageType, data, err = c.readMessage()
+	if err != nil {
+		c.readErr = err
+	}
+	return
+}
+
+func (c *Conn) readMessage() (int, []byte, error) {
+	var (
+		messageType int
+		compressed  bool
+		message     []byte
+	)
+	for {
+		fin, rsv1, opcode, payload, err := c.readFrame(int64(len(message)))
+		if err != nil {
+			return 0, nil, err
+		}
+		switch opcode {
+		case PingMessage:
+			h := c.pingHandler
+			if h == nil {
+				h = func(data []byte) error {
+					err := c.WriteMessage(PongMessage, data)
+					if err == ErrCloseSent {
+						return nil
+					}
+					return err
+				}
+			}
+			if err := h(payload); err != nil {
+				return 0, nil, err
+			}
+			continue
+		case PongMessage:
+			if c.pongHandler != nil {
+				if err := c.pongHandler(payload); err != nil {
+					return 0, nil, err
+				}
+			}
+			continue
+		case CloseMessage:
+			return 0, nil, c.handleClose(payload)
+		case continuationFrame:
+			if messageType == 0 {
+				return 0, nil, c.fail(CloseProtocolError, "unexpected continuation frame")
+			}
+		default:
+			if messageType != 0 {
+				return 0, nil, c.fail(CloseProtocolError, "expected continuation frame")
+			}
+			messageType, compressed = opcode, rsv1
+		}
+		message = append(message, payload...)
+		if !fin {
+			continue
+		}
+		if compressed {
+			if message, err = inflate(message, c.readLimit); err == errMessageTooBig {
+				return 0, nil, c.fail(CloseMessageTooBig, "")
+			} else if err != nil {
+				return 0, nil, c.fail(CloseInvalidFramePayloadData, "invalid compressed data")
+			}
+		}
+		if messageType == TextMessage && !utf8.Valid(message) {
+			return 0, nil, c.fail(CloseInvalidFramePayloadData, "invalid UTF-8 text")
+		}
+		if message == nil {
+			message = []byte{}
+		}
+		return messageType, message, nil
+	}
+}
+
+// readFrame reads a frame and checks that it is valid. The read parameter is the number of bytes
+// of the current message read so far, which is used to enforce the read limit.
+func (c *Conn) readFrame(read int64) (fin, rsv1 bool, opcode int, payload []byte, err error) {
+	var header [8]byte
+	if _, err = io.ReadFull(c.br, header[:2]); err != nil {
+		return
+	}
+	fin, rsv1, opcode = header[0]&finBit != 0, header[0]&rsv1Bit != 0, int(header[0]&0x0f)
+	masked, length := header[1]&maskBit != 0, uint64(header[1]&0x7f)
+
+	switch {
+	case header[0]&(rsv2Bit|rsv3Bit) != 0:
+		err = c.fail(CloseProtocolError, "unexpected reserved bits")
+	case rsv1 && (!c.compress || opcode == continuationFrame || opcode >= CloseMessage):
+		err = c.fail(CloseProtocolError, "unexpected reserved bits")
+	case opcode > BinaryMessage && opcode < CloseMessage || opcode > PongMessage:
+		err = c.fail(CloseProtocolError, "unknown opcode "+strconv.Itoa(opcode))
+	case opcode >= CloseMessage && (!fin || length > maxControlPayload):
+		err = c.fail(CloseProtocolError, "invalid control frame")
+	case !masked:
+		err = c.fail(CloseProtocolError, "client frames must be masked")
+	}
+	if err != nil {
+		return
+	}
+
+	switch length {
+	case 126:
+		if _, err = io.ReadFull(c.br, header[:2]); err != nil {
+			return
+		}
+		length = uint64(binary.BigEndian.Uint16(header[:2]))
+	case 127:
+		if _, err = io.ReadFull(c.br, header[:8]); err != nil {
+			return
+		}
+		if length = binary.BigEndian.Uint64(header[:8]); length>>63 != 0 {
+			err = c.fail(CloseProtocolError, "invalid payload length")
+			return
+		}
+	}
+	if opcode < CloseMessage && c.readLimit >= 0 && length > uint64(c.readLimit-read) {
+		err = c.fail(CloseMessageTooBig, "")
+		return
+	}
+
+	var mask [4]byte
+	if _, err = io.ReadFull(c.br, mask[:]); err != nil {
+		return
+	}
+	// the payload grows as it is received, so that a client cannot make the server allocate
+	// more memory than it sends by declaring a huge length, even if there is no read limit
+	payload = make([]byte, 0, min(length, readChunkSize))
+	for n := uint64(0); n < length; n = uint64(len(payload)) {
+		chunk := min(length-n, readChunkSize)
+		payload = append(payload, make([]byte, chunk)...)
+		if _, err = io.ReadFull(c.br, payload[n:]); err != nil {
+			return
+		}
+	}
+	for i := range payload {
+		payload[i] ^= mask[i&3]
+	}
+	return
+}
+
+// handleClose answers a close message received from the client.
+func (c *Conn) handleClose(payload []byte) error {
+	e := &CloseError{Code: CloseNoStatusReceived}
+	switch {
+	case len(payload) == 1:
+		return c.fail(CloseProtocolError, "invalid close payload")
+	case len(payload) >= 2:
+		e.Code, e.Text = int(binary.BigEndian.Uint16(payload)), string(payload[2:])
+		if !validCloseCode(e.Code) {
+			return c.fail(CloseProtocolError, "invalid close code")
+		}
+		if !utf8.ValidString(e.Text) {
+			return c.fail(CloseInvalidFramePayloadData, "invalid UTF-8 close reason")
+		}
+	}
+	if e.Code == CloseNoStatusReceived {
+		c.WriteClose(CloseNormalClosure, "")
+	} else {
+		c.WriteClose(e.Code, "")
+	}
+	return e
+}
+
+// fail sends a close message because of an error and returns the corresponding CloseError.
+func (c *Conn) fail(code int, text string) error {
+	c.WriteClose(code, text)
+	return &CloseError{Code: code, Text: text}
+}
+
+// validCloseCode checks if the given code may be sent in a close frame (RFC 6455, Section 7.4).
+func validCloseCode(code int) bool {
+	switch {
+	case code >= 1000 && code <= 1003, code >= 1007 && code <= 1011:
+		return true
+	case code >= 3000 && code <= 4999:
+		return true
+	}
+	return false
+}
+
+// WriteMessage sends a message of the given type to the client.
+//
+// Data messages (TextMessage and BinaryMessage) are compressed if the permessage-deflate extension
+// has been negotiated, and are split into fragments if Options.FragmentSize is set.
+// Control messages (PingMessage and PongMessage) cannot carry more than 125 bytes.
+// Use WriteClose to send a close message.
+func (c *Conn) WriteMessage(messageType int, data []byte) error {
+	switch messageType {
+	case TextMessage, BinaryMessage:
+		return c.writeData(messageType, data)
+	case PingMessage, PongMessage:
+		if len(data) > maxControlPayload {
+			return ErrInvalidControl
+		}
+		return c.writeFrames(frame{true, false, messageType, data})
+	case CloseMessage:
+		code, text := CloseNoStatusReceived, ""
+		if len(data) >= 2 {
+			code, text = int(binary.BigEndian.Uint16(data)), string(data[2:])
+		}
+		return c.WriteClose(code, text)
+	}
+	return ErrInvalidMessageType
+}
+
+// WriteClose sends a close message with the given code and reason to the client.
+// No more messages can be written afterwards. The code CloseNoStatusReceived sends a close message without payload.
+func (c *Conn) WriteClose(code int, text string) error {
+	var payload []byte
+	if code != CloseNoStatusReceived {
+		payload = make([]byte, 2, 2+len(text))
+		binary.BigEndian.PutUint16(payload, uint16(code))
+		payload = append(payload, text...)
+		if len(payload) > maxControlPayload {
+			return ErrInvalidControl
+		}
+	}
+	return c.writeFrames(frame{true, false, CloseMessage, payload})
+}
+
+func (c *Conn) writeData(messageType int, data []byte) error {
+	compressed := false
+	if c.compress {
+		var err error
+		if data, err = deflate(data); err != nil {
+			return err
+		}
+		compressed = true
+	}
+	if c.fragmentSize <= 0 || len(data) <= c.fragmentSize {
+		return c.writeFrames(frame{true, compressed, messageType, data})
+	}
+	var frames []frame
+	opcode := messageType
+	for len(data) > 0 {
+		n := c.fragmentSize
+		if n > len(data) {
+			n = len(data)
+		}
+		frames = append(frames, frame{n == len(data), compressed && opcode != continuationFrame, opcode, data[:n]})
+		data, opcode = data[n:], continuationFrame
+	}
+	return c.writeFrames(frames...)
+}
+
+type frame struct {
+	fin     bool
+	rsv1    bool
+	opcode  int
+	payload []byte
+}
+
+// writeFrames sends the given frames, which are not masked as they are sent by the server.
+func (c *Conn) writeFrames(frames ...frame) error {
+	size := 0
+	for _, f := range frames {
+		size += len(f.payload) + 10
+	}
+	buf := make([]byte, 0, size)
+	for _, f := range frames {
+		b := byte(f.opcode)
+		if f.fin {
+			b |= finBit
+		}
+		if f.rsv1 {
+			b |= rsv1Bit
+		}
+		buf = append(buf, b)
+		switch n := len(f.payload); {
+		case n <= 125:
+			buf = append(buf, byte(n))
+		case n <= 0xffff:
+			buf = append(buf, 126, byte(n>>8), byte(n))
+		default:
+			buf = append(buf, 127)
+			buf = binary.BigEndian.AppendUint64(buf, uint64(n))
+		}
+		buf = append(buf, f.payload...)
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if c.closeSent {
+		return ErrCloseSent
+	}
+	if frames[0].opcode == CloseMessage {
+		c.closeSent = true
+	}
+	_, err := c.conn.Write(buf)
+	return err
+}
+
+// closeNormally sends a close message with CloseNormalClosure unless a close message has already been sent.
+func (c *Conn) closeNormally() {
+	c.WriteClose(CloseNormalClosure, "")
+}
@@ -0,0 +1,311 @@
+package websocket
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testClient sends masked frames and reads the frames sent by the server, as a WebSocket client would.
+type testClient struct {
+	conn net.Conn
+	br   *bufio.Reader
+}
+
+func (c *testClient) writeFrame(b0 byte, payload []byte) error {
+	return c.writeRawFrame(b0, true, payload)
+}
+
+func (c *testClient) writeRawFrame(b0 byte, masked bool, payload []byte) error {
+	buf := []byte{b0}
+	var maskBit byte
+	if masked {
+		maskBit = 0x80
+	}
+	switch n := len(payload); {
+	case n <= 125:
+		buf = append(buf, maskBit|byte(n))
+	case n <= 0xffff:
+		buf = append(buf, maskBit|126, byte(n>>8), byte(n))
+	default:
+		buf = append(buf, maskBit|127)
+		buf = binary.BigEndian.AppendUint64(buf, uint64(n))
+	}
+	if masked {
+		mask := []byte{0x12, 0x34, 0x56, 0x78}
+		buf = append(buf, mask...)
+		for i, b := range payload {
+			buf = append(buf, b^mask[i&3])
+		}
+	} else {
+		buf = append(buf, payload...)
+	}
+	_, err := c.conn.Write(buf)
+	return err
+}
+
+func (c *testClient) readFrame() (b0 byte, payload []byte, err error) {
+	var header [8]byte
+	if _, err = io.ReadFull(c.br, header[:2]); err != nil {
+		return
+	}
+	length := uint64(header[1] & 0x7f)
+	switch length {
+	case 126:
+		if _, err = io.ReadFull(c.br, header[:2]); err != nil {
+			return
+		}
+		length = uint64(binary.BigEndian.Uint16(header[:2]))
+	case 127:
+		if _, err = io.ReadFull(c.br, header[:8]); err != nil {
+			return
+		}
+		length = binary.BigEndian.Uint64(header[:8])
+	}
+	payload = make([]byte, length)
+	_, err = io.ReadFull(c.br, payload)
+	return header[0], payload, err
+}
+
+func closePayload(code int, text string) []byte {
+	payload := make([]byte, 2)
+	binary.BigEndian.PutUint16(payload, uint16(code))
+	return append(payload, text...)
+}
+
+type readResult struct {
+	messageType int
+	data        []byte
+	err         error
+}
+
+func readAsync(conn *Conn) <-chan readResult {
+	ch := make(chan readResult, 1)
+	go func() {
+		messageType, data, err := conn.ReadMessage()
+		ch <- readResult{messageType, data, err}
+	}()
+	return ch
+}
+
+func newTestConn(options Options) (*Conn, *testClient) {
+	server, client := net.Pipe()
+	return newConn(server, &options), &testClient{client, bufio.NewReader(client)}
+}
+
+func TestConnReadMessage(t *testing.T) {
+	conn, client := newTestConn(Options{})
+	defer conn.conn.Close()
+
+	// fragmented message with a ping in between
+	res := readAsync(conn)
+	assert.Nil(t, client.writeFrame(TextMessage, []byte("hel")))
+	assert.Nil(t, client.writeFrame(finBit|PingMessage, []byte("p")))
+	b0, payload, err := client.readFrame()
+	if assert.Nil(t, err) {
+		assert.Equal(t, byte(finBit|PongMessage), b0)
+		assert.Equal(t, "p", string(payload))
+	}
+	assert.Nil(t, client.writeFrame(finBit|continuationFrame, []byte("lo")))
+	r := <-res
+	assert.Nil(t, r.err)
+	assert.Equal(t, TextMessage, r.messageType)
+	assert.Equal(t, "hello", string(r.data))
+
+	// pong handler and extended payload length
+	var pong string
+	conn.SetPongHandler(func(data []byte) error {
+		pong = string(data)
+		return nil
+	})
+	data := bytes.Repeat([]byte{7}, 70000)
+	res = readAsync(conn)
+	assert.Nil(t, client.writeFrame(finBit|PongMessage, []byte("q")))
+	assert.Nil(t, client.writeFrame(finBit|BinaryMessage, data))
+	r = <-res
+	assert.Nil(t, r.err)
+	assert.Equal(t, BinaryMessage, r.messageType)
+	assert.Equal(t, data, r.data)
+	assert.Equal(t, "q", pong)
+
+	// empty message
+	res = readAsync(conn)
+	assert.Nil(t, client.writeFrame(finBit|TextMessage, nil))
+	r = <-res
+	assert.Nil(t, r.err)
+	assert.Equal(t, []byte{}, r.data)
+}
+
+func TestConnClose(t *testing.T) {
+	conn, client := newTestConn(Options{})
+	defer conn.conn.Close()
+
+	res := readAsync(conn)
+	assert.Nil(t, client.writeFrame(finBit|CloseMessage, closePayload(CloseGoingAway, "bye")))
+	b0, payload, err := client.readFrame()
+	if assert.Nil(t, err) {
+		assert.Equal(t, byte(finBit|CloseMessage), b0)
+		assert.Equal(t, closePayload(CloseGoingAway, ""), payload)
+	}
+	r := <-res
+	assert.Equal(t, &CloseError{Code: CloseGoingAway, Text: "bye"}, r.err)
+	assert.Equal(t, "websocket: close 1001 bye", r.err.Error())
+
+	_, _, err = conn.ReadMessage()
+	assert.Equal(t, r.err, err)
+	assert.Equal(t, ErrCloseSent, conn.WriteMessage(TextMessage, []byte("late")))
+
+	// a close message without payload is answered with a normal closure
+	conn, client = newTestConn(Options{})
+	defer conn.conn.Close()
+	res = readAsync(conn)
+	assert.Nil(t, client.writeFrame(finBit|CloseMessage, nil))
+	_, payload, _ = client.readFrame()
+	assert.Equal(t, closePayload(CloseNormalClosure, ""), payload)
+	assert.Equal(t, &CloseError{Code: CloseNoStatusReceived}, (<-res).err)
+}
+
+func TestConnProtocolErrors(t *testing.T) {
+	tests := []struct {
+		tag     string
+		options Options
+		b0      byte
+		masked  bool
+		payload []byte
+		code    int
+	}{
+		{"t1", Options{}, finBit | TextMessage, false, []byte("a"), CloseProtocolError},
+		{"t2", Options{}, finBit | TextMessage, true, []byte{0xff, 0xfe}, CloseInvalidFramePayloadData},
+		{"t3", Options{}, finBit | continuationFrame, true, []byte("a"), CloseProtocolError},
+		{"t4", Options{}, finBit | rsv2Bit | TextMessage, true, []byte("a"), CloseProtocolError},
+		{"t5", Options{}, finBit | rsv1Bit | TextMessage, true, []byte("a"), CloseProtocolError},
+		{"t6", Options{}, PingMessage, true, []byte("a"), CloseProtocolError},
+		{"t7", Options{}, finBit | 3, true, []byte("a"), CloseProtocolError},
+		{"t8", Options{ReadLimit: 4}, finBit | BinaryMessage, true, []byte("abcde"), CloseMessageTooBig},
+		{"t9", Options{}, finBit | CloseMessage, true, closePayload(CloseNoStatusReceived, ""), CloseProtocolError},
+		{"t10", Options{}, finBit | CloseMessage, true, []byte{3}, CloseProtocolError},
+		{"t11", Options{}, finBit | CloseMessage, true, closePayload(CloseNormalClosure, "\xff"), CloseInvalidFramePayloadData},
+		{"t12", Options{}, finBit | PingMessage, true, bytes.Repeat([]byte("a"), 126), CloseProtocolError},
+	}
+	for _, test := range tests {
+		conn, client := newTestConn(test.options)
+		res := readAsync(conn)
+		go client.writeRawFrame(test.b0, test.masked, test.payload)
+		b0, payload, err := client.readFrame()
+		if assert.Nil(t, err, test.tag) {
+			assert.Equal(t, byte(finBit|CloseMessage), b0, test.tag)
+			assert.Equal(t, test.code, int(binary.BigEndian.Uint16(payload)), test.tag)
+		}
+		r := <-res
+		if assert.IsType(t, &CloseError{}, r.err, test.tag) {
+			assert.Equal(t, test.code, r.err.(*CloseError).Code, test.tag)
+		}
+		conn.conn.Close()
+	}
+
+	// the read limit applies to the whole message
+	conn, client := newTestConn(Options{ReadLimit: 4})
+	defer conn.conn.Close()
+	res := readAsync(conn)
+	assert.Nil(t, client.writeFrame(TextMessage, []byte("abc")))
+	go client.writeFrame(finBit|continuationFrame, []byte("de"))
+	_, payload, _ := client.readFrame()
+	assert.Equal(t, closePayload(CloseMessageTooBig, ""), payload)
+	assert.Equal(t, &CloseError{Code: CloseMessageTooBig}, (<-res).err)
+}
+
+func TestConnReadNoLimit(t *testing.T) {
+	conn, client := newTestConn(Options{ReadLimit: -1})
+	defer conn.conn.Close()
+
+	message := bytes.Repeat([]byte("0123456789"), readChunkSize/4)
+	res := readAsync(conn)
+	go client.writeFrame(finBit|BinaryMessage, message)
+	r := <-res
+	assert.Nil(t, r.err)
+	assert.Equal(t, message, r.data)
+
+	// a huge declared length is not allocated upfront
+	res = readAsync(conn)
+	header := []byte{finBit | BinaryMessage, 0x80 | 127}
+	header = binary.BigEndian.AppendUint64(header, 1<<62)
+	header = append(header, 0x12, 0x34, 0x56, 0x78)
+	go func() {
+		client.conn.Write(append(header, "abc"...))
+		client.conn.Close()
+	}()
+	assert.NotNil(t, (<-res).err)
+}
+
+func TestConnWriteMessage(t *testing.T) {
+	conn, client := newTestConn(Options{FragmentSize: 4})
+	defer conn.conn.Close()
+
+	go func() {
+		conn.WriteMessage(TextMessage, []byte("hello world"))
+		conn.WriteMessage(PingMessage, []byte("p"))
+		conn.WriteMessage(CloseMessage, closePayload(CloseGoingAway, "bye"))
+	}()
+	expected := []struct {
+		b0      byte
+		payload string
+	}{
+		{TextMessage, "hell"},
+		{continuationFrame, "o wo"},
+		{finBit | continuationFrame, "rld"},
+		{finBit | PingMessage, "p"},
+		{finBit | CloseMessage, string(closePayload(CloseGoingAway, "bye"))},
+	}
+	for _, e := range expected {
+		b0, payload, err := client.readFrame()
+		if assert.Nil(t, err) {
+			assert.Equal(t, e.b0, b0)
+			assert.Equal(t, e.payload, string(payload))
+		}
+	}
+
+	assert.Equal(t, ErrCloseSent, conn.WriteMessage(BinaryMessage, []byte("late")))
+	assert.Equal(t, ErrInvalidControl, conn.WriteMessage(PongMessage, bytes.Repeat([]byte("a"), 126)))
+	assert.Equal(t, ErrInvalidControl, conn.WriteClose(CloseNormalClosure, string(bytes.Repeat([]byte("a"), 124))))
+	assert.Equal(t, ErrInvalidMessageType, conn.WriteMessage(3, nil))
+}
+
+func TestConnCompression(t *testing.T) {
+	conn, client := newTestConn(Options{})
+	conn.compress = true
+	defer conn.conn.Close()
+
+	message := bytes.Repeat([]byte("compress me "), 20)
+	compressed, err := deflate(message)
+	assert.Nil(t, err)
+
+	res := readAsync(conn)
+	assert.Nil(t, client.writeFrame(rsv1Bit|TextMessage, compressed[:5]))
+	assert.Nil(t, client.writeFrame(finBit|continuationFrame, compressed[5:]))
+	r := <-res
+	assert.Nil(t, r.err)
+	assert.Equal(t, message, r.data)
+
+	go conn.WriteMessage(BinaryMessage, message)
+	b0, payload, err := client.readFrame()
+	if assert.Nil(t, err) {
+		assert.Equal(t, byte(finBit|rsv1Bit|BinaryMessage), b0)
+		assert.True(t, len(payload) < len(message))
+		data, err := inflate(payload, -1)
+		assert.Nil(t, err)
+		assert.Equal(t, message, data)
+	}
+
+	// the read limit applies to the decompressed message
+	conn.SetReadLimit(100)
+	res = readAsync(conn)
+	go client.writeFrame(finBit|rsv1Bit|TextMessage, compressed)
+	_, payload, _ = client.readFrame()
+	assert.Equal(t, closePayload(CloseMessageTooBig, ""), payload)
+	assert.Equal(t, &CloseError{Code: CloseMessageTooBig}, (<-res).err)
+}
@@ -0,0 +1,157 @@
+// Package websocket provides WebSocket support for the ozzo routing package.
+package websocket
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/base64"
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/jackwhelpton/fasthttp-routing/v2"
+	"github.com/valyala/fasthttp"
+)
+
+// DefaultReadLimit is the default maximum size of the messages read from clients.
+const DefaultReadLimit = 1 << 20
+
+// acceptGUID is the GUID used to compute the "Sec-WebSocket-Accept" header as defined in RFC 6455, Section 4.2.2.
+const acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// Options configures the WebSocket connections established by Handler.
+type Options struct {
+	// Subprotocols lists the subprotocols supported by the server in order of preference.
+	// The first one that is also requested by the client is selected.
+	Subprotocols []string
+	// CheckOrigin determines whether the handshake request comes from an allowed origin.
+	// If nil, requests with an "Origin" header are accepted only if its host matches the "Host" header.
+	CheckOrigin func(c *routing.Context) bool
+	// ReadLimit is the maximum size of the messages read from clients. Defaults to DefaultReadLimit.
+	// A negative value means no limit.
+	ReadLimit int64
+	// FragmentSize is the maximum payload size of the frames sent by the server. Longer messages are fragmented.
+	// Zero means messages are never fragmented.
+	FragmentSize int
+	// EnableCompression enables the permessage-deflate extension (RFC 7692) when the client offers it.
+	EnableCompression bool
+}
+
+// Handler returns a handler that upgrades the request to a WebSocket connection and calls fn with the connection.
+//
+// The handler performs the opening handshake described in RFC 6455 and takes over the underlying network
+// connection via fasthttp.RequestCtx.Hijack(). Invalid handshake requests are rejected with a 400 HTTP error
+// (or a 426 HTTP error for unsupported protocol versions), and requests from disallowed origins with a 403 HTTP error.
+//
+// fn is called once the handshake response has been sent, after the handler has returned, so it must not use
+// the routing.Context of the request; the route parameters and the data registered with the context are available
+// through the connection instead. The connection is closed when fn returns.
+//
+//     api.Get("/rooms/<id>/ws", websocket.Handler(func(conn *websocket.Conn) {
+//         for {
+//             messageType, data, err := conn.ReadMessage()
+//             if err != nil {
+//                 return
+//             }
+//             conn.WriteMessage(messageType, data)
+//         }
+//     }))
+func Handler(fn func(conn *Conn), opts ...Options) routing.Handler {
+	var options Options
+	if len(opts) > 0 {
+		options = opts[0]
+	}
+	if options.CheckOrigin == nil {
+		options.CheckOrigin = checkSameOrigin
+	}
+
+	return func(c *routing.Context) error {
+		key, err := checkHandshake(c)
+		if err != nil {
+			return err
+		}
+		if !options.CheckOrigin(c) {
+			return routing.NewHTTPError(fasthttp.StatusForbidden, "websocket: origin not allowed")
+		}
+		subprotocol := selectSubprotocol(string(c.Request.Header.Peek("Sec-WebSocket-Protocol")), options.Subprotocols)
+		compress := options.EnableCompression && offersDeflate(string(c.Request.Header.Peek("Sec-WebSocket-Extensions")))
+
+		c.Response.SetStatusCode(fasthttp.StatusSwitchingProtocols)
+		c.Response.Header.Set("Upgrade", "websocket")
+		c.Response.Header.Set("Connection", "Upgrade")
+		c.Response.Header.Set("Sec-WebSocket-Accept", acceptKey(key))
+		if subprotocol != "" {
+			c.Response.Header.Set("Sec-WebSocket-Protocol", subprotocol)
+		}
+		if compress {
+			c.Response.Header.Set("Sec-WebSocket-Extensions", "permessage-deflate; server_no_context_takeover; client_no_context_takeover")
+		}
+
+		params, data := c.Params(), c.Data()
+		c.Hijack(func(nc net.Conn) {
+			conn := newConn(nc, &options)
+			conn.subprotocol, conn.compress = subprotocol, compress
+			conn.params, conn.data = params, data
+			fn(conn)
+			conn.closeNormally()
+		})
+		return nil
+	}
+}
+
+// checkHandshake validates the opening handshake request and returns its "Sec-WebSocket-Key" header.
+func checkHandshake(c *routing.Context) (string, error) {
+	if !c.IsGet() {
+		return "", routing.NewHTTPError(fasthttp.StatusMethodNotAllowed, "websocket: the handshake request must use the GET method")
+	}
+	if !headerContains(c.Request.Header.Peek("Connection"), "upgrade") || !headerContains(c.Request.Header.Peek("Upgrade"), "websocket") {
+		return "", routing.NewHTTPError(fasthttp.StatusBadRequest, "websocket: the request is not a websocket handshake")
+	}
+	if string(c.Request.Header.Peek("Sec-WebSocket-Version")) != "13" {
+		c.Response.Header.Set("Sec-WebSocket-Version", "13")
+		return "", routing.NewHTTPError(fasthttp.StatusUpgradeRequired, "websocket: unsupported version")
+	}
+	key := strings.TrimSpace(string(c.Request.Header.Peek("Sec-WebSocket-Key")))
+	if k, err := base64.StdEncoding.DecodeString(key); err != nil || len(k) != 16 {
+		return "", routing.NewHTTPError(fasthttp.StatusBadRequest, "websocket: invalid Sec-WebSocket-Key header")
+	}
+	return key, nil
+}
+
+// headerContains checks if the given comma-separated header value contains the given token, ignoring case.
+func headerContains(value []byte, token string) bool {
+	for _, t := range bytes.Split(value, []byte{','}) {
+		if strings.EqualFold(string(bytes.TrimSpace(t)), token) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkSameOrigin accepts requests without an "Origin" header and requests whose origin host matches the "Host" header.
+func checkSameOrigin(c *routing.Context) bool {
+	origin := c.Request.Header.Peek("Origin")
+	if len(origin) == 0 {
+		return true
+	}
+	u, err := url.Parse(string(origin))
+	return err == nil && strings.EqualFold(u.Host, string(c.Host()))
+}
+
+// selectSubprotocol returns the first of the supported subprotocols that is requested by the client.
+func selectSubprotocol(requested string, supported []string) string {
+	for _, s := range supported {
+		for _, r := range strings.Split(requested, ",") {
+			if strings.TrimSpace(r) == s {
+				return s
+			}
+		}
+	}
+	return ""
+}
+
+// acceptKey computes the "Sec-WebSocket-Accept" header value for the given "Sec-WebSocket-Key" header value.
+func acceptKey(key string) string {
+	h := sha1.Sum([]byte(key + acceptGUID))
+	return base64.StdEncoding.EncodeToString(h[:])
+}
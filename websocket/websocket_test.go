@@ -0,0 +1,145 @@
+package websocket
+
+import (
+	"bufio"
+	"net/http"
+	"testing"
+
+	"github.com/jackwhelpton/fasthttp-routing/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+	"github.com/valyala/fasthttp/fasthttputil"
+)
+
+func newHandshakeRequest(ctx *fasthttp.RequestCtx) {
+	ctx.Request.Header.SetMethod("GET")
+	ctx.Request.SetRequestURI("/ws")
+	ctx.Request.Header.SetHost("example.com")
+	ctx.Request.Header.Set("Connection", "keep-alive, Upgrade")
+	ctx.Request.Header.Set("Upgrade", "websocket")
+	ctx.Request.Header.Set("Sec-WebSocket-Version", "13")
+	ctx.Request.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+}
+
+func TestHandlerHandshake(t *testing.T) {
+	tests := []struct {
+		tag     string
+		options Options
+		header  string
+		value   string
+		status  int
+	}{
+		{"t1", Options{}, "", "", fasthttp.StatusSwitchingProtocols},
+		{"t2", Options{}, "Connection", "keep-alive", fasthttp.StatusBadRequest},
+		{"t3", Options{}, "Upgrade", "h2c", fasthttp.StatusBadRequest},
+		{"t4", Options{}, "Sec-WebSocket-Version", "8", fasthttp.StatusUpgradeRequired},
+		{"t5", Options{}, "Sec-WebSocket-Key", "c2hvcnQ=", fasthttp.StatusBadRequest},
+		{"t6", Options{}, "Origin", "https://example.com", fasthttp.StatusSwitchingProtocols},
+		{"t7", Options{}, "Origin", "https://evil.com", fasthttp.StatusForbidden},
+		{"t8", Options{CheckOrigin: func(c *routing.Context) bool { return true }}, "Origin", "https://evil.com", fasthttp.StatusSwitchingProtocols},
+	}
+	for _, test := range tests {
+		var ctx fasthttp.RequestCtx
+		newHandshakeRequest(&ctx)
+		if test.header != "" {
+			ctx.Request.Header.Set(test.header, test.value)
+		}
+		err := Handler(func(*Conn) {}, test.options)(routing.NewContext(&ctx))
+		if test.status == fasthttp.StatusSwitchingProtocols {
+			assert.Nil(t, err, test.tag)
+			assert.Equal(t, test.status, ctx.Response.StatusCode(), test.tag)
+			assert.True(t, ctx.Hijacked(), test.tag)
+		} else if assert.NotNil(t, err, test.tag) {
+			assert.Equal(t, test.status, err.(routing.HTTPError).StatusCode(), test.tag)
+			assert.False(t, ctx.Hijacked(), test.tag)
+		}
+	}
+
+	var ctx fasthttp.RequestCtx
+	newHandshakeRequest(&ctx)
+	ctx.Request.Header.SetMethod("POST")
+	err := Handler(func(*Conn) {})(routing.NewContext(&ctx))
+	assert.Equal(t, fasthttp.StatusMethodNotAllowed, err.(routing.HTTPError).StatusCode())
+
+	ctx.Request.Header.SetMethod("GET")
+	ctx.Request.Header.Set("Sec-WebSocket-Version", "8")
+	Handler(func(*Conn) {})(routing.NewContext(&ctx))
+	assert.Equal(t, "13", string(ctx.Response.Header.Peek("Sec-WebSocket-Version")))
+}
+
+func TestHandler(t *testing.T) {
+	router := routing.New()
+	router.Get("/rooms/<id>", func(c *routing.Context) error {
+		c.Set("user", "john")
+		return c.Next()
+	}, Handler(func(conn *Conn) {
+		conn.WriteMessage(TextMessage, []byte(conn.Param("id")+":"+conn.Get("user").(string)+":"+conn.Subprotocol()))
+		for {
+			messageType, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			conn.WriteMessage(messageType, data)
+		}
+	}, Options{Subprotocols: []string{"v2.chat", "v1.chat"}, EnableCompression: true}))
+
+	ln := fasthttputil.NewInmemoryListener()
+	defer ln.Close()
+	go fasthttp.Serve(ln, router.HandleRequest)
+
+	nc, err := ln.Dial()
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer nc.Close()
+	nc.Write([]byte("GET /rooms/42 HTTP/1.1\r\n" +
+		"Host: example.com\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Sec-WebSocket-Version: 13\r\n" +
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\n" +
+		"Sec-WebSocket-Protocol: v1.chat, v2.chat\r\n" +
+		"Sec-WebSocket-Extensions: permessage-deflate; client_max_window_bits\r\n\r\n"))
+
+	client := &testClient{nc, bufio.NewReader(nc)}
+	res, err := http.ReadResponse(client.br, nil)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, fasthttp.StatusSwitchingProtocols, res.StatusCode)
+	assert.Equal(t, "websocket", res.Header.Get("Upgrade"))
+	assert.Equal(t, "Upgrade", res.Header.Get("Connection"))
+	assert.Equal(t, "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=", res.Header.Get("Sec-WebSocket-Accept"))
+	assert.Equal(t, "v2.chat", res.Header.Get("Sec-WebSocket-Protocol"))
+	assert.Equal(t, "permessage-deflate; server_no_context_takeover; client_no_context_takeover", res.Header.Get("Sec-WebSocket-Extensions"))
+
+	b0, payload, err := client.readFrame()
+	if assert.Nil(t, err) {
+		assert.Equal(t, byte(finBit|rsv1Bit|TextMessage), b0)
+		data, _ := inflate(payload, -1)
+		assert.Equal(t, "42:john:v2.chat", string(data))
+	}
+
+	client.writeFrame(finBit|BinaryMessage, []byte("echo"))
+	_, payload, err = client.readFrame()
+	if assert.Nil(t, err) {
+		data, _ := inflate(payload, -1)
+		assert.Equal(t, "echo", string(data))
+	}
+
+	client.writeFrame(finBit|CloseMessage, closePayload(CloseNormalClosure, ""))
+	b0, payload, err = client.readFrame()
+	if assert.Nil(t, err) {
+		assert.Equal(t, byte(finBit|CloseMessage), b0)
+		assert.Equal(t, closePayload(CloseNormalClosure, ""), payload)
+	}
+	// the connection is closed once the handler returns, without sending another close message
+	_, _, err = client.readFrame()
+	assert.NotNil(t, err)
+}
+
+func Test_selectSubprotocol(t *testing.T) {
+	assert.Equal(t, "", selectSubprotocol("", []string{"a"}))
+	assert.Equal(t, "", selectSubprotocol("a, b", nil))
+	assert.Equal(t, "b", selectSubprotocol("a, b", []string{"c", "b", "a"}))
+}
@@ -15,6 +15,16 @@ type DataWriter interface {
 	Write(io.Writer, interface{}) error
 }
 
+// ViewWriter is a DataWriter that needs the context of the request, such as content.TemplateDataWriter,
+// which renders named templates, or views, and content.JSONAPIDataWriter, which builds links from the current route.
+// Context.Write() and Context.Render() call WriteView() instead of Write() on such writers.
+type ViewWriter interface {
+	DataWriter
+	// WriteView writes the given data into the response using the named view.
+	// An empty name lets the writer choose the view, for example from the tags of the route.
+	WriteView(c *Context, name string, data interface{}) error
+}
+
 // DefaultDataWriter writes the given data in an HTTP response.
 // If the data is neither string nor byte array, it will use fmt.Fprint() to write it into the response.
 var DefaultDataWriter DataWriter = &dataWriter{}